@@ -1,40 +1,133 @@
 package migrate
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"strconv"
 
 	"github.com/spf13/cobra"
 
 	"github.com/csams/common-inventory/pkg/errors"
-	"github.com/csams/common-inventory/pkg/models"
+	"github.com/csams/common-inventory/pkg/models/migrations"
 	"github.com/csams/common-inventory/pkg/storage"
 )
 
 func NewCommand(options *storage.Options, log *slog.Logger) *cobra.Command {
+	var dryRun bool
+
+	// newMigrator connects to the database and loads the migrations for its dialect.
+	newMigrator := func(cmd *cobra.Command) (*migrations.Migrator, error) {
+		if errs := options.Complete(); errs != nil {
+			return nil, errors.NewAggregate(errs)
+		}
+
+		if errs := options.Validate(); errs != nil {
+			return nil, errors.NewAggregate(errs)
+		}
+
+		config := storage.NewConfig(options).Complete()
+
+		db, err := storage.New(config)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := migrations.New(db)
+		if err != nil {
+			return nil, err
+		}
+
+		m.DryRun = dryRun
+		m.Out = cmd.OutOrStdout()
+		return m, nil
+	}
+
+	up := func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+
+		count, err := m.Up(context.Background())
+		if !dryRun {
+			log.Info(fmt.Sprintf("Applied %d migrations", count))
+		}
+		return err
+	}
+
 	cmd := &cobra.Command{
 		Use:   "migrate",
 		Short: "Create or migrate the database tables",
+		Long:  "Create or migrate the database tables.  Without a subcommand, all pending migrations are applied.",
+		Args:  cobra.NoArgs,
+		RunE:  up,
+	}
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE:  up,
+	}
+
+	downCmd := &cobra.Command{
+		Use:   "down [N]",
+		Short: "Roll back the N most recently applied migrations (default 1)",
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if errs := options.Complete(); errs != nil {
-				return errors.NewAggregate(errs)
+			n := 1
+			if len(args) > 0 {
+				var err error
+				if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+					return fmt.Errorf("N must be a positive integer: %s", args[0])
+				}
 			}
 
-			if errs := options.Validate(); errs != nil {
-				return errors.NewAggregate(errs)
+			m, err := newMigrator(cmd)
+			if err != nil {
+				return err
 			}
 
-			config := storage.NewConfig(options).Complete()
+			count, err := m.Down(context.Background(), n)
+			if !dryRun {
+				log.Info(fmt.Sprintf("Rolled back %d migrations", count))
+			}
+			return err
+		},
+	}
 
-			db, err := storage.New(config)
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show which migrations have been applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newMigrator(cmd)
 			if err != nil {
 				return err
 			}
 
-			return models.Migrate(db)
+			statuses, err := m.Status(context.Background())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, s := range statuses {
+				applied := "pending"
+				if s.AppliedAt != nil {
+					applied = s.AppliedAt.Format("2006-01-02T15:04:05Z07:00")
+				}
+				fmt.Fprintf(out, "%06d_%s\t%s\n", s.Version, s.Name, applied)
+			}
+			return nil
 		},
 	}
 
-	options.AddFlags(cmd.Flags(), "storage")
+	cmd.AddCommand(upCmd, downCmd, statusCmd)
+
+	cmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print the SQL that would be run instead of running it")
+	options.AddFlags(cmd.PersistentFlags(), "storage")
 
 	return cmd
 }
@@ -0,0 +1,57 @@
+package psk
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/csams/common-inventory/pkg/authn/util"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "psk",
+		Short: "Manage pre-shared keys",
+	}
+
+	var id, algorithm string
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a pre-shared key and the hash to put in the pre-shared key file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if id == "" {
+				b := make([]byte, 6)
+				if _, err := rand.Read(b); err != nil {
+					return err
+				}
+				id = hex.EncodeToString(b)
+			}
+
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			secret := base64.RawURLEncoding.EncodeToString(b)
+
+			hash, err := util.HashSecret(algorithm, secret)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "# give this key to the client: %s.%s\n", id, secret)
+			fmt.Fprintf(out, "%q:\n  hash: %q\n  principal: \"\"\n", id, hash)
+			return nil
+		},
+	}
+
+	generateCmd.Flags().StringVar(&id, "id", "", "the id of the key.  Random if not given.")
+	generateCmd.Flags().StringVar(&algorithm, "algorithm", util.SHA256, "the hash algorithm.  Either sha256 or bcrypt.")
+
+	cmd.AddCommand(generateCmd)
+	return cmd
+}
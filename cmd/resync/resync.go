@@ -0,0 +1,177 @@
+package resync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/controllers"
+	"github.com/csams/common-inventory/pkg/errors"
+	"github.com/csams/common-inventory/pkg/eventing"
+	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+	"github.com/csams/common-inventory/pkg/models/migrations"
+	"github.com/csams/common-inventory/pkg/resync"
+	"github.com/csams/common-inventory/pkg/storage"
+)
+
+// identity is the actor of the events the command sends.
+var identity = &authnapi.Identity{Principal: "common-inventory-resync"}
+
+func NewCommand(
+	storageOptions *storage.Options,
+	eventingOptions *eventing.Options,
+	resyncOptions *resync.Options,
+	log *slog.Logger,
+) *cobra.Command {
+	var input models.ResyncIn
+	var resume int64
+
+	cmd := &cobra.Command{
+		Use:   "resync",
+		Short: "Send a snapshot event for every matching resource",
+		Long: "Send a snapshot event for every resource that matches the filters through the configured eventers, then an event " +
+			"that marks the end of the snapshot.  A resync that stops can be resumed from where it got to with --resume.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			// configure storage
+			if errs := storageOptions.Complete(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			if errs := storageOptions.Validate(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			storageConfig := storage.NewConfig(storageOptions).Complete()
+
+			// configure eventing
+			if errs := eventingOptions.Complete(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			if errs := eventingOptions.Validate(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			eventingConfig, errs := eventing.NewConfig(eventingOptions).Complete()
+			if errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			// configure the resync
+			if errs := resyncOptions.Complete(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			if errs := resyncOptions.Validate(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			resyncConfig, err := resync.NewConfig(resyncOptions).Complete()
+			if err != nil {
+				return err
+			}
+
+			// bring up storage
+			db, err := storage.New(storageConfig)
+			if err != nil {
+				return err
+			}
+			if sqlDB, err := db.DB(); err == nil {
+				defer sqlDB.Close()
+			}
+
+			migrator, err := migrations.New(db)
+			if err != nil {
+				return err
+			}
+
+			if err := migrator.Check(ctx); err != nil {
+				return err
+			}
+
+			eventingManager, err := eventing.New(eventingConfig, db, log)
+			if err != nil {
+				return err
+			}
+			defer shutdownEventing(eventingManager, log)
+
+			resyncer := resync.New(resyncConfig, controllers.BasePath, controllers.ResourcePaths(), db, eventingManager, log)
+
+			var rs *models.Resync
+			if resume != 0 {
+				rs = &models.Resync{}
+				if err := db.First(rs, resume).Error; err != nil {
+					return fmt.Errorf("failed to load resync %d: %w", resume, err)
+				}
+			} else {
+				if errs := resyncer.Validate(&input); errs != nil {
+					return errors.NewAggregate(errs)
+				}
+				if rs, err = resyncer.Create(ctx, &input, identity); err != nil {
+					return err
+				}
+			}
+
+			// stop on errors that mean no more events can be sent
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				for {
+					select {
+					case err := <-eventingManager.Errs():
+						if eventingapi.IsFatal(err) {
+							log.Error(fmt.Sprintf("Eventing error: %v", err))
+							cancel()
+							return
+						}
+						log.Warn(fmt.Sprintf("Eventing error: %v", err))
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			if err := resyncer.Run(ctx, rs, identity); err != nil {
+				return fmt.Errorf("resync %d stopped after %d snapshots, resume it with --resume %d: %w", rs.ID, rs.Sent, rs.ID, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Resync %d sent %d snapshots\n", rs.ID, rs.Sent)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&input.ResourceType, "resource-type", "", "Only send the resources of this type.")
+	cmd.Flags().StringVar(&input.Workspace, "workspace", "", "Only send the resources in this workspace.")
+	cmd.Flags().StringVar(&input.ReporterType, "reporter-type", "", "Only send the resources reported by this type of reporter.")
+	cmd.Flags().StringVar(&input.ReporterId, "reporter-id", "", "Only send the resources reported by this reporter.")
+	cmd.Flags().Int64Var(&resume, "resume", 0, "Resume the resync with this id instead of starting a new one.  Its filters are kept.")
+	cmd.MarkFlagsMutuallyExclusive("resume", "resource-type")
+	cmd.MarkFlagsMutuallyExclusive("resume", "workspace")
+	cmd.MarkFlagsMutuallyExclusive("resume", "reporter-type")
+	cmd.MarkFlagsMutuallyExclusive("resume", "reporter-id")
+
+	storageOptions.AddFlags(cmd.Flags(), "storage")
+	eventingOptions.AddFlags(cmd.Flags(), "eventing")
+	resyncOptions.AddFlags(cmd.Flags(), "resync")
+
+	return cmd
+}
+
+// shutdownEventing waits for the events to be sent, like serve does when it stops.
+func shutdownEventing(em eventingapi.Manager, log *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := em.Shutdown(ctx); err != nil {
+		log.Error(fmt.Sprintf("Error Gracefully Shutting Down Eventing: %v", err))
+	}
+}
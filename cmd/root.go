@@ -9,11 +9,16 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/csams/common-inventory/cmd/migrate"
+	"github.com/csams/common-inventory/cmd/psk"
+	resynccmd "github.com/csams/common-inventory/cmd/resync"
+	"github.com/csams/common-inventory/cmd/schemaregistry"
 	"github.com/csams/common-inventory/cmd/serve"
 
 	"github.com/csams/common-inventory/pkg/authn"
 	"github.com/csams/common-inventory/pkg/authz"
 	"github.com/csams/common-inventory/pkg/eventing"
+	"github.com/csams/common-inventory/pkg/ingest"
+	"github.com/csams/common-inventory/pkg/resync"
 	"github.com/csams/common-inventory/pkg/server"
 	"github.com/csams/common-inventory/pkg/storage"
 )
@@ -38,12 +43,16 @@ var (
 		Authz    *authz.Options    `mapstructure:"authz"`
 		Storage  *storage.Options  `mapstructure:"storage"`
 		Eventing *eventing.Options `mapstructure:"eventing"`
+		Resync   *resync.Options   `mapstructure:"resync"`
+		Ingest   *ingest.Options   `mapstructure:"ingest"`
 		Server   *server.Options   `mapstructure:"server"`
 	}{
 		authn.NewOptions(),
 		authz.NewOptions(),
 		storage.NewOptions(),
 		eventing.NewOptions(),
+		resync.NewOptions(),
+		ingest.NewOptions(),
 		server.NewOptions(),
 	}
 )
@@ -68,11 +77,19 @@ func init() {
 
 	migrateCmd := migrate.NewCommand(options.Storage, rootLog.WithGroup("storage"))
 	rootCmd.AddCommand(migrateCmd)
-	viper.BindPFlags(migrateCmd.Flags())
+	viper.BindPFlags(migrateCmd.PersistentFlags())
 
-	serveCmd := serve.NewCommand(options.Server, options.Storage, options.Authn, options.Authz, options.Eventing, rootLog.WithGroup("server"))
+	rootCmd.AddCommand(psk.NewCommand())
+
+	serveCmd := serve.NewCommand(options.Server, options.Storage, options.Authn, options.Authz, options.Eventing, options.Resync, options.Ingest, rootLog.WithGroup("server"))
 	rootCmd.AddCommand(serveCmd)
 	viper.BindPFlags(serveCmd.Flags())
+
+	resyncCmd := resynccmd.NewCommand(options.Storage, options.Eventing, options.Resync, rootLog.WithGroup("resync"))
+	rootCmd.AddCommand(resyncCmd)
+	viper.BindPFlags(resyncCmd.Flags())
+
+	rootCmd.AddCommand(schemaregistry.NewCommand(rootLog.WithGroup("schema-registry")))
 }
 
 // initConfig reads in config file and ENV variables if set.
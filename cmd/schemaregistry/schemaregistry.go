@@ -0,0 +1,55 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/csams/common-inventory/pkg/eventing/kafka/registry"
+)
+
+func NewCommand(log *slog.Logger) *cobra.Command {
+	addr := "127.0.0.1:8081"
+
+	cmd := &cobra.Command{
+		Use:   "schema-registry",
+		Short: "Run an in-memory schema registry for development and tests",
+		Long: "Run an in-memory stand-in for a Confluent-compatible schema registry, for the avro and protobuf kafka " +
+			"serializations.  It checks Avro schemas for BACKWARD compatibility and forgets everything when it stops.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			srv := &http.Server{Addr: addr, Handler: registry.NewServer().Routes()}
+			errs := make(chan error, 1)
+			go func() {
+				log.Info(fmt.Sprintf("Schema registry listening on %s", addr))
+				errs <- srv.ListenAndServe()
+			}()
+
+			select {
+			case err := <-errs:
+				return err
+			case <-ctx.Done():
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", addr, "The address to listen on.")
+
+	return cmd
+}
@@ -13,11 +13,15 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/csams/common-inventory/pkg/authn"
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
 	"github.com/csams/common-inventory/pkg/authz"
 	"github.com/csams/common-inventory/pkg/controllers"
 	"github.com/csams/common-inventory/pkg/errors"
 	"github.com/csams/common-inventory/pkg/eventing"
 	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/ingest"
+	"github.com/csams/common-inventory/pkg/models/migrations"
+	"github.com/csams/common-inventory/pkg/resync"
 	"github.com/csams/common-inventory/pkg/server"
 	"github.com/csams/common-inventory/pkg/storage"
 )
@@ -28,6 +32,8 @@ func NewCommand(
 	authnOptions *authn.Options,
 	authzOptions *authz.Options,
 	eventingOptions *eventing.Options,
+	resyncOptions *resync.Options,
+	ingestOptions *ingest.Options,
 	log *slog.Logger,
 ) *cobra.Command {
 	cmd := &cobra.Command{
@@ -56,7 +62,9 @@ func NewCommand(
 				return errors.NewAggregate(errs)
 			}
 
-			authnConfig, errs := authn.NewConfig(authnOptions).Complete()
+			authnIncompleteConfig := authn.NewConfig(authnOptions)
+			authnIncompleteConfig.ClientCert.ClientCAFile = serverOptions.ClientCAFile
+			authnConfig, errs := authnIncompleteConfig.Complete()
 			if errs != nil {
 				return errors.NewAggregate(errs)
 			}
@@ -89,6 +97,34 @@ func NewCommand(
 				return errors.NewAggregate(errs)
 			}
 
+			// configure resyncs
+			if errs := resyncOptions.Complete(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			if errs := resyncOptions.Validate(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			resyncConfig, err := resync.NewConfig(resyncOptions).Complete()
+			if err != nil {
+				return err
+			}
+
+			// configure ingest
+			if errs := ingestOptions.Complete(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			if errs := ingestOptions.Validate(); errs != nil {
+				return errors.NewAggregate(errs)
+			}
+
+			ingestConfig, err := ingest.NewConfig(ingestOptions).Complete()
+			if err != nil {
+				return err
+			}
+
 			// configure the server
 			if errs := serverOptions.Complete(); errs != nil {
 				return errors.NewAggregate(errs)
@@ -109,8 +145,18 @@ func NewCommand(
 				return err
 			}
 
+			// refuse to serve against a schema that doesn't match this version
+			migrator, err := migrations.New(db)
+			if err != nil {
+				return err
+			}
+
+			if err := migrator.Check(ctx); err != nil {
+				return err
+			}
+
 			// bring up the authenticator
-			authenticator, err := authn.New(authnConfig)
+			authenticator, err := authn.New(authnConfig, db, log)
 			if err != nil {
 				return err
 			}
@@ -118,10 +164,28 @@ func NewCommand(
 			// bring up the authorizer
 			authorizer, err := authz.New(ctx, authzConfig)
 
-			eventingManager, err := eventing.New(eventingConfig, log)
+			eventingManager, err := eventing.New(eventingConfig, db, log)
+			if err != nil {
+				return err
+			}
+
+			resyncer := resync.New(resyncConfig, controllers.BasePath, controllers.ResourcePaths(), db, eventingManager, log)
+
+			// consume reporter updates from kafka if it's enabled.  ingestErrs is nil otherwise, so it never fires.
+			var ingester *ingest.Ingester
+			var ingestErrs <-chan error
+			if ingestConfig.Enabled {
+				resourceControllers := controllers.NewResourceControllers(db, authorizer, eventingManager, authzConfig.RequireRegisteredReporters, log)
+				ingester, err = ingest.New(ingestConfig, resourceControllers, log)
+				if err != nil {
+					return err
+				}
+				ingestErrs = ingester.Errs()
+			}
 
 			// bring up the server
-			rootHandler := controllers.NewRootHandler(db, authenticator, authorizer, eventingManager, log)
+			rootHandler := controllers.NewRootHandler(db, authenticator, authorizer, authzConfig.Admins, authzConfig.RequireRegisteredReporters, eventingManager, resyncer, eventingConfig.Subscriptions.Targets, log)
+
 			server := server.New(serverConfig, rootHandler, log)
 			if err != nil {
 				return err
@@ -135,17 +199,28 @@ func NewCommand(
 			quit := make(chan os.Signal, 1)
 			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-			shutdown := gracefulShutdown(db, server, eventingManager, log)
-
-			select {
-			case err := <-srvErrs:
-				shutdown(err)
-			case sig := <-quit:
-				shutdown(sig)
-			case emErr := <-eventingManager.Errs():
-				shutdown(emErr)
+			shutdown := gracefulShutdown(db, server, authenticator, ingester, resyncer, eventingManager, log)
+
+			// transient eventing errors show up in /readyz rather than stopping the server
+			for {
+				select {
+				case err := <-srvErrs:
+					shutdown(err)
+					return nil
+				case sig := <-quit:
+					shutdown(sig)
+					return nil
+				case ingestErr := <-ingestErrs:
+					shutdown(ingestErr)
+					return nil
+				case emErr := <-eventingManager.Errs():
+					if eventingapi.IsFatal(emErr) {
+						shutdown(emErr)
+						return nil
+					}
+					log.Warn(fmt.Sprintf("Eventing error: %v", emErr))
+				}
 			}
-			return nil
 		},
 	}
 
@@ -154,11 +229,13 @@ func NewCommand(
 	authnOptions.AddFlags(cmd.Flags(), "authn")
 	authzOptions.AddFlags(cmd.Flags(), "authz")
 	eventingOptions.AddFlags(cmd.Flags(), "eventing")
+	resyncOptions.AddFlags(cmd.Flags(), "resync")
+	ingestOptions.AddFlags(cmd.Flags(), "ingest")
 
 	return cmd
 }
 
-func gracefulShutdown(db *gorm.DB, srv *server.Server, em eventingapi.Manager, log *slog.Logger) func(reason interface{}) {
+func gracefulShutdown(db *gorm.DB, srv *server.Server, authenticator authnapi.Authenticator, ingester *ingest.Ingester, resyncer *resync.Resyncer, em eventingapi.Manager, log *slog.Logger) func(reason interface{}) {
 	return func(reason interface{}) {
 		log.Info(fmt.Sprintf("Server Shutdown: %s", reason))
 
@@ -169,6 +246,26 @@ func gracefulShutdown(db *gorm.DB, srv *server.Server, em eventingapi.Manager, l
 			log.Error(fmt.Sprintf("Error Gracefully Shutting Down API: %v", err))
 		}
 
+		if s, ok := authenticator.(authnapi.Stopper); ok {
+			s.Stop()
+		}
+
+		// stop ingesting before eventing, so the changes it's applying can send their events
+		if ingester != nil {
+			ctx, cancel = context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := ingester.Shutdown(ctx); err != nil {
+				log.Error(fmt.Sprintf("Error Gracefully Shutting Down Ingest: %v", err))
+			}
+		}
+
+		// resyncs record how far they got, so they can be resumed
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := resyncer.Shutdown(ctx); err != nil {
+			log.Error(fmt.Sprintf("Error Gracefully Shutting Down Resyncs: %v", err))
+		}
+
 		ctx, cancel = context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		if err := em.Shutdown(ctx); err != nil {
@@ -12,8 +12,40 @@ const (
 	Ignore          = "IGNORE"
 )
 
+// Result is an authenticator's decision about a request.
+type Result struct {
+	Decision Decision
+
+	// Authenticator names the authenticator that made the decision, like psk or oidc.
+	Authenticator string
+
+	// Reason says why the request was denied.  It's sent to the client, so it must not contain secrets or
+	// internal details.
+	Reason string
+}
+
+// Ignored means the authenticator doesn't handle the request's credentials.
+var Ignored = Result{Decision: Ignore}
+
+func Allowed(authenticator string) Result {
+	return Result{Decision: Allow, Authenticator: authenticator}
+}
+
+func Denied(authenticator string, reason string) Result {
+	return Result{Decision: Deny, Authenticator: authenticator, Reason: reason}
+}
+
 type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, Result)
+}
+
+// StatusReporter is implemented by authenticators and other components with runtime state worth reporting, like
+// when their keys were last loaded.  Status must return a value that can be serialized as json.
+type StatusReporter interface {
+	Status() any
+}
 
-	// TODO: update the return value so it can carry a message in the DENY case
-	Authenticate(r *http.Request) (*Identity, Decision)
+// Stopper is implemented by authenticators with background work, like reloading files.  Stop ends it.
+type Stopper interface {
+	Stop()
 }
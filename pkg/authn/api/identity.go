@@ -1,5 +1,7 @@
 package api
 
+import "slices"
+
 // Identity is the identity of the requester
 type Identity struct {
 
@@ -14,4 +16,40 @@ type Identity struct {
 	Type       string `yaml:"type"`
 	Href       string `yaml:"href"`
 	IsGuest    bool   `yaml:"is_guest"`
+
+	// Scopes limits what the identity may do regardless of authorization.  Nil means no limits.
+	Scopes *Scopes `yaml:"scopes"`
+}
+
+// The verbs that Scopes can allow.
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+)
+
+// Scopes are the resource types and verbs an identity is limited to.  An empty list allows everything.
+type Scopes struct {
+	ResourceTypes []string `yaml:"resource_types"`
+	Verbs         []string `yaml:"verbs"`
+}
+
+// Allows reports whether the identity's scopes permit the verb on the resource type.
+func (i *Identity) Allows(resourceType string, verb string) bool {
+	if i.Scopes == nil {
+		return true
+	}
+	return i.Scopes.Allows(resourceType, verb)
+}
+
+func (s *Scopes) Allows(resourceType string, verb string) bool {
+	if len(s.ResourceTypes) > 0 && !slices.Contains(s.ResourceTypes, resourceType) {
+		return false
+	}
+	if len(s.Verbs) > 0 && !slices.Contains(s.Verbs, verb) {
+		return false
+	}
+	return true
 }
@@ -0,0 +1,174 @@
+// Package apikey provides an authenticator for the API keys issued through the admin API.
+package apikey
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/util"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+const name = "apikey"
+
+// keyIdBytes is the number of random bytes in a key id.  Ids are hex encoded, which lets the authenticator ignore
+// tokens from other schemes without looking them up.
+const keyIdBytes = 8
+
+// maxCacheEntries bounds the cache, which also holds misses, so random tokens can't grow it without limit.
+const maxCacheEntries = 10000
+
+type ApiKeyAuthenticator struct {
+	Db  *gorm.DB
+	TTL time.Duration
+	Log *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	// key is nil if there's no key with the id.
+	key       *models.ApiKey
+	fetchedAt time.Time
+}
+
+// Status reports the cache settings and how many keys are cached.
+type Status struct {
+	Authenticator string `json:"authenticator"`
+	CachedKeys    int    `json:"cached_keys"`
+	CacheTTL      string `json:"cache_ttl"`
+}
+
+func New(config CompletedConfig, db *gorm.DB, log *slog.Logger) *ApiKeyAuthenticator {
+	return &ApiKeyAuthenticator{
+		Db:    db,
+		TTL:   config.CacheTTL,
+		Log:   log,
+		cache: map[string]cacheEntry{},
+	}
+}
+
+// Generate returns a new key id and secret, and the hash of the secret to store.
+func Generate() (keyId string, secret string, hash string, err error) {
+	id := make([]byte, keyIdBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+
+	// the secrets are random, so a fast hash is as good as a slow one and keeps authentication cheap
+	hash, err = util.HashSecret(util.SHA256, secret)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return hex.EncodeToString(id), secret, hash, nil
+}
+
+// Token is what the caller presents as a bearer token.
+func Token(keyId string, secret string) string {
+	return keyId + "." + secret
+}
+
+func (a *ApiKeyAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
+	keyId, secret, found := strings.Cut(util.GetBearerToken(r), ".")
+	if !found || !isKeyId(keyId) {
+		return nil, api.Ignored
+	}
+
+	key, err := a.lookup(keyId)
+	if err != nil {
+		a.Log.Error(fmt.Sprintf("Failed to look up API key %s: %v", keyId, err))
+		return nil, api.Denied(name, "The API key couldn't be checked")
+	}
+
+	// another scheme may use the same token format
+	if key == nil {
+		return nil, api.Ignored
+	}
+
+	switch {
+	case !util.VerifyHash(key.Hash, secret):
+		return nil, api.Denied(name, "The API key's secret is wrong")
+	case key.RevokedAt != nil:
+		return nil, api.Denied(name, "The API key is revoked")
+	case !key.Active(time.Now()):
+		return nil, api.Denied(name, "The API key expired")
+	}
+
+	return key.Identity(), api.Allowed(name)
+}
+
+func (a *ApiKeyAuthenticator) Status() any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return Status{
+		Authenticator: name,
+		CachedKeys:    len(a.cache),
+		CacheTTL:      a.TTL.String(),
+	}
+}
+
+// lookup returns the key with the id from the cache or the database.  It returns nil if there's no such key.
+func (a *ApiKeyAuthenticator) lookup(keyId string) (*models.ApiKey, error) {
+	now := time.Now()
+
+	a.mu.Lock()
+	entry, found := a.cache[keyId]
+	a.mu.Unlock()
+
+	if found && now.Sub(entry.fetchedAt) < a.TTL {
+		return entry.key, nil
+	}
+
+	key := &models.ApiKey{}
+	if err := a.Db.Where("key_id = ?", keyId).First(key).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		key = nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cache) >= maxCacheEntries {
+		for id, e := range a.cache {
+			if now.Sub(e.fetchedAt) >= a.TTL {
+				delete(a.cache, id)
+			}
+		}
+		if len(a.cache) >= maxCacheEntries {
+			clear(a.cache)
+		}
+	}
+	a.cache[keyId] = cacheEntry{key: key, fetchedAt: now}
+
+	return key, nil
+}
+
+func isKeyId(s string) bool {
+	if len(s) != 2*keyIdBytes {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,119 @@
+package apikey
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/models"
+	"github.com/csams/common-inventory/pkg/models/migrations"
+)
+
+const cacheTTL = 100 * time.Millisecond
+
+func newAuthenticator(t *testing.T) *ApiKeyAuthenticator {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "inventory.db")), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := NewConfig(&Options{CacheTTL: cacheTTL}).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(config, db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// issue stores a new key for the principal and returns it with its token.
+func issue(t *testing.T, a *ApiKeyAuthenticator, principal string) (*models.ApiKey, string) {
+	keyId, secret, hash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := models.NewApiKey(&models.ApiKeyIn{Principal: principal, IsReporter: true}, keyId, hash)
+	if err := a.Db.Create(key).Error; err != nil {
+		t.Fatal(err)
+	}
+	return key, Token(keyId, secret)
+}
+
+func authenticate(a *ApiKeyAuthenticator, token string) (*api.Identity, api.Result) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return a.Authenticate(r)
+}
+
+func expect(t *testing.T, a *ApiKeyAuthenticator, token string, decision api.Decision) {
+	t.Helper()
+	if _, result := authenticate(a, token); result.Decision != decision {
+		t.Errorf("expected %s, got %+v", decision, result)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := newAuthenticator(t)
+	key, token := issue(t, a, "acm-hub-1")
+
+	identity, result := authenticate(a, token)
+	if result.Decision != api.Allow || identity.Principal != "acm-hub-1" || !identity.IsReporter {
+		t.Errorf("expected the key's identity, got %+v %+v", identity, result)
+	}
+
+	expect(t, a, key.KeyId+".wrong", api.Deny)
+	// other schemes' tokens and unknown ids are left to the other authenticators
+	expect(t, a, "0123456789abcdef.secret", api.Ignore)
+	expect(t, a, "acm-hub.secret", api.Ignore)
+	expect(t, a, "1234", api.Ignore)
+}
+
+func TestRevokedKeysStopWorkingAfterTheCacheTTL(t *testing.T) {
+	a := newAuthenticator(t)
+	key, token := issue(t, a, "acm-hub-1")
+	expect(t, a, token, api.Allow)
+
+	now := time.Now().UTC()
+	if err := a.Db.Model(key).Updates(models.ApiKey{RevokedAt: &now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect(t, a, token, api.Allow)
+
+	time.Sleep(cacheTTL)
+	expect(t, a, token, api.Deny)
+}
+
+func TestRotatedSecretsStopWorkingAfterTheCacheTTL(t *testing.T) {
+	a := newAuthenticator(t)
+	key, token := issue(t, a, "acm-hub-1")
+	expect(t, a, token, api.Allow)
+
+	_, secret, hash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Db.Model(key).Updates(models.ApiKey{Hash: hash}).Error; err != nil {
+		t.Fatal(err)
+	}
+	rotated := Token(key.KeyId, secret)
+	expect(t, a, token, api.Allow)
+
+	time.Sleep(cacheTTL)
+	expect(t, a, token, api.Deny)
+	expect(t, a, rotated, api.Allow)
+}
@@ -0,0 +1,29 @@
+package apikey
+
+import (
+	"time"
+)
+
+type Config struct {
+	CacheTTL time.Duration
+}
+
+type completedConfig struct {
+	CacheTTL time.Duration
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		CacheTTL: o.CacheTTL,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	return CompletedConfig{&completedConfig{
+		CacheTTL: c.CacheTTL,
+	}}, nil
+}
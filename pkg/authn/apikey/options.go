@@ -0,0 +1,41 @@
+package apikey
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	CacheTTL time.Duration `mapstructure:"cache-ttl"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		CacheTTL: 30 * time.Second,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.BoolVar(&o.Enabled, prefix+"enabled", o.Enabled, "Authenticate API keys issued through the admin API.")
+	fs.DurationVar(&o.CacheTTL, prefix+"cache-ttl", o.CacheTTL, "How long to cache API keys.  Revoked and rotated keys may work for this long.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if o.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache-ttl must not be negative: %s", o.CacheTTL))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
@@ -1,38 +1,54 @@
 package authn
 
 import (
+	"log/slog"
+
+	"gorm.io/gorm"
+
 	"github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/apikey"
 	"github.com/csams/common-inventory/pkg/authn/clientcert"
 	"github.com/csams/common-inventory/pkg/authn/delegator"
-	//	"github.com/csams/common-inventory/pkg/authn/guest"
+	"github.com/csams/common-inventory/pkg/authn/guest"
+	"github.com/csams/common-inventory/pkg/authn/introspection"
 	"github.com/csams/common-inventory/pkg/authn/oidc"
 	"github.com/csams/common-inventory/pkg/authn/psk"
 )
 
-func New(config CompletedConfig) (api.Authenticator, error) {
+func New(config CompletedConfig, db *gorm.DB, log *slog.Logger) (api.Authenticator, error) {
 	d := delegator.New()
 
 	// client certs authn
-	d.Add(clientcert.New())
+	d.Add(clientcert.New(config.ClientCert, log))
 
 	// pre shared key authn
 	if config.PreSharedKeys != nil {
-		a := psk.New(*config.PreSharedKeys)
-		d.Add(a)
-	}
-
-	// oidc tokens
-	if config.Oidc != nil {
-		if a, err := oidc.New(*config.Oidc); err == nil {
+		if a, err := psk.New(*config.PreSharedKeys, log); err == nil {
 			d.Add(a)
 		} else {
 			return nil, err
 		}
 	}
 
-	// unauthenticated
-	// TODO: make it configurable whether we allow unauthenticated access
-	// d.Add(guest.New())
+	// api keys issued through the admin api
+	if config.ApiKeys != nil {
+		d.Add(apikey.New(*config.ApiKeys, db, log))
+	}
+
+	// oidc tokens.  Each authenticator ignores the tokens of other issuers.
+	for _, c := range config.Oidc {
+		d.Add(oidc.New(c, log))
+	}
+
+	// opaque oauth2 tokens.  After oidc, which ignores tokens that aren't JWTs from its issuers.
+	if config.Introspection != nil {
+		d.Add(introspection.New(*config.Introspection, log))
+	}
+
+	// unauthenticated.  Last, so it only sees requests without credentials.
+	if config.Guest != nil {
+		d.Add(guest.New(*config.Guest))
+	}
 
 	return d, nil
 }
@@ -1,35 +1,113 @@
 package clientcert
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/csams/common-inventory/pkg/authn/api"
 )
 
-type ClientCertAuthenticator struct{}
+const name = "clientcert"
 
-func New() *ClientCertAuthenticator {
-	return &ClientCertAuthenticator{}
+type ClientCertAuthenticator struct {
+	CompletedConfig
+	Log *slog.Logger
+
+	mu           sync.RWMutex
+	crls         []*crlSet
+	crlsLoadedAt time.Time
+	crlError     error
+	ocspCache    map[string]ocspEntry
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Status reports the revocation checks in use.
+type Status struct {
+	Authenticator string     `json:"authenticator"`
+	CRLs          int        `json:"crls"`
+	CRLsLoadedAt  *time.Time `json:"crls_loaded_at,omitempty"`
+	CRLError      string     `json:"crl_error,omitempty"`
+	OCSP          string     `json:"ocsp"`
+	OCSPCached    int        `json:"ocsp_cached"`
+}
+
+func New(config CompletedConfig, log *slog.Logger) *ClientCertAuthenticator {
+	a := &ClientCertAuthenticator{
+		CompletedConfig: config,
+		Log:             log,
+		crls:            newCRLSets(config.CRLs),
+		crlsLoadedAt:    time.Now().UTC(),
+		ocspCache:       map[string]ocspEntry{},
+		stop:            make(chan struct{}),
+	}
+
+	if len(config.CRLFiles) > 0 {
+		go a.refreshCRLs()
+	}
+
+	return a
 }
 
-func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Decision) {
-	if r.TLS == nil {
-		return nil, api.Ignore
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
+	// only certificates the server verified against the client CA are trusted
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, api.Ignored
 	}
 
-	if len(r.TLS.PeerCertificates) == 0 {
-		return nil, api.Ignore
+	chain := r.TLS.VerifiedChains[0]
+	cert, issuer := chain[0], chain[0]
+	if len(chain) > 1 {
+		issuer = chain[1]
 	}
 
-	cert := r.TLS.PeerCertificates[0]
+	if err := a.checkRevocation(r.Context(), cert, issuer); err == errRevoked {
+		return nil, api.Denied(name, "The client certificate is revoked")
+	} else if err != nil {
+		a.Log.Error(fmt.Sprintf("Failed to check whether client certificate %s is revoked: %v", cert.Subject, err))
+		return nil, api.Denied(name, "The client certificate's revocation status couldn't be checked")
+	}
 
-	// TODO: What do we do about tenant id here?
-	// TODO: Should we say all reporters will authenticate with client certificates?
-	// TODO: How do we get Type, Href, etc.?  We may need to look at a CR in openshift or something if that's
-	// how reporters get registered.
-	return &api.Identity{
-		Principal:  cert.Subject.CommonName,
-		Groups:     cert.Subject.Organization,
-		IsReporter: true,
-	}, api.Allow
+	identity := &api.Identity{
+		Principal:  a.Principal.Value(cert),
+		Type:       a.Type.Value(cert),
+		Tenant:     a.Tenant.Value(cert),
+		Groups:     a.Groups.Values(cert),
+		IsReporter: a.IsReporter,
+	}
+
+	if identity.Principal == "" {
+		return nil, api.Denied(name, "The client certificate doesn't match the principal rule")
+	}
+
+	return identity, api.Allowed(name)
+}
+
+// Stop stops reloading the CRL files.
+func (a *ClientCertAuthenticator) Stop() {
+	a.stopOnce.Do(func() { close(a.stop) })
+}
+
+func (a *ClientCertAuthenticator) Status() any {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	status := Status{
+		Authenticator: name,
+		CRLs:          len(a.crls),
+		OCSP:          a.OCSP,
+		OCSPCached:    len(a.ocspCache),
+	}
+	if len(a.CRLFiles) > 0 {
+		at := a.crlsLoadedAt
+		status.CRLsLoadedAt = &at
+	}
+	if a.crlError != nil {
+		status.CRLError = a.crlError.Error()
+	}
+	return status
 }
@@ -0,0 +1,165 @@
+package clientcert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/csams/common-inventory/pkg/authn/util"
+)
+
+type Config struct {
+	*Options
+	Client *http.Client
+
+	// ClientCAFile holds the CAs the server verifies client certificates with.  CRLs must be signed by one of them.
+	ClientCAFile string
+}
+
+type completedConfig struct {
+	Principal  *Rule
+	Type       *Rule
+	Tenant     *Rule
+	Groups     *Rule
+	IsReporter bool
+
+	CRLFiles   []string
+	CRLRefresh time.Duration
+	CRLs       []*x509.RevocationList
+	ClientCAs  []*x509.Certificate
+
+	OCSP        string
+	OCSPTimeout time.Duration
+	Client      *http.Client
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	cfg := &completedConfig{
+		IsReporter:  c.IsReporter,
+		CRLFiles:    c.CRLFiles,
+		CRLRefresh:  c.CRLRefresh,
+		OCSP:        c.OCSP,
+		OCSPTimeout: c.OCSPTimeout,
+		Client:      c.Client,
+	}
+
+	var err error
+	if cfg.Principal, err = ParseRule(c.Principal); err != nil {
+		return CompletedConfig{}, err
+	}
+	if cfg.Type, err = ParseRule(c.Type); err != nil {
+		return CompletedConfig{}, err
+	}
+	if cfg.Tenant, err = ParseRule(c.Tenant); err != nil {
+		return CompletedConfig{}, err
+	}
+	if cfg.Groups, err = ParseRule(c.Groups); err != nil {
+		return CompletedConfig{}, err
+	}
+
+	if len(c.CRLFiles) > 0 {
+		if c.ClientCAFile == "" {
+			return CompletedConfig{}, fmt.Errorf("clientcert crl-files need the server's client-ca-file to verify them")
+		}
+		if cfg.ClientCAs, err = LoadCertificates(c.ClientCAFile); err != nil {
+			return CompletedConfig{}, err
+		}
+		if cfg.CRLs, err = LoadCRLs(c.CRLFiles, cfg.ClientCAs); err != nil {
+			return CompletedConfig{}, err
+		}
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = util.NewClient(false)
+	}
+
+	return CompletedConfig{cfg}, nil
+}
+
+// LoadCertificates reads the PEM encoded certificates in the file.
+func LoadCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s has no certificates", file)
+	}
+	return certs, nil
+}
+
+// LoadCRLs reads the revocation lists in the files.  A file may hold one DER encoded list or any number of PEM
+// encoded ones.  Every list must be signed by one of the CAs.
+func LoadCRLs(files []string, cas []*x509.Certificate) ([]*x509.RevocationList, error) {
+	var crls []*x509.RevocationList
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		block, rest := pem.Decode(data)
+		if block == nil {
+			crl, err := x509.ParseRevocationList(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if err := verifyCRL(crl, cas); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			crls = append(crls, crl)
+			continue
+		}
+
+		for ; block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "X509 CRL" {
+				continue
+			}
+			crl, err := x509.ParseRevocationList(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if err := verifyCRL(crl, cas); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			crls = append(crls, crl)
+		}
+	}
+	return crls, nil
+}
+
+// verifyCRL returns an error unless the list is signed by the CA that issued it.
+func verifyCRL(crl *x509.RevocationList, cas []*x509.Certificate) error {
+	for _, ca := range cas {
+		if bytes.Equal(ca.RawSubject, crl.RawIssuer) && crl.CheckSignatureFrom(ca) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("the CRL of %s isn't signed by a client CA", crl.Issuer)
+}
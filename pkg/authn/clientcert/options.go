@@ -0,0 +1,87 @@
+package clientcert
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// The OCSP modes.
+const (
+	OCSPDisabled = "disabled"
+	OCSPSoftFail = "soft-fail"
+	OCSPHardFail = "hard-fail"
+)
+
+type Options struct {
+	// Rules for each Identity field.  See ParseRule for the syntax.
+	Principal  string `mapstructure:"principal"`
+	Type       string `mapstructure:"type"`
+	Tenant     string `mapstructure:"tenant"`
+	Groups     string `mapstructure:"groups"`
+	IsReporter bool   `mapstructure:"is-reporter"`
+
+	CRLFiles   []string      `mapstructure:"crl-files"`
+	CRLRefresh time.Duration `mapstructure:"crl-refresh"`
+
+	OCSP        string        `mapstructure:"ocsp"`
+	OCSPTimeout time.Duration `mapstructure:"ocsp-timeout"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Principal:   "cn",
+		Groups:      "o",
+		IsReporter:  true,
+		CRLRefresh:  time.Hour,
+		OCSP:        OCSPDisabled,
+		OCSPTimeout: 5 * time.Second,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.Principal, prefix+"principal", o.Principal, "the rule for the principal, like 'cn' or 'uri:^spiffe://.+$'")
+	fs.StringVar(&o.Type, prefix+"type", o.Type, "the rule for the reporter type, like 'ou' or '=ACM'")
+	fs.StringVar(&o.Tenant, prefix+"tenant", o.Tenant, "the rule for the tenant, like 'uri:^spiffe://([^/]+)/'")
+	fs.StringVar(&o.Groups, prefix+"groups", o.Groups, "the rule for the groups.  Every match is a group.")
+	fs.BoolVar(&o.IsReporter, prefix+"is-reporter", o.IsReporter, "whether clients authenticated by certificate are reporters")
+	fs.StringSliceVar(&o.CRLFiles, prefix+"crl-files", o.CRLFiles, "CRLs of revoked client certificates, in PEM or DER")
+	fs.DurationVar(&o.CRLRefresh, prefix+"crl-refresh", o.CRLRefresh, "how often to reread the CRL files")
+	fs.StringVar(&o.OCSP, prefix+"ocsp", o.OCSP, "check client certificates with their OCSP responder.  One of 'disabled', 'soft-fail' or 'hard-fail'.")
+	fs.DurationVar(&o.OCSPTimeout, prefix+"ocsp-timeout", o.OCSPTimeout, "how long to wait for an OCSP responder")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	rules := map[string]string{"principal": o.Principal, "type": o.Type, "tenant": o.Tenant, "groups": o.Groups}
+	for name, rule := range rules {
+		if _, err := ParseRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("invalid clientcert %s rule: %w", name, err))
+		}
+	}
+
+	if o.Principal == "" {
+		errs = append(errs, fmt.Errorf("the clientcert principal rule must not be empty"))
+	}
+
+	if len(o.CRLFiles) > 0 && o.CRLRefresh <= 0 {
+		errs = append(errs, fmt.Errorf("clientcert crl-refresh must be positive: %s", o.CRLRefresh))
+	}
+
+	switch o.OCSP {
+	case OCSPDisabled, OCSPSoftFail, OCSPHardFail:
+	default:
+		errs = append(errs, fmt.Errorf("invalid clientcert ocsp mode: %s.  Options are 'disabled', 'soft-fail' and 'hard-fail'", o.OCSP))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
@@ -0,0 +1,206 @@
+package clientcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// ocspDefaultTTL is how long to cache an OCSP response that doesn't say when it will be updated.
+	ocspDefaultTTL = time.Hour
+
+	// ocspFailureTTL is how long to remember that a certificate's status couldn't be checked, so an unavailable
+	// responder doesn't hold up every request for the timeout.
+	ocspFailureTTL = 30 * time.Second
+
+	// maxOCSPCacheEntries bounds the OCSP cache.
+	maxOCSPCacheEntries = 10000
+
+	// ocspClockSkew is how far in the future an OCSP response may say it was produced.
+	ocspClockSkew = 5 * time.Minute
+)
+
+// crlSet is a revocation list and the serial numbers it revokes.
+type crlSet struct {
+	crl     *x509.RevocationList
+	revoked map[string]bool
+}
+
+func newCRLSets(crls []*x509.RevocationList) []*crlSet {
+	var sets []*crlSet
+	for _, crl := range crls {
+		set := &crlSet{crl: crl, revoked: map[string]bool{}}
+		for _, entry := range crl.RevokedCertificateEntries {
+			set.revoked[entry.SerialNumber.String()] = true
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+// ocspEntry is a certificate's OCSP status, or the error from checking it.
+type ocspEntry struct {
+	revoked bool
+	err     error
+	expires time.Time
+}
+
+// checkRevocation returns an error if the certificate is revoked or, with hard-fail OCSP, if its status can't be
+// determined.
+func (a *ClientCertAuthenticator) checkRevocation(ctx context.Context, cert *x509.Certificate, issuer *x509.Certificate) error {
+	if err := a.checkCRLs(cert); err != nil {
+		return err
+	}
+
+	if a.OCSP == OCSPDisabled {
+		return nil
+	}
+
+	err := a.checkOCSP(ctx, cert, issuer)
+	if err == errRevoked || a.OCSP == OCSPHardFail {
+		return err
+	}
+	if err != nil {
+		a.Log.Warn(fmt.Sprintf("Allowing %s without an OCSP check: %v", cert.Subject, err))
+	}
+	return nil
+}
+
+var errRevoked = fmt.Errorf("the certificate is revoked")
+
+// checkCRLs checks the lists of the certificate's issuer.  Their signatures were verified when they were loaded.
+func (a *ClientCertAuthenticator) checkCRLs(cert *x509.Certificate) error {
+	a.mu.RLock()
+	sets := a.crls
+	a.mu.RUnlock()
+
+	serial := cert.SerialNumber.String()
+	for _, set := range sets {
+		if bytes.Equal(set.crl.RawIssuer, cert.RawIssuer) && set.revoked[serial] {
+			return errRevoked
+		}
+	}
+	return nil
+}
+
+func (a *ClientCertAuthenticator) checkOCSP(ctx context.Context, cert *x509.Certificate, issuer *x509.Certificate) error {
+	key := string(cert.RawIssuer) + "/" + cert.SerialNumber.String()
+	now := time.Now()
+
+	a.mu.RLock()
+	entry, found := a.ocspCache[key]
+	a.mu.RUnlock()
+
+	if !found || !now.Before(entry.expires) {
+		entry = ocspEntry{expires: now.Add(ocspFailureTTL)}
+
+		resp, err := a.queryOCSP(ctx, cert, issuer)
+		switch {
+		case err != nil:
+			entry.err = err
+		case resp.ThisUpdate.After(now.Add(ocspClockSkew)):
+			entry.err = fmt.Errorf("the OCSP response was produced in the future, at %s", resp.ThisUpdate)
+		case !resp.NextUpdate.IsZero() && !now.Before(resp.NextUpdate):
+			entry.err = fmt.Errorf("the OCSP response is stale.  Its next update was due at %s", resp.NextUpdate)
+		case resp.Status == ocsp.Good, resp.Status == ocsp.Revoked:
+			entry.revoked = resp.Status == ocsp.Revoked
+			entry.expires = now.Add(ocspDefaultTTL)
+			if !resp.NextUpdate.IsZero() {
+				entry.expires = resp.NextUpdate
+			}
+		default:
+			entry.err = fmt.Errorf("the OCSP responder doesn't know the certificate")
+		}
+
+		a.mu.Lock()
+		if len(a.ocspCache) >= maxOCSPCacheEntries {
+			clear(a.ocspCache)
+		}
+		a.ocspCache[key] = entry
+		a.mu.Unlock()
+	}
+
+	if entry.err != nil {
+		return entry.err
+	}
+	if entry.revoked {
+		return errRevoked
+	}
+	return nil
+}
+
+func (a *ClientCertAuthenticator) queryOCSP(ctx context.Context, cert *x509.Certificate, issuer *x509.Certificate) (*ocsp.Response, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, fmt.Errorf("the certificate has no OCSP responder")
+	}
+
+	body, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.OCSPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cert.OCSPServer[0], bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", cert.OCSPServer[0], resp.Status)
+	}
+
+	der, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	return ocsp.ParseResponseForCert(der, cert, issuer)
+}
+
+// refreshCRLs rereads the CRL files every CRLRefresh until Stop is called.  The current lists are kept if the
+// files are invalid.
+func (a *ClientCertAuthenticator) refreshCRLs() {
+	ticker := time.NewTicker(a.CRLRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			return
+		}
+
+		crls, err := LoadCRLs(a.CRLFiles, a.ClientCAs)
+
+		a.mu.Lock()
+		if err != nil {
+			a.crlError = err
+		} else {
+			a.crls = newCRLSets(crls)
+			a.crlsLoadedAt = time.Now().UTC()
+			a.crlError = nil
+		}
+		a.mu.Unlock()
+
+		if err != nil {
+			a.Log.Error(fmt.Sprintf("Keeping the current CRLs.  Failed to reload them: %v", err))
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package clientcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue signs a client certificate with the serial number that names the OCSP responder, if there is one.
+func (ca *testCA) issue(t *testing.T, serial int64, ocspServer string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "acm-hub-1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// crl is a PEM encoded CRL signed by the CA that revokes the serial numbers.
+func (ca *testCA) crl(t *testing.T, revoked ...int64) []byte {
+	var entries []x509.RevocationListEntry
+	for _, serial := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: time.Now()})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func writeFile(t *testing.T, file string, data []byte) string {
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newAuthenticator(t *testing.T, o *Options, clientCAFile string) (*ClientCertAuthenticator, error) {
+	c := NewConfig(o)
+	c.ClientCAFile = clientCAFile
+	config, err := c.Complete()
+	if err != nil {
+		return nil, err
+	}
+	a := New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(a.Stop)
+	return a, nil
+}
+
+// authenticate presents the certificate as if the server verified it against the CA.
+func authenticate(a *ClientCertAuthenticator, cert *x509.Certificate, ca *testCA) api.Result {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.cert}}}
+	_, result := a.Authenticate(r)
+	return result
+}
+
+func TestCRLs(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "client ca")
+	caFile := writeFile(t, filepath.Join(dir, "ca.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+	crlFile := writeFile(t, filepath.Join(dir, "crl.pem"), ca.crl(t, 2))
+
+	o := NewOptions()
+	o.CRLFiles = []string{crlFile}
+	o.CRLRefresh = 20 * time.Millisecond
+	a, err := newAuthenticator(t, o, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := authenticate(a, ca.issue(t, 2, ""), ca); result.Decision != api.Deny {
+		t.Errorf("expected the revoked certificate to be denied, got %+v", result)
+	}
+	if result := authenticate(a, ca.issue(t, 3, ""), ca); result.Decision != api.Allow {
+		t.Errorf("expected the certificate to be allowed, got %+v", result)
+	}
+
+	// a list signed by another CA is refused and the current lists are kept
+	other := newTestCA(t, "client ca")
+	writeFile(t, crlFile, other.crl(t))
+	deadline := time.Now().Add(5 * time.Second)
+	for a.Status().(Status).CRLError == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if a.Status().(Status).CRLError == "" {
+		t.Fatal("expected the CRL signed by another CA to be refused")
+	}
+	if result := authenticate(a, ca.issue(t, 2, ""), ca); result.Decision != api.Deny {
+		t.Errorf("expected the revoked certificate to still be denied, got %+v", result)
+	}
+}
+
+func TestCRLsMustBeSignedByAClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "client ca")
+	caFile := writeFile(t, filepath.Join(dir, "ca.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+	crlFile := writeFile(t, filepath.Join(dir, "crl.pem"), newTestCA(t, "client ca").crl(t, 2))
+
+	o := NewOptions()
+	o.CRLFiles = []string{crlFile}
+	if _, err := newAuthenticator(t, o, caFile); err == nil {
+		t.Error("expected a CRL signed by another CA to be refused")
+	}
+	if _, err := newAuthenticator(t, o, ""); err == nil {
+		t.Error("expected CRLs to need the client CA")
+	}
+}
+
+// ocspResponder answers every request with the status and update times it's set to, or fails.
+type ocspResponder struct {
+	ca *testCA
+
+	mu         sync.Mutex
+	status     int
+	thisUpdate time.Time
+	nextUpdate time.Time
+	fail       bool
+}
+
+func (o *ocspResponder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := ocsp.ParseRequest(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	template := ocsp.Response{
+		Status:       o.status,
+		SerialNumber: req.SerialNumber,
+		ThisUpdate:   o.thisUpdate,
+		NextUpdate:   o.nextUpdate,
+	}
+	if o.status == ocsp.Revoked {
+		template.RevokedAt = o.thisUpdate
+	}
+	der, err := ocsp.CreateResponse(o.ca.cert, o.ca.cert, template, o.ca.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.Write(der)
+}
+
+func TestOCSP(t *testing.T) {
+	ca := newTestCA(t, "client ca")
+	responder := &ocspResponder{ca: ca}
+	srv := httptest.NewServer(responder)
+	defer srv.Close()
+
+	now := time.Now()
+	tests := []struct {
+		name       string
+		mode       string
+		status     int
+		thisUpdate time.Time
+		nextUpdate time.Time
+		fail       bool
+		expected   api.Decision
+	}{
+		{"good", OCSPHardFail, ocsp.Good, now.Add(-time.Minute), now.Add(time.Hour), false, api.Allow},
+		{"revoked", OCSPSoftFail, ocsp.Revoked, now.Add(-time.Minute), now.Add(time.Hour), false, api.Deny},
+		{"stale hard-fail", OCSPHardFail, ocsp.Good, now.Add(-2 * time.Hour), now.Add(-time.Hour), false, api.Deny},
+		{"stale soft-fail", OCSPSoftFail, ocsp.Revoked, now.Add(-2 * time.Hour), now.Add(-time.Hour), false, api.Allow},
+		{"future hard-fail", OCSPHardFail, ocsp.Good, now.Add(time.Hour), now.Add(2 * time.Hour), false, api.Deny},
+		{"unavailable hard-fail", OCSPHardFail, ocsp.Good, now, now, true, api.Deny},
+		{"unavailable soft-fail", OCSPSoftFail, ocsp.Good, now, now, true, api.Allow},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responder.mu.Lock()
+			responder.status, responder.thisUpdate, responder.nextUpdate, responder.fail = test.status, test.thisUpdate, test.nextUpdate, test.fail
+			responder.mu.Unlock()
+
+			o := NewOptions()
+			o.OCSP = test.mode
+			a, err := newAuthenticator(t, o, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a new serial number each time, so the status isn't cached
+			if result := authenticate(a, ca.issue(t, int64(10+i), srv.URL), ca); result.Decision != test.expected {
+				t.Errorf("expected %s, got %+v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package clientcert
+
+import (
+	"crypto/x509"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule extracts values from a certificate.
+type Rule struct {
+	// Field is the certificate field the values come from.  Empty for literal rules.
+	Field string
+
+	// Pattern selects and trims values.  A value is kept if it matches, and the first capture group is used if
+	// there is one.  Nil keeps every value.
+	Pattern *regexp.Regexp
+
+	Literal string
+}
+
+// Fields are the certificate fields rules can use.
+var Fields = map[string]func(*x509.Certificate) []string{
+	"cn":     func(c *x509.Certificate) []string { return nonEmpty(c.Subject.CommonName) },
+	"o":      func(c *x509.Certificate) []string { return c.Subject.Organization },
+	"ou":     func(c *x509.Certificate) []string { return c.Subject.OrganizationalUnit },
+	"serial": func(c *x509.Certificate) []string { return []string{c.SerialNumber.String()} },
+	"dns":    func(c *x509.Certificate) []string { return c.DNSNames },
+	"email":  func(c *x509.Certificate) []string { return c.EmailAddresses },
+	"uri": func(c *x509.Certificate) []string {
+		var uris []string
+		for _, u := range c.URIs {
+			uris = append(uris, u.String())
+		}
+		return uris
+	},
+}
+
+// ParseRule parses a rule.  Rules are one of
+//
+//	<field>           every value of the field, like cn or ou
+//	<field>:<regexp>  the values of the field that match, or their first capture group
+//	=<value>          the value itself
+//
+// An empty rule extracts nothing.
+func ParseRule(s string) (*Rule, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if literal, found := strings.CutPrefix(s, "="); found {
+		return &Rule{Literal: literal}, nil
+	}
+
+	field, pattern, hasPattern := strings.Cut(s, ":")
+	if _, found := Fields[field]; !found {
+		return nil, fmt.Errorf("unknown certificate field %q", field)
+	}
+
+	rule := &Rule{Field: field}
+	if hasPattern {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		rule.Pattern = re
+	}
+
+	return rule, nil
+}
+
+// Values returns every value the rule extracts from the certificate.
+func (r *Rule) Values(cert *x509.Certificate) []string {
+	if r == nil {
+		return nil
+	}
+
+	if r.Field == "" {
+		return []string{r.Literal}
+	}
+
+	var values []string
+	for _, v := range Fields[r.Field](cert) {
+		if r.Pattern == nil {
+			values = append(values, v)
+			continue
+		}
+
+		m := r.Pattern.FindStringSubmatch(v)
+		switch {
+		case m == nil:
+		case len(m) > 1:
+			values = append(values, m[1])
+		default:
+			values = append(values, m[0])
+		}
+	}
+	return values
+}
+
+// Value returns the first value the rule extracts, or "" if there's none.
+func (r *Rule) Value(cert *x509.Certificate) string {
+	if values := r.Values(cert); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
@@ -1,20 +1,34 @@
 package authn
 
 import (
+	"github.com/csams/common-inventory/pkg/authn/apikey"
+	"github.com/csams/common-inventory/pkg/authn/clientcert"
+	"github.com/csams/common-inventory/pkg/authn/guest"
+	"github.com/csams/common-inventory/pkg/authn/introspection"
 	"github.com/csams/common-inventory/pkg/authn/oidc"
 	"github.com/csams/common-inventory/pkg/authn/psk"
 )
 
 type Config struct {
-	Oidc          *oidc.Config
+	Oidc          []*oidc.Config
 	PreSharedKeys *psk.Config
+	ApiKeys       *apikey.Config
+	Introspection *introspection.Config
+	ClientCert    *clientcert.Config
+	Guest         *guest.Config
 }
 
 func NewConfig(o *Options) *Config {
-	cfg := &Config{}
+	cfg := &Config{
+		ClientCert: clientcert.NewConfig(o.ClientCert),
+	}
 
 	if len(o.Oidc.AuthorizationServerURL) > 0 {
-		cfg.Oidc = oidc.NewConfig(o.Oidc)
+		cfg.Oidc = append(cfg.Oidc, oidc.NewConfig(o.Oidc))
+	}
+
+	for _, p := range o.OidcProviders {
+		cfg.Oidc = append(cfg.Oidc, oidc.NewConfig(p))
 	}
 
 	if len(o.PreSharedKeys.PreSharedKeyFile) > 0 {
@@ -22,12 +36,28 @@ func NewConfig(o *Options) *Config {
 
 	}
 
+	if o.ApiKeys.Enabled {
+		cfg.ApiKeys = apikey.NewConfig(o.ApiKeys)
+	}
+
+	if len(o.Introspection.IntrospectionURL) > 0 {
+		cfg.Introspection = introspection.NewConfig(o.Introspection)
+	}
+
+	if o.Guest.Enabled {
+		cfg.Guest = guest.NewConfig(o.Guest)
+	}
+
 	return cfg
 }
 
 type completedConfig struct {
-	Oidc          *oidc.CompletedConfig
+	Oidc          []oidc.CompletedConfig
 	PreSharedKeys *psk.CompletedConfig
+	ApiKeys       *apikey.CompletedConfig
+	Introspection *introspection.CompletedConfig
+	ClientCert    clientcert.CompletedConfig
+	Guest         *guest.CompletedConfig
 }
 
 type CompletedConfig struct {
@@ -38,9 +68,9 @@ func (c *Config) Complete() (CompletedConfig, []error) {
 	var errs []error
 	cfg := CompletedConfig{&completedConfig{}}
 
-	if c.Oidc != nil {
-		if o, err := c.Oidc.Complete(); err == nil {
-			cfg.Oidc = &o
+	for _, p := range c.Oidc {
+		if o, err := p.Complete(); err == nil {
+			cfg.Oidc = append(cfg.Oidc, o)
 		} else {
 			errs = append(errs, err)
 		}
@@ -54,6 +84,36 @@ func (c *Config) Complete() (CompletedConfig, []error) {
 		}
 	}
 
+	if c.ApiKeys != nil {
+		if o, err := c.ApiKeys.Complete(); err == nil {
+			cfg.ApiKeys = &o
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	if o, err := c.ClientCert.Complete(); err == nil {
+		cfg.ClientCert = o
+	} else {
+		errs = append(errs, err)
+	}
+
+	if c.Introspection != nil {
+		if o, err := c.Introspection.Complete(); err == nil {
+			cfg.Introspection = &o
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Guest != nil {
+		if o, err := c.Guest.Complete(); err == nil {
+			cfg.Guest = &o
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
 	if errs != nil {
 		return CompletedConfig{completedConfig: &completedConfig{}}, errs
 	}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/util"
 )
 
 type DelegatingAuthenticator struct {
@@ -18,13 +19,37 @@ func (d *DelegatingAuthenticator) Add(a api.Authenticator) {
 	d.Authenticators = append(d.Authenticators, a)
 }
 
-func (d *DelegatingAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Decision) {
+func (d *DelegatingAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
 	for _, a := range d.Authenticators {
-		identity, decision := a.Authenticate(r)
-		if decision == api.Ignore {
+		identity, result := a.Authenticate(r)
+		if result.Decision == api.Ignore {
 			continue
 		}
-		return identity, decision
+		return identity, result
+	}
+
+	if util.GetBearerToken(r) != "" {
+		return nil, api.Denied("", "The bearer token isn't recognized")
+	}
+	return nil, api.Denied("", "No credentials were presented")
+}
+
+// Status returns the status of each delegate that reports one.
+func (d *DelegatingAuthenticator) Status() any {
+	statuses := []any{}
+	for _, a := range d.Authenticators {
+		if r, ok := a.(api.StatusReporter); ok {
+			statuses = append(statuses, r.Status())
+		}
+	}
+	return statuses
+}
+
+// Stop stops the delegates that have background work.
+func (d *DelegatingAuthenticator) Stop() {
+	for _, a := range d.Authenticators {
+		if s, ok := a.(api.Stopper); ok {
+			s.Stop()
+		}
 	}
-	return nil, api.Deny
 }
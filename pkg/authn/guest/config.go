@@ -0,0 +1,25 @@
+package guest
+
+type Config struct {
+	ResourceTypes []string
+}
+
+type completedConfig struct {
+	ResourceTypes []string
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		ResourceTypes: o.ResourceTypes,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	return CompletedConfig{&completedConfig{
+		ResourceTypes: c.ResourceTypes,
+	}}, nil
+}
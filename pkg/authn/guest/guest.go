@@ -1,3 +1,5 @@
+// Package guest provides an authenticator for requests without credentials.  Guests may only get and list the
+// configured resource types.
 package guest
 
 import (
@@ -6,20 +8,47 @@ import (
 	"github.com/csams/common-inventory/pkg/authn/api"
 )
 
-type GuestAuthenticator struct{}
+const name = "guest"
 
-func New() *GuestAuthenticator {
-	return &GuestAuthenticator{}
+type GuestAuthenticator struct {
+	ResourceTypes []string
 }
 
-func (a *GuestAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Decision) {
+// Status reports what guests may read.
+type Status struct {
+	Authenticator string   `json:"authenticator"`
+	ResourceTypes []string `json:"resource_types"`
+}
+
+func New(config CompletedConfig) *GuestAuthenticator {
+	return &GuestAuthenticator{
+		ResourceTypes: config.ResourceTypes,
+	}
+}
+
+func (a *GuestAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
+	// credentials nobody recognized are denied rather than downgraded to guest access
+	if r.Header.Get("Authorization") != "" {
+		return nil, api.Ignored
+	}
 
 	// TODO: should we use something else? ip address?
 	ua := r.Header.Get("User-Agent")
 	identity := &api.Identity{
 		Principal: ua,
 		IsGuest:   true,
+		Scopes: &api.Scopes{
+			ResourceTypes: a.ResourceTypes,
+			Verbs:         []string{api.VerbGet, api.VerbList},
+		},
 	}
 
-	return identity, api.Allow
+	return identity, api.Allowed(name)
+}
+
+func (a *GuestAuthenticator) Status() any {
+	return Status{
+		Authenticator: name,
+		ResourceTypes: a.ResourceTypes,
+	}
 }
@@ -0,0 +1,38 @@
+package guest
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	ResourceTypes []string `mapstructure:"resource-types"`
+}
+
+func NewOptions() *Options {
+	return &Options{}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.BoolVar(&o.Enabled, prefix+"enabled", o.Enabled, "Allow unauthenticated requests to read the resource types in resource-types.")
+	fs.StringSliceVar(&o.ResourceTypes, prefix+"resource-types", o.ResourceTypes, "The resource types guests may get and list.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if o.Enabled && len(o.ResourceTypes) == 0 {
+		errs = append(errs, fmt.Errorf("guest access needs at least one resource type"))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
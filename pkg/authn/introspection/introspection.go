@@ -0,0 +1,212 @@
+// Package introspection provides an authenticator for opaque OAuth2 access tokens.  Tokens are checked with an
+// RFC 7662 introspection endpoint.  See https://www.rfc-editor.org/rfc/rfc7662
+package introspection
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/oidc"
+	"github.com/csams/common-inventory/pkg/authn/util"
+)
+
+const name = "introspection"
+
+// maxCacheEntries bounds the cache, which also holds inactive tokens, so random tokens can't grow it without limit.
+const maxCacheEntries = 10000
+
+type IntrospectionAuthenticator struct {
+	CompletedConfig
+	Log *slog.Logger
+
+	mu sync.Mutex
+	// cache is keyed by a hash of the token so tokens aren't kept in memory.
+	cache map[[sha256.Size]byte]cacheEntry
+}
+
+type cacheEntry struct {
+	// identity is nil if the token is denied for the reason.
+	identity *api.Identity
+	reason   string
+	expires  time.Time
+}
+
+// Status reports the introspection endpoint and how many tokens are cached.
+type Status struct {
+	Authenticator string `json:"authenticator"`
+	URL           string `json:"url"`
+	CachedTokens  int    `json:"cached_tokens"`
+}
+
+func New(config CompletedConfig, log *slog.Logger) *IntrospectionAuthenticator {
+	return &IntrospectionAuthenticator{
+		CompletedConfig: config,
+		Log:             log,
+		cache:           map[[sha256.Size]byte]cacheEntry{},
+	}
+}
+
+func (a *IntrospectionAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
+	token := util.GetBearerToken(r)
+	if token == "" {
+		return nil, api.Ignored
+	}
+
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	a.mu.Lock()
+	entry, found := a.cache[key]
+	a.mu.Unlock()
+
+	if !found || !now.Before(entry.expires) {
+		claims, err := a.Introspect(r.Context(), token)
+		if err != nil {
+			// not cached, so the token is checked again once the endpoint recovers
+			a.Log.Error(fmt.Sprintf("Token introspection failed: %v", err))
+			return nil, api.Denied(name, "The token couldn't be introspected")
+		}
+
+		entry = a.newEntry(claims, now)
+
+		a.mu.Lock()
+		if len(a.cache) >= maxCacheEntries {
+			for k, e := range a.cache {
+				if !now.Before(e.expires) {
+					delete(a.cache, k)
+				}
+			}
+			if len(a.cache) >= maxCacheEntries {
+				clear(a.cache)
+			}
+		}
+		a.cache[key] = entry
+		a.mu.Unlock()
+	}
+
+	if entry.identity == nil {
+		return nil, api.Denied(name, entry.reason)
+	}
+
+	identity := *entry.identity
+	return &identity, api.Allowed(name)
+}
+
+// Introspect asks the endpoint about the token and returns its response.
+func (a *IntrospectionAuthenticator) Introspect(ctx context.Context, token string) (oidc.Claims, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	defer cancel()
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.ClientId != "" {
+		req.SetBasicAuth(url.QueryEscape(a.ClientId), url.QueryEscape(a.ClientSecret))
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", a.IntrospectionURL, resp.Status)
+	}
+
+	return oidc.ParseClaims(body)
+}
+
+// newEntry maps the introspection response to a cache entry.  Active tokens are cached until they expire, but no
+// longer than the cache TTL.
+func (a *IntrospectionAuthenticator) newEntry(claims oidc.Claims, now time.Time) cacheEntry {
+	entry := cacheEntry{expires: now.Add(a.CacheTTL), reason: "The token is invalid"}
+
+	if active, err := claims.Bool("active"); err != nil || !active {
+		entry.reason = "The token isn't active"
+		return entry
+	}
+
+	expires, found, err := unixTime(claims, "exp")
+	if err != nil {
+		return entry
+	}
+	if found && !now.Before(expires) {
+		entry.reason = "The token expired"
+		return entry
+	}
+	if found && expires.Before(entry.expires) {
+		entry.expires = expires
+	}
+
+	notBefore, found, err := unixTime(claims, "nbf")
+	if err != nil {
+		return entry
+	}
+	if found && now.Before(notBefore) {
+		entry.reason = "The token isn't valid yet"
+		// check again once the token becomes valid
+		if notBefore.Before(entry.expires) {
+			entry.expires = notBefore
+		}
+		return entry
+	}
+
+	identity, err := a.Claims.Identity(claims)
+	if err != nil {
+		entry.reason = fmt.Sprintf("The token's claims are invalid: %v", err)
+		return entry
+	}
+
+	entry.identity = identity
+	return entry
+}
+
+func (a *IntrospectionAuthenticator) Status() any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return Status{
+		Authenticator: name,
+		URL:           a.IntrospectionURL,
+		CachedTokens:  len(a.cache),
+	}
+}
+
+// unixTime returns the time of a claim given in seconds since the epoch.
+func unixTime(claims oidc.Claims, name string) (time.Time, bool, error) {
+	value, found := claims.Lookup(name)
+	if !found {
+		return time.Time{}, false, nil
+	}
+
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("claim %s isn't a number", name)
+	}
+
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
@@ -0,0 +1,70 @@
+package introspection
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/csams/common-inventory/pkg/authn/oidc"
+)
+
+type Options struct {
+	IntrospectionURL string `mapstructure:"url"`
+	ClientId         string `mapstructure:"client-id"`
+	ClientSecret     string `mapstructure:"client-secret"`
+	InsecureClient   bool   `mapstructure:"insecure-client"`
+
+	Timeout  time.Duration `mapstructure:"timeout"`
+	CacheTTL time.Duration `mapstructure:"cache-ttl"`
+
+	Claims *oidc.ClaimOptions `mapstructure:"claims"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Timeout:  5 * time.Second,
+		CacheTTL: 5 * time.Minute,
+		Claims:   oidc.NewClaimOptions(),
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.IntrospectionURL, prefix+"url", o.IntrospectionURL, "the RFC 7662 token introspection endpoint")
+	fs.StringVar(&o.ClientId, prefix+"client-id", o.ClientId, "the client id used to authenticate to the introspection endpoint")
+	fs.StringVar(&o.ClientSecret, prefix+"client-secret", o.ClientSecret, "the client secret used to authenticate to the introspection endpoint")
+	fs.BoolVar(&o.InsecureClient, prefix+"insecure-client", o.InsecureClient, "skip verifying the introspection endpoint's serving cert")
+	fs.DurationVar(&o.Timeout, prefix+"timeout", o.Timeout, "how long to wait for the introspection endpoint")
+	fs.DurationVar(&o.CacheTTL, prefix+"cache-ttl", o.CacheTTL, "the longest time to cache a token's introspection, even if it expires later")
+	o.Claims.AddFlags(fs, prefix+"claims")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if o.IntrospectionURL != "" {
+		if u, err := url.Parse(o.IntrospectionURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid introspection url: %s", o.IntrospectionURL))
+		}
+	}
+
+	if o.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("the introspection timeout must be positive: %s", o.Timeout))
+	}
+
+	if o.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("the introspection cache-ttl must not be negative: %s", o.CacheTTL))
+	}
+
+	errs = append(errs, o.Claims.Validate()...)
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
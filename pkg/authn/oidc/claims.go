@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+)
+
+// ClaimOptions says which token claims populate an Identity.  Claims are named by dotted paths into the token, like
+// realm_access.roles.
+type ClaimOptions struct {
+	Principal  string `mapstructure:"principal"`
+	Tenant     string `mapstructure:"tenant"`
+	Groups     string `mapstructure:"groups"`
+	Type       string `mapstructure:"type"`
+	IsReporter string `mapstructure:"is-reporter"`
+
+	// Required maps claims to a value the token must have.  If the claim is a list, it must contain the value.
+	Required map[string]string `mapstructure:"required"`
+}
+
+func NewClaimOptions() *ClaimOptions {
+	return &ClaimOptions{
+		Principal: "preferred_username",
+	}
+}
+
+func (o *ClaimOptions) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.Principal, prefix+"principal", o.Principal, "the claim holding the principal")
+	fs.StringVar(&o.Tenant, prefix+"tenant", o.Tenant, "the claim holding the tenant")
+	fs.StringVar(&o.Groups, prefix+"groups", o.Groups, "the claim holding the groups, either a list or a single group")
+	fs.StringVar(&o.Type, prefix+"type", o.Type, "the claim holding the reporter type")
+	fs.StringVar(&o.IsReporter, prefix+"is-reporter", o.IsReporter, "the boolean claim that marks reporters")
+	fs.StringToStringVar(&o.Required, prefix+"required", o.Required, "claims the token must have, as claim=value pairs")
+}
+
+func (o *ClaimOptions) Validate() []error {
+	var errs []error
+
+	if len(o.Principal) == 0 {
+		errs = append(errs, fmt.Errorf("the principal claim must not be empty"))
+	}
+
+	for claim := range o.Required {
+		if len(claim) == 0 {
+			errs = append(errs, fmt.Errorf("required claims must be named"))
+		}
+	}
+
+	return errs
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]any
+
+// ParseClaims decodes the raw claims of a token.  Numbers are kept as written so ids don't lose precision.
+func ParseClaims(raw []byte) (Claims, error) {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+
+	var claims Claims
+	if err := d.Decode(&claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Lookup returns the value at the dotted path.
+func (c Claims) Lookup(path string) (any, bool) {
+	var value any = map[string]any(c)
+	for _, name := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// String returns the value at the path if it's a string, number or boolean.
+func (c Claims) String(path string) (string, error) {
+	value, found := c.Lookup(path)
+	if !found {
+		return "", nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("claim %s isn't a string", path)
+}
+
+// Strings returns the value at the path as a list.  A single value is a list of one.
+func (c Claims) Strings(path string) ([]string, error) {
+	value, found := c.Lookup(path)
+	if !found {
+		return nil, nil
+	}
+
+	list, ok := value.([]any)
+	if !ok {
+		s, err := c.String(path)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+
+	var result []string
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("claim %s isn't a list of strings", path)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Bool returns the value at the path if it's a boolean or "true" or "false".
+func (c Claims) Bool(path string) (bool, error) {
+	value, found := c.Lookup(path)
+	if !found {
+		return false, nil
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("claim %s isn't a boolean", path)
+}
+
+// Identity maps the claims to an Identity.  It returns an error if a required claim is missing or a claim has
+// the wrong type.
+func (o *ClaimOptions) Identity(claims Claims) (*api.Identity, error) {
+	for claim, want := range o.Required {
+		values, err := claims.Strings(claim)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(values, want) {
+			return nil, fmt.Errorf("claim %s must be %q", claim, want)
+		}
+	}
+
+	identity := &api.Identity{}
+
+	var err error
+	if identity.Principal, err = claims.String(o.Principal); err != nil {
+		return nil, err
+	}
+	if identity.Principal == "" {
+		return nil, fmt.Errorf("the token has no %s claim", o.Principal)
+	}
+
+	if o.Tenant != "" {
+		if identity.Tenant, err = claims.String(o.Tenant); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Groups != "" {
+		if identity.Groups, err = claims.Strings(o.Groups); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Type != "" {
+		if identity.Type, err = claims.String(o.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.IsReporter != "" {
+		if identity.IsReporter, err = claims.Bool(o.IsReporter); err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, nil
+}
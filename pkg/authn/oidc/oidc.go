@@ -3,7 +3,15 @@ package oidc
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
 
@@ -11,69 +19,201 @@ import (
 	"github.com/csams/common-inventory/pkg/authn/util"
 )
 
+const name = "oidc"
+
+const (
+	// discoveryTimeout bounds how long a request waits for the issuer's discovery document.
+	discoveryTimeout = 10 * time.Second
+
+	// discoveryRetryInterval keeps an unavailable issuer from being contacted on every request.
+	discoveryRetryInterval = 10 * time.Second
+)
+
+// OAuth2Authenticator verifies the tokens of a single issuer.  Tokens from other issuers are ignored, so several
+// can be chained.  The issuer's discovery document and keys are fetched when the first token arrives so an
+// unavailable issuer doesn't block startup.
 type OAuth2Authenticator struct {
 	CompletedConfig
+	Log *slog.Logger
 
 	ClientContext context.Context
-	Verifier      *coreosoidc.IDTokenVerifier
+
+	mu          sync.Mutex
+	verifier    *coreosoidc.IDTokenVerifier
+	issuer      string
+	lastAttempt time.Time
+	lastError   error
+}
+
+// Status reports whether the issuer has been discovered and the last discovery error, if any.
+type Status struct {
+	Authenticator string `json:"authenticator"`
+	Issuer        string `json:"issuer"`
+	Discovered    bool   `json:"discovered"`
+	LastError     string `json:"last_error,omitempty"`
 }
 
-func New(c CompletedConfig) (*OAuth2Authenticator, error) {
+func New(c CompletedConfig, log *slog.Logger) *OAuth2Authenticator {
 	// this allows us to test locally against KeyCloak or something using an http client that doesn't check
 	// serving certs
 	ctx := coreosoidc.ClientContext(context.Background(), c.Client)
-	provider, err := coreosoidc.NewProvider(ctx, c.AuthorizationServerURL)
-	if err != nil {
-		return nil, err
-	}
 
-	oidcConfig := &coreosoidc.Config{ClientID: c.ClientId}
 	return &OAuth2Authenticator{
 		CompletedConfig: c,
+		Log:             log,
 		ClientContext:   ctx,
-		Verifier:        provider.Verifier(oidcConfig),
-	}, nil
-
+	}
 }
 
-func (o *OAuth2Authenticator) Authenticate(r *http.Request) (*api.Identity, api.Decision) {
+func (o *OAuth2Authenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
 	// get the token from the request
 	rawToken := util.GetBearerToken(r)
 
-	// ensure we got one
-	if rawToken == "" {
-		return nil, api.Ignore
+	// leave opaque tokens, keys and other issuers' tokens to other authenticators
+	if issuer, err := UnverifiedIssuer(rawToken); err != nil || NormalizeIssuer(issuer) != NormalizeIssuer(o.AuthorizationServerURL) {
+		return nil, api.Ignored
 	}
 
-	// verify and parse it
+	// verify and parse it.  The verifier checks that the client id is one of the audiences.
 	tok, err := o.Verify(rawToken)
 	if err != nil {
-		return nil, api.Deny
+		var expired *coreosoidc.TokenExpiredError
+		if errors.As(err, &expired) {
+			return nil, api.Denied(name, "The token expired")
+		}
+		return nil, api.Denied(name, "The token couldn't be verified")
+	}
+
+	var raw json.RawMessage
+	if err := tok.Claims(&raw); err != nil {
+		return nil, api.Denied(name, "The token's claims are invalid")
+	}
+
+	claims, err := ParseClaims(raw)
+	if err != nil {
+		return nil, api.Denied(name, "The token's claims are invalid")
+	}
+
+	identity, err := o.Claims.Identity(claims)
+	if err != nil {
+		return nil, api.Denied(name, fmt.Sprintf("The token's claims are invalid: %v", err))
+	}
+
+	return identity, api.Allowed(name)
+}
+
+func (o *OAuth2Authenticator) Verify(token string) (*coreosoidc.IDToken, error) {
+	verifier, err := o.Verifier()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := verifier.Verify(o.ClientContext, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// the verifier doesn't check the issuer because the configured URL may not be written exactly like it
+	o.mu.Lock()
+	issuer := o.issuer
+	o.mu.Unlock()
+	if tok.Issuer != issuer {
+		return nil, fmt.Errorf("the token was issued by %q, not %q", tok.Issuer, issuer)
+	}
+	return tok, nil
+}
+
+// Verifier returns the issuer's verifier, discovering the issuer if it hasn't been yet.
+func (o *OAuth2Authenticator) Verifier() (*coreosoidc.IDTokenVerifier, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.verifier != nil {
+		return o.verifier, nil
+	}
+
+	if o.lastError != nil && time.Since(o.lastAttempt) < discoveryRetryInterval {
+		return nil, o.lastError
+	}
+
+	o.lastAttempt = time.Now()
+
+	ctx, cancel := context.WithTimeout(o.ClientContext, discoveryTimeout)
+	defer cancel()
+
+	issuer, provider, err := discover(ctx, o.AuthorizationServerURL)
+	if err != nil {
+		o.lastError = err
+		o.Log.Error(fmt.Sprintf("Failed to discover OIDC issuer %s: %v", o.AuthorizationServerURL, err))
+		return nil, err
 	}
 
-	// TODO: make JWT claim fields configurable
-	// extract the claims we care about
-	u := &Claims{}
-	tok.Claims(u)
-	if u.Id == "" {
-		return nil, api.Deny
+	// keys are fetched when the first token is verified
+	o.verifier = provider.Verifier(&coreosoidc.Config{ClientID: o.ClientId, SkipIssuerCheck: true})
+	o.issuer = issuer
+	o.lastError = nil
+	return o.verifier, nil
+}
+
+func (o *OAuth2Authenticator) Status() any {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	status := Status{
+		Authenticator: name,
+		Issuer:        o.AuthorizationServerURL,
+		Discovered:    o.verifier != nil,
 	}
+	if o.lastError != nil {
+		status.LastError = o.lastError.Error()
+	}
+	return status
+}
 
-	if u.Audience != o.CompletedConfig.ClientId {
-		return nil, api.Deny
+// discover fetches the discovery document of the issuer at the URL and returns the issuer it names, which must be
+// the URL up to a trailing slash.
+func discover(ctx context.Context, url string) (string, *coreosoidc.Provider, error) {
+	// go-oidc wants the URL written exactly like the issuer, so check it here instead
+	provider, err := coreosoidc.NewProvider(coreosoidc.InsecureIssuerURLContext(ctx, url), url)
+	if err != nil {
+		return "", nil, err
 	}
 
-	// TODO: What are the tenant and group claims?
-	return &api.Identity{Principal: u.Id}, api.Allow
+	var doc struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := provider.Claims(&doc); err != nil {
+		return "", nil, err
+	}
+	if NormalizeIssuer(doc.Issuer) != NormalizeIssuer(url) {
+		return "", nil, fmt.Errorf("the discovery document names issuer %q instead of %q", doc.Issuer, url)
+	}
+	return doc.Issuer, provider, nil
 }
 
-// TODO: make JWT claim fields configurable
-// Claims holds the values we want to extract from the JWT.
-type Claims struct {
-	Id       string `json:"preferred_username"`
-	Audience string `json:"aud"`
+// NormalizeIssuer drops the trailing slash from an issuer URL so URLs that only differ by one compare equal.
+func NormalizeIssuer(url string) string {
+	return strings.TrimSuffix(url, "/")
 }
 
-func (l *OAuth2Authenticator) Verify(token string) (*coreosoidc.IDToken, error) {
-	return l.Verifier.Verify(l.ClientContext, token)
+// UnverifiedIssuer returns the iss claim of a JWT without verifying the token.  It's only good for choosing the
+// verifier.
+func UnverifiedIssuer(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", err
+	}
+	return claims.Issuer, nil
 }
@@ -0,0 +1,383 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+)
+
+// testIssuer is an in-process OIDC issuer that serves discovery and keys and signs tokens with RS256.
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	// issuer is the issuer the discovery document names.  It's the server's URL unless a test changes it.
+	issuer      string
+	discoveries atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &testIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		i.discoveries.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                i.issuer,
+			"jwks_uri":                              i.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{map[string]any{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	i.Server = httptest.NewServer(mux)
+	i.issuer = i.URL
+	t.Cleanup(i.Close)
+	return i
+}
+
+// token signs the claims.  iss, aud and exp default to the issuer, "inventory" and an hour from now.
+func (i *testIssuer) token(t *testing.T, claims map[string]any) string {
+	all := map[string]any{
+		"iss": i.issuer,
+		"aud": "inventory",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "test"})
+	payload, err := json.Marshal(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newAuthenticator(t *testing.T, url string, claims *ClaimOptions) *OAuth2Authenticator {
+	o := NewOptions()
+	o.ClientId = "inventory"
+	o.AuthorizationServerURL = url
+	if claims != nil {
+		o.Claims = claims
+	}
+	if errs := o.Complete(); errs != nil {
+		t.Fatal(errs)
+	}
+	if errs := o.Validate(); errs != nil {
+		t.Fatal(errs)
+	}
+
+	c, err := NewConfig(o).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(c, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func authenticate(o *OAuth2Authenticator, token string) (*api.Identity, api.Result) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return o.Authenticate(r)
+}
+
+func TestAuthenticate(t *testing.T) {
+	issuer := newTestIssuer(t)
+	o := newAuthenticator(t, issuer.URL, &ClaimOptions{
+		Principal:  "preferred_username",
+		Tenant:     "org.id",
+		Groups:     "realm_access.roles",
+		Type:       "reporter_type",
+		IsReporter: "is_reporter",
+	})
+
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		decision api.Decision
+		want     api.Identity
+	}{
+		{
+			name: "mapped claims",
+			claims: map[string]any{
+				"preferred_username": "alice",
+				"org":                map[string]any{"id": 12345678901234567},
+				"realm_access":       map[string]any{"roles": []string{"admin", "reader"}},
+				"reporter_type":      "ACM",
+				"is_reporter":        "true",
+			},
+			decision: api.Allow,
+			want: api.Identity{
+				Principal:  "alice",
+				Tenant:     "12345678901234567",
+				Groups:     []string{"admin", "reader"},
+				Type:       "ACM",
+				IsReporter: true,
+			},
+		},
+		{
+			name:     "array audience",
+			claims:   map[string]any{"preferred_username": "bob", "aud": []string{"other", "inventory"}},
+			decision: api.Allow,
+			want:     api.Identity{Principal: "bob"},
+		},
+		{
+			name:     "wrong audience",
+			claims:   map[string]any{"preferred_username": "bob", "aud": []string{"other"}},
+			decision: api.Deny,
+		},
+		{
+			name:     "expired",
+			claims:   map[string]any{"preferred_username": "bob", "exp": time.Now().Add(-time.Hour).Unix()},
+			decision: api.Deny,
+		},
+		{
+			name:     "no principal",
+			claims:   map[string]any{"sub": "bob"},
+			decision: api.Deny,
+		},
+		{
+			name:     "wrongly typed claim",
+			claims:   map[string]any{"preferred_username": "bob", "is_reporter": "maybe"},
+			decision: api.Deny,
+		},
+		{
+			name:     "other issuer",
+			claims:   map[string]any{"preferred_username": "bob", "iss": "https://elsewhere.example.com"},
+			decision: api.Ignore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, result := authenticate(o, issuer.token(t, tt.claims))
+			if result.Decision != tt.decision {
+				t.Fatalf("got %s (%s), want %s", result.Decision, result.Reason, tt.decision)
+			}
+			if tt.decision != api.Allow {
+				return
+			}
+
+			if !equalIdentities(identity, &tt.want) {
+				t.Errorf("got %+v, want %+v", identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRejectsOtherKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	forger := newTestIssuer(t)
+	forger.issuer = issuer.URL
+	o := newAuthenticator(t, issuer.URL, nil)
+
+	_, result := authenticate(o, forger.token(t, map[string]any{"preferred_username": "mallory"}))
+	if result.Decision != api.Deny {
+		t.Fatalf("got %s, want %s", result.Decision, api.Deny)
+	}
+}
+
+func TestAuthenticateIgnoresOpaqueTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	o := newAuthenticator(t, issuer.URL, nil)
+
+	if _, result := authenticate(o, "not-a-jwt"); result.Decision != api.Ignore {
+		t.Fatalf("got %s, want %s", result.Decision, api.Ignore)
+	}
+	if n := issuer.discoveries.Load(); n != 0 {
+		t.Errorf("opaque tokens shouldn't discover the issuer, but it was discovered %d times", n)
+	}
+}
+
+func TestLazyDiscovery(t *testing.T) {
+	issuer := newTestIssuer(t)
+	o := newAuthenticator(t, issuer.URL, nil)
+
+	if n := issuer.discoveries.Load(); n != 0 {
+		t.Fatalf("New discovered the issuer %d times", n)
+	}
+	if o.Status().(Status).Discovered {
+		t.Fatal("the status says the issuer was discovered before any token arrived")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, result := authenticate(o, issuer.token(t, map[string]any{"preferred_username": "alice"})); result.Decision != api.Allow {
+			t.Fatalf("got %s (%s), want %s", result.Decision, result.Reason, api.Allow)
+		}
+	}
+	if n := issuer.discoveries.Load(); n != 1 {
+		t.Errorf("the issuer was discovered %d times, want once", n)
+	}
+	if !o.Status().(Status).Discovered {
+		t.Error("the status doesn't say the issuer was discovered")
+	}
+}
+
+func TestFailedDiscoveryIsRetriedLater(t *testing.T) {
+	issuer := newTestIssuer(t)
+	url := issuer.URL
+	issuer.Close()
+
+	o := newAuthenticator(t, url, nil)
+	_, err := o.Verifier()
+	if err == nil {
+		t.Fatal("expected discovery of a stopped issuer to fail")
+	}
+	status := o.Status().(Status)
+	if status.Discovered || status.LastError == "" {
+		t.Errorf("the status doesn't report the failure: %+v", status)
+	}
+
+	// the issuer isn't contacted again until the retry interval passes
+	if _, again := o.Verifier(); again != err {
+		t.Errorf("got %v, want the error of the first attempt", again)
+	}
+}
+
+func TestIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.issuer = "https://impostor.example.com"
+	o := newAuthenticator(t, issuer.URL, nil)
+
+	_, err := o.Verifier()
+	if err == nil || !strings.Contains(err.Error(), "impostor") {
+		t.Fatalf("got %v, want an error naming the other issuer", err)
+	}
+}
+
+func TestIssuerTrailingSlash(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured func(url string) string
+		named      func(url string) string
+	}{
+		{"configured with a slash", func(url string) string { return url + "/" }, func(url string) string { return url }},
+		{"issued with a slash", func(url string) string { return url }, func(url string) string { return url + "/" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			issuer.issuer = tt.named(issuer.URL)
+			o := newAuthenticator(t, tt.configured(issuer.URL), nil)
+
+			identity, result := authenticate(o, issuer.token(t, map[string]any{"preferred_username": "alice"}))
+			if result.Decision != api.Allow {
+				t.Fatalf("got %s (%s), want %s", result.Decision, result.Reason, api.Allow)
+			}
+			if identity.Principal != "alice" {
+				t.Errorf("got principal %q, want alice", identity.Principal)
+			}
+
+			// the token must still name the issuer exactly like its discovery document does
+			issuer.issuer = tt.configured(issuer.URL)
+			if issuer.issuer != tt.named(issuer.URL) {
+				if _, err := o.Verify(issuer.token(t, map[string]any{"preferred_username": "alice"})); err == nil {
+					t.Error("expected a token that names the issuer differently from its discovery document to fail")
+				}
+			}
+		})
+	}
+}
+
+func TestRequiredClaims(t *testing.T) {
+	o := &ClaimOptions{Principal: "sub", Required: map[string]string{"realm_access.roles": "inventory", "env": "prod"}}
+
+	tests := []struct {
+		name   string
+		claims string
+		ok     bool
+	}{
+		{"present", `{"sub": "a", "realm_access": {"roles": ["x", "inventory"]}, "env": "prod"}`, true},
+		{"missing from the list", `{"sub": "a", "realm_access": {"roles": ["x"]}, "env": "prod"}`, false},
+		{"wrong value", `{"sub": "a", "realm_access": {"roles": ["inventory"]}, "env": "dev"}`, false},
+		{"missing", `{"sub": "a", "realm_access": {"roles": ["inventory"]}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseClaims([]byte(tt.claims))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = o.Identity(claims)
+			if (err == nil) != tt.ok {
+				t.Errorf("got %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClaimTypes(t *testing.T) {
+	claims, err := ParseClaims([]byte(`{"n": 12345678901234567, "s": "one", "l": ["a", "b"], "b": false, "o": {"k": "v"}, "mixed": ["a", 1]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := claims.String("n"); err != nil || s != "12345678901234567" {
+		t.Errorf("String(n) = %q, %v", s, err)
+	}
+	if l, err := claims.Strings("s"); err != nil || len(l) != 1 || l[0] != "one" {
+		t.Errorf("Strings(s) = %q, %v", l, err)
+	}
+	if l, err := claims.Strings("l"); err != nil || len(l) != 2 {
+		t.Errorf("Strings(l) = %q, %v", l, err)
+	}
+	if b, err := claims.Bool("b"); err != nil || b {
+		t.Errorf("Bool(b) = %v, %v", b, err)
+	}
+	if s, err := claims.String("o.k"); err != nil || s != "v" {
+		t.Errorf("String(o.k) = %q, %v", s, err)
+	}
+	if s, err := claims.String("o.missing"); err != nil || s != "" {
+		t.Errorf("String(o.missing) = %q, %v", s, err)
+	}
+	if _, err := claims.String("o"); err == nil {
+		t.Error("String(o) should fail for an object")
+	}
+	if _, err := claims.Strings("mixed"); err == nil {
+		t.Error("Strings(mixed) should fail for a list with a number")
+	}
+}
+
+func equalIdentities(a *api.Identity, b *api.Identity) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
@@ -8,10 +8,14 @@ type Options struct {
 	ClientId               string `mapstructure:"client-id"`
 	AuthorizationServerURL string `mapstructure:"authn-server-url"`
 	InsecureClient         bool   `mapstructure:"insecure-client"`
+
+	Claims *ClaimOptions `mapstructure:"claims"`
 }
 
 func NewOptions() *Options {
-	return &Options{}
+	return &Options{
+		Claims: NewClaimOptions(),
+	}
 }
 
 func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
@@ -21,12 +25,20 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 	fs.StringVar(&o.ClientId, prefix+"client-id", "", "the clientId issued by the authorization server that represents the application")
 	fs.StringVar(&o.AuthorizationServerURL, prefix+"authn-server-url", "", "the URL to the authorization server")
 	fs.BoolVarP(&o.InsecureClient, prefix+"insecure-client", "k", false, "validate authorization server certs?")
+	o.Claims.AddFlags(fs, prefix+"claims")
 }
 
 func (o *Options) Validate() []error {
-	return nil
+	return o.Claims.Validate()
 }
 
 func (o *Options) Complete() []error {
+	// providers from a list in the config file don't start from NewOptions
+	if o.Claims == nil {
+		o.Claims = NewClaimOptions()
+	}
+	if o.Claims.Principal == "" {
+		o.Claims.Principal = NewClaimOptions().Principal
+	}
 	return nil
 }
@@ -1,20 +1,38 @@
 package authn
 
 import (
+	"fmt"
+
+	"github.com/csams/common-inventory/pkg/authn/apikey"
+	"github.com/csams/common-inventory/pkg/authn/clientcert"
+	"github.com/csams/common-inventory/pkg/authn/guest"
+	"github.com/csams/common-inventory/pkg/authn/introspection"
 	"github.com/csams/common-inventory/pkg/authn/oidc"
 	"github.com/csams/common-inventory/pkg/authn/psk"
 	"github.com/spf13/pflag"
 )
 
 type Options struct {
-	Oidc          *oidc.Options `mapstructure:"oidc"`
-	PreSharedKeys *psk.Options  `mapstructure:"psk"`
+	Oidc *oidc.Options `mapstructure:"oidc"`
+
+	// OidcProviders are more OIDC issuers.  They can only be set in the config file.
+	OidcProviders []*oidc.Options `mapstructure:"oidc-providers"`
+
+	PreSharedKeys *psk.Options           `mapstructure:"psk"`
+	ApiKeys       *apikey.Options        `mapstructure:"apikey"`
+	Introspection *introspection.Options `mapstructure:"introspection"`
+	ClientCert    *clientcert.Options    `mapstructure:"clientcert"`
+	Guest         *guest.Options         `mapstructure:"guest"`
 }
 
 func NewOptions() *Options {
 	return &Options{
 		Oidc:          oidc.NewOptions(),
 		PreSharedKeys: psk.NewOptions(),
+		ApiKeys:       apikey.NewOptions(),
+		Introspection: introspection.NewOptions(),
+		ClientCert:    clientcert.NewOptions(),
+		Guest:         guest.NewOptions(),
 	}
 }
 
@@ -25,13 +43,33 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 
 	o.Oidc.AddFlags(fs, prefix+"oidc")
 	o.PreSharedKeys.AddFlags(fs, prefix+"psk")
+	o.ApiKeys.AddFlags(fs, prefix+"apikey")
+	o.Introspection.AddFlags(fs, prefix+"introspection")
+	o.ClientCert.AddFlags(fs, prefix+"clientcert")
+	o.Guest.AddFlags(fs, prefix+"guest")
 }
 
 func (o *Options) Validate() []error {
 	var errs []error
 
 	errs = append(errs, o.Oidc.Validate()...)
+
+	issuers := map[string]bool{oidc.NormalizeIssuer(o.Oidc.AuthorizationServerURL): o.Oidc.AuthorizationServerURL != ""}
+	for i, p := range o.OidcProviders {
+		if p.AuthorizationServerURL == "" || p.ClientId == "" {
+			errs = append(errs, fmt.Errorf("oidc-providers[%d] needs an authn-server-url and a client-id", i))
+		}
+		if issuers[oidc.NormalizeIssuer(p.AuthorizationServerURL)] {
+			errs = append(errs, fmt.Errorf("oidc-providers[%d]: the issuer %s is configured more than once", i, p.AuthorizationServerURL))
+		}
+		issuers[oidc.NormalizeIssuer(p.AuthorizationServerURL)] = true
+		errs = append(errs, p.Validate()...)
+	}
 	errs = append(errs, o.PreSharedKeys.Validate()...)
+	errs = append(errs, o.ApiKeys.Validate()...)
+	errs = append(errs, o.Introspection.Validate()...)
+	errs = append(errs, o.ClientCert.Validate()...)
+	errs = append(errs, o.Guest.Validate()...)
 
 	return errs
 }
@@ -40,7 +78,14 @@ func (o *Options) Complete() []error {
 	var errs []error
 
 	errs = append(errs, o.Oidc.Complete()...)
+	for _, p := range o.OidcProviders {
+		errs = append(errs, p.Complete()...)
+	}
 	errs = append(errs, o.PreSharedKeys.Complete()...)
+	errs = append(errs, o.ApiKeys.Complete()...)
+	errs = append(errs, o.Introspection.Complete()...)
+	errs = append(errs, o.ClientCert.Complete()...)
+	errs = append(errs, o.Guest.Complete()...)
 
 	return errs
 }
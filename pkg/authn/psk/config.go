@@ -1,19 +1,26 @@
 package psk
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/csams/common-inventory/pkg/authn/util"
 )
 
 type Config struct {
 	PreSharedKeyFile string
-	Keys             IdentityMap
+	Watch            bool
+	Keys             KeyMap
 }
 
 type completedConfig struct {
-	Keys IdentityMap
+	PreSharedKeyFile string
+	Watch            bool
+	Keys             KeyMap
 }
 
 type CompletedConfig struct {
@@ -23,35 +30,67 @@ type CompletedConfig struct {
 func NewConfig(o *Options) *Config {
 	return &Config{
 		PreSharedKeyFile: o.PreSharedKeyFile,
+		Watch:            o.Watch,
 	}
 }
 
 func (c *Config) Complete() (CompletedConfig, error) {
 	if len(c.Keys) == 0 {
-		if err := c.loadPreSharedKeys(); err != nil {
+		keys, err := LoadPreSharedKeys(c.PreSharedKeyFile)
+		if err != nil {
 			return CompletedConfig{}, err
 		}
+		c.Keys = keys
 	}
 
 	return CompletedConfig{&completedConfig{
-		Keys: c.Keys,
+		PreSharedKeyFile: c.PreSharedKeyFile,
+		Watch:            c.Watch,
+		Keys:             c.Keys,
 	}}, nil
 }
 
-func (c *Config) loadPreSharedKeys() error {
-	// TODO: fsnotify to reload the keys when the PSK file changes.
-	if file, err := os.Open(c.PreSharedKeyFile); err == nil {
-		defer file.Close()
-		data, err := io.ReadAll(file)
-		if err == nil {
-			if err := yaml.Unmarshal(data, &c.Keys); err != nil {
-				return err
+// LoadPreSharedKeys reads and validates the keys in a pre-shared key file.
+func LoadPreSharedKeys(path string) (KeyMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys KeyMap
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	for id, key := range keys {
+		if len(id) == 0 {
+			return nil, fmt.Errorf("%s: pre-shared keys must not be empty", path)
+		}
+
+		if len(key.Principal) == 0 {
+			return nil, fmt.Errorf("%s: an identity has no principal", path)
+		}
+
+		if key.Hash != "" {
+			// hashed keys are presented as <id>.<secret>, so the id is split off at the first dot
+			if strings.Contains(id, ".") {
+				return nil, fmt.Errorf("%s: the id of the hashed key for %s must not contain '.'", path, key.Principal)
 			}
-		} else {
-			return err
+			if err := util.ValidateHash(key.Hash); err != nil {
+				return nil, fmt.Errorf("%s: the hashed key for %s is invalid: %w", path, key.Principal, err)
+			}
+		}
+
+		if key.NotBefore != nil && key.ExpiresAt != nil && !key.ExpiresAt.After(*key.NotBefore) {
+			return nil, fmt.Errorf("%s: the key for %s expires before it becomes valid", path, key.Principal)
 		}
-	} else {
-		return err
 	}
-	return nil
+
+	return keys, nil
 }
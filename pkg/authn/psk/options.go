@@ -6,10 +6,13 @@ import (
 
 type Options struct {
 	PreSharedKeyFile string `mapstructure:"pre-shared-key-file"`
+	Watch            bool   `mapstructure:"watch"`
 }
 
 func NewOptions() *Options {
-	return &Options{}
+	return &Options{
+		Watch: true,
+	}
 }
 
 func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
@@ -17,6 +20,7 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 		prefix = prefix + "."
 	}
 	fs.StringVar(&o.PreSharedKeyFile, prefix+"pre-shared-key-file", "", "A file of identities with pre-shared keys that allow them to authenticate.")
+	fs.BoolVar(&o.Watch, prefix+"watch", o.Watch, "Reload the pre-shared key file when it changes.")
 }
 
 func (o *Options) Validate() []error {
@@ -2,36 +2,225 @@
 package psk
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 
 	"github.com/csams/common-inventory/pkg/authn/api"
 	"github.com/csams/common-inventory/pkg/authn/util"
 )
 
-type IdentityMap map[string]api.Identity
+// KeyMap holds the keys from the pre-shared key file.  Plaintext keys are their own map key.  Hashed keys are
+// stored under an id, and callers present them as <id>.<secret>.
+type KeyMap map[string]Key
+
+// Key is an identity and the constraints on the key that authenticates as it.
+type Key struct {
+	api.Identity `yaml:",inline"`
+
+	// Hash of the secret.  See util.HashSecret for the supported formats.  Empty means the map key is the plaintext key.
+	Hash string `yaml:"hash"`
+
+	// The key is only valid between these times.  Nil means unbounded.
+	NotBefore *time.Time `yaml:"not_before"`
+	ExpiresAt *time.Time `yaml:"expires_at"`
+}
+
+const name = "psk"
+
+// reloadDelay lets a burst of file events from a single write settle before the file is read.
+const reloadDelay = 100 * time.Millisecond
 
 type PreSharedKeyAuthenticator struct {
-	Store IdentityMap
+	File string
+	Log  *slog.Logger
+
+	// store is swapped as a whole on reload so requests never see a partially loaded map.
+	store atomic.Pointer[keyStore]
+
+	mu          sync.Mutex
+	lastError   error
+	lastErrorAt time.Time
+
+	// watcher is nil unless the file is watched
+	watcher *fsnotify.Watcher
+}
+
+type keyStore struct {
+	Keys       KeyMap
+	Generation int64
+	LoadedAt   time.Time
 }
 
-func New(config CompletedConfig) *PreSharedKeyAuthenticator {
-	return &PreSharedKeyAuthenticator{Store: config.Keys}
+// Status reports which generation of the key file is in use and the last reload error, if any.
+type Status struct {
+	Authenticator string     `json:"authenticator"`
+	File          string     `json:"file"`
+	Keys          int        `json:"keys"`
+	Generation    int64      `json:"generation"`
+	LoadedAt      time.Time  `json:"loaded_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+func New(config CompletedConfig, log *slog.Logger) (*PreSharedKeyAuthenticator, error) {
+	a := &PreSharedKeyAuthenticator{
+		File: config.PreSharedKeyFile,
+		Log:  log,
+	}
+	a.store.Store(&keyStore{Keys: config.Keys, Generation: 1, LoadedAt: time.Now().UTC()})
+
+	if config.Watch && config.PreSharedKeyFile != "" {
+		if err := a.watch(); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
 }
 
-func (a *PreSharedKeyAuthenticator) Lookup(key string) *api.Identity {
-	if len(key) > 0 {
-		if identity, found := a.Store[key]; found {
-			return &identity
+// Lookup finds the key for a token.  It returns nil if the token doesn't match a key.
+func (a *PreSharedKeyAuthenticator) Lookup(token string) *Key {
+	if len(token) == 0 {
+		return nil
+	}
+
+	keys := a.store.Load().Keys
+
+	if id, secret, found := strings.Cut(token, "."); found {
+		if key, found := keys[id]; found && key.Hash != "" && util.VerifyHash(key.Hash, secret) {
+			return &key
 		}
 	}
+
+	if key, found := keys[token]; found && key.Hash == "" {
+		return &key
+	}
+
 	return nil
 }
 
-func (a *PreSharedKeyAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Decision) {
+func (a *PreSharedKeyAuthenticator) Authenticate(r *http.Request) (*api.Identity, api.Result) {
 	token := util.GetBearerToken(r)
-	identity := a.Lookup(token)
-	if identity != nil {
-		return identity, api.Allow
+	key := a.Lookup(token)
+	if key == nil {
+		return nil, api.Ignored
+	}
+
+	now := time.Now()
+	if key.NotBefore != nil && now.Before(*key.NotBefore) {
+		return nil, api.Denied(name, "The pre-shared key isn't valid yet")
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, api.Denied(name, "The pre-shared key expired")
+	}
+
+	identity := key.Identity
+	return &identity, api.Allowed(name)
+}
+
+func (a *PreSharedKeyAuthenticator) Status() any {
+	store := a.store.Load()
+	status := Status{
+		Authenticator: name,
+		File:          a.File,
+		Keys:          len(store.Keys),
+		Generation:    store.Generation,
+		LoadedAt:      store.LoadedAt,
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.lastError != nil {
+		at := a.lastErrorAt
+		status.LastError = a.lastError.Error()
+		status.LastErrorAt = &at
+	}
+
+	return status
+}
+
+// Reload reads the key file and swaps in its keys.  The current keys are kept if the file is invalid.
+func (a *PreSharedKeyAuthenticator) Reload() error {
+	keys, err := LoadPreSharedKeys(a.File)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err != nil {
+		a.lastError = err
+		a.lastErrorAt = time.Now().UTC()
+		return err
+	}
+
+	current := a.store.Load()
+	a.store.Store(&keyStore{Keys: keys, Generation: current.Generation + 1, LoadedAt: time.Now().UTC()})
+	a.lastError = nil
+	return nil
+}
+
+// watch reloads the keys whenever the file changes.  The directory is watched rather than the file so
+// replacements by rename and kubernetes' ..data symlink swaps for mounted secrets are seen too.
+func (a *PreSharedKeyAuthenticator) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Clean(a.File)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return err
+	}
+	a.watcher = watcher
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) != file && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, a.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				a.Log.Error(fmt.Sprintf("Error watching pre-shared key file %s: %v", a.File, err))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops watching the file.
+func (a *PreSharedKeyAuthenticator) Stop() {
+	if a.watcher != nil {
+		a.watcher.Close()
+	}
+}
+
+func (a *PreSharedKeyAuthenticator) reload() {
+	if err := a.Reload(); err != nil {
+		a.Log.Error(fmt.Sprintf("Keeping the current pre-shared keys.  Failed to reload %s: %v", a.File, err))
+		return
 	}
-	return nil, api.Ignore
+	a.Log.Info(fmt.Sprintf("Reloaded pre-shared keys from %s (generation %d)", a.File, a.store.Load().Generation))
 }
@@ -0,0 +1,214 @@
+package psk
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/util"
+)
+
+func newAuthenticator(t *testing.T, keys KeyMap) *PreSharedKeyAuthenticator {
+	config, err := (&Config{Keys: keys}).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Stop)
+	return a
+}
+
+func hash(t *testing.T, algorithm string, secret string) string {
+	h, err := util.HashSecret(algorithm, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func authenticate(a *PreSharedKeyAuthenticator, token string) (*api.Identity, api.Result) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return a.Authenticate(r)
+}
+
+func TestLookup(t *testing.T) {
+	a := newAuthenticator(t, KeyMap{
+		"1234":     {Identity: api.Identity{Principal: "user@example.com"}},
+		"acm.hub":  {Identity: api.Identity{Principal: "dotted"}},
+		"acm-hub":  {Identity: api.Identity{Principal: "acm-hub-1"}, Hash: hash(t, util.SHA256, "s3cret")},
+		"ocm-prod": {Identity: api.Identity{Principal: "ocm-1"}, Hash: hash(t, util.Bcrypt, "s3cret")},
+	})
+
+	tests := []struct {
+		token     string
+		principal string
+	}{
+		{"1234", "user@example.com"},
+		{"acm.hub", "dotted"},
+		{"acm-hub.s3cret", "acm-hub-1"},
+		{"ocm-prod.s3cret", "ocm-1"},
+		// a hashed key's id isn't a key, and the secret has to match its hash
+		{"acm-hub", ""},
+		{"acm-hub.wrong", ""},
+		{"ocm-prod.wrong", ""},
+		{"4321", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		key := a.Lookup(test.token)
+		switch {
+		case test.principal == "" && key != nil:
+			t.Errorf("%q: expected no key, got %s", test.token, key.Principal)
+		case test.principal != "" && (key == nil || key.Principal != test.principal):
+			t.Errorf("%q: expected the key of %s, got %v", test.token, test.principal, key)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	a := newAuthenticator(t, KeyMap{
+		"current": {Identity: api.Identity{Principal: "current"}, NotBefore: &past, ExpiresAt: &future},
+		"early":   {Identity: api.Identity{Principal: "early"}, NotBefore: &future},
+		"expired": {Identity: api.Identity{Principal: "expired"}, ExpiresAt: &past},
+	})
+
+	tests := []struct {
+		token    string
+		decision api.Decision
+	}{
+		{"current", api.Allow},
+		{"early", api.Deny},
+		{"expired", api.Deny},
+		{"unknown", api.Ignore},
+	}
+
+	for _, test := range tests {
+		identity, result := authenticate(a, test.token)
+		if result.Decision != test.decision {
+			t.Errorf("%s: expected %s, got %+v", test.token, test.decision, result)
+		}
+		if test.decision == api.Allow && (identity == nil || identity.Principal != test.token) {
+			t.Errorf("%s: unexpected identity %+v", test.token, identity)
+		}
+	}
+}
+
+// newWatchingAuthenticator loads the keys file and reloads it when it changes.
+func newWatchingAuthenticator(t *testing.T, file string) *PreSharedKeyAuthenticator {
+	config, err := NewConfig(&Options{PreSharedKeyFile: file, Watch: true}).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Stop)
+	return a
+}
+
+func keysFile(principal string) []byte {
+	return []byte(fmt.Sprintf("%s:\n  principal: %s\n", principal, principal))
+}
+
+// waitFor polls the authenticator's status until the condition holds.
+func waitFor(t *testing.T, a *PreSharedKeyAuthenticator, condition func(Status) bool) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := a.Status().(Status)
+		if condition(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the keys to be reloaded: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "psks.yaml")
+	if err := os.WriteFile(file, keysFile("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := newWatchingAuthenticator(t, file)
+
+	// a valid change is swapped in
+	if err := os.WriteFile(file, keysFile("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, a, func(s Status) bool { return s.Generation == 2 })
+	if a.Lookup("first") != nil || a.Lookup("second") == nil {
+		t.Error("expected only the new key")
+	}
+
+	// an invalid file keeps the current keys
+	if err := os.WriteFile(file, []byte("second:\n  hash: nonsense\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	status := waitFor(t, a, func(s Status) bool { return s.LastError != "" })
+	if status.Generation != 2 || a.Lookup("second") == nil {
+		t.Errorf("expected the current keys to be kept, got %+v", status)
+	}
+
+	// a replacement by rename is seen and clears the error
+	tmp := filepath.Join(dir, "psks.yaml.tmp")
+	if err := os.WriteFile(tmp, keysFile("third"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+	status = waitFor(t, a, func(s Status) bool { return s.Generation == 3 })
+	if status.LastError != "" || a.Lookup("third") == nil {
+		t.Errorf("expected the renamed file's keys, got %+v", status)
+	}
+}
+
+// TestWatchMountedSecret swaps the ..data symlink like kubernetes does when a mounted secret changes.
+func TestWatchMountedSecret(t *testing.T) {
+	dir := t.TempDir()
+	mount := func(name string, principal string) {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "psks.yaml"), keysFile(principal), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(name, filepath.Join(dir, "..data_tmp")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mount("..2024_06_01", "first")
+	file := filepath.Join(dir, "psks.yaml")
+	if err := os.Symlink(filepath.Join("..data", "psks.yaml"), file); err != nil {
+		t.Fatal(err)
+	}
+	a := newWatchingAuthenticator(t, file)
+
+	mount("..2024_06_02", "second")
+	waitFor(t, a, func(s Status) bool { return s.Generation == 2 })
+	if a.Lookup("first") != nil || a.Lookup("second") == nil {
+		t.Error("expected the keys of the new secret")
+	}
+}
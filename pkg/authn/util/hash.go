@@ -0,0 +1,86 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported hash algorithms.  sha256 hashes look like sha256:<base64 salt>:<hex digest of salt+secret>.  bcrypt
+// hashes are in the usual $2b$ format.  bcrypt is deliberately slow, so prefer sha256 for high volume reporters
+// with long random secrets.
+const (
+	SHA256 = "sha256"
+	Bcrypt = "bcrypt"
+)
+
+// HashSecret returns a salted hash of the secret using the algorithm.
+func HashSecret(algorithm string, secret string) (string, error) {
+	switch algorithm {
+	case SHA256:
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		digest := sha256.Sum256(append(salt, secret...))
+		return fmt.Sprintf("%s:%s:%s", SHA256, base64.RawStdEncoding.EncodeToString(salt), hex.EncodeToString(digest[:])), nil
+	case Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		return string(hash), err
+	}
+	return "", fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+}
+
+// ValidateHash returns an error if the hash isn't in a supported format.
+func ValidateHash(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, SHA256+":"):
+		_, _, err := parseSHA256(hash)
+		return err
+	case strings.HasPrefix(hash, "$2"):
+		_, err := bcrypt.Cost([]byte(hash))
+		return err
+	}
+	return fmt.Errorf("unsupported hash format")
+}
+
+// VerifyHash reports whether the secret matches the hash.
+func VerifyHash(hash string, secret string) bool {
+	switch {
+	case strings.HasPrefix(hash, SHA256+":"):
+		salt, expected, err := parseSHA256(hash)
+		if err != nil {
+			return false
+		}
+		digest := sha256.Sum256(append(salt, secret...))
+		return subtle.ConstantTimeCompare(digest[:], expected) == 1
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+	}
+	return false
+}
+
+func parseSHA256(hash string) ([]byte, []byte, error) {
+	parts := strings.Split(hash, ":")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("sha256 hashes must look like sha256:<salt>:<digest>")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sha256 salt: %w", err)
+	}
+
+	digest, err := hex.DecodeString(parts[2])
+	if err != nil || len(digest) != sha256.Size {
+		return nil, nil, fmt.Errorf("invalid sha256 digest")
+	}
+
+	return salt, digest, nil
+}
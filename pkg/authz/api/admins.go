@@ -0,0 +1,31 @@
+package api
+
+import (
+	"slices"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+)
+
+// Admins are the identities allowed to use the administrative API, like issuing API keys.
+type Admins struct {
+	Principals []string
+	Groups     []string
+}
+
+func (a *Admins) IsAdmin(identity *authnapi.Identity) bool {
+	if a == nil || identity == nil || identity.IsGuest {
+		return false
+	}
+
+	if slices.Contains(a.Principals, identity.Principal) {
+		return true
+	}
+
+	for _, g := range identity.Groups {
+		if slices.Contains(a.Groups, g) {
+			return true
+		}
+	}
+
+	return false
+}
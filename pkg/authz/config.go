@@ -3,12 +3,16 @@ package authz
 import (
 	"context"
 
+	"github.com/csams/common-inventory/pkg/authz/api"
 	"github.com/csams/common-inventory/pkg/authz/kessel"
 )
 
 type Config struct {
 	Authz  string
 	Kessel *kessel.Config
+	Admins *api.Admins
+
+	RequireRegisteredReporters bool
 }
 
 func NewConfig(o *Options) *Config {
@@ -20,12 +24,20 @@ func NewConfig(o *Options) *Config {
 	return &Config{
 		Authz:  o.Authz,
 		Kessel: kcfg,
+		Admins: &api.Admins{
+			Principals: o.AdminPrincipals,
+			Groups:     o.AdminGroups,
+		},
+		RequireRegisteredReporters: o.RequireRegisteredReporters,
 	}
 }
 
 type completedConfig struct {
 	Authz  string
 	Kessel kessel.CompletedConfig
+	Admins *api.Admins
+
+	RequireRegisteredReporters bool
 }
 
 type CompletedConfig struct {
@@ -33,7 +45,7 @@ type CompletedConfig struct {
 }
 
 func (c *Config) Complete(ctx context.Context) (CompletedConfig, []error) {
-	cfg := &completedConfig{}
+	cfg := &completedConfig{Admins: c.Admins, RequireRegisteredReporters: c.RequireRegisteredReporters}
 
 	if c.Authz == Kessel {
 		if ksl, errs := c.Kessel.Complete(ctx); errs != nil {
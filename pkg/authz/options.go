@@ -12,6 +12,11 @@ import (
 type Options struct {
 	Authz  string          `mapstructure:"impl"`
 	Kessel *kessel.Options `mapstructure:"kessel"`
+
+	AdminPrincipals []string `mapstructure:"admin-principals"`
+	AdminGroups     []string `mapstructure:"admin-groups"`
+
+	RequireRegisteredReporters bool `mapstructure:"require-registered-reporters"`
 }
 
 const (
@@ -33,6 +38,11 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 
 	fs.StringVar(&o.Authz, prefix+"impl", o.Authz, "Authz impl to use.  Options are 'allow-all' and 'kessel'.")
 	o.Kessel.AddFlags(fs, prefix+"kessel")
+
+	fs.StringSliceVar(&o.AdminPrincipals, prefix+"admin-principals", o.AdminPrincipals, "Principals allowed to use the admin API.")
+	fs.StringSliceVar(&o.AdminGroups, prefix+"admin-groups", o.AdminGroups, "Members of these groups are allowed to use the admin API.")
+
+	fs.BoolVar(&o.RequireRegisteredReporters, prefix+"require-registered-reporters", o.RequireRegisteredReporters, "Only let reporters registered through the reporters API change resources.")
 }
 
 func (o *Options) Validate() []error {
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	authzapi "github.com/csams/common-inventory/pkg/authz/api"
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// AllResourcesController lists resources across every registered resource type.
+type AllResourcesController struct {
+	// BasePaths maps each registered resource type to the base path of its ResourceController.
+	BasePaths  map[string]string
+	Db         *gorm.DB
+	Authorizer authzapi.Authorizer
+	Log        *slog.Logger
+}
+
+func NewAllResourcesController(
+	basePaths map[string]string,
+	db *gorm.DB,
+	authorizer authzapi.Authorizer,
+	log *slog.Logger) *AllResourcesController {
+	return &AllResourcesController{
+		BasePaths:  basePaths,
+		Db:         db,
+		Authorizer: authorizer,
+		Log:        log,
+	}
+}
+
+func (c AllResourcesController) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(middleware.Pagination, middleware.Filtering).Get("/", c.List)
+
+	return r
+}
+
+func (c *AllResourcesController) List(w http.ResponseWriter, r *http.Request) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	pagination, err := middleware.GetPaginationRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	filter, err := middleware.GetFilterRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	var types []string
+	if resourceType := r.URL.Query().Get("resource_type"); resourceType != "" {
+		if _, found := c.BasePaths[resourceType]; !found {
+			writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("Unknown resource_type: %s", resourceType))
+			return
+		}
+		if !identity.Allows(resourceType, authnapi.VerbList) {
+			writeForbidden(w, r, resourceType, authnapi.VerbList)
+			return
+		}
+		types = []string{resourceType}
+	} else {
+		// only list the types the caller's scopes allow
+		for t := range c.BasePaths {
+			if identity.Allows(t, authnapi.VerbList) {
+				types = append(types, t)
+			}
+		}
+	}
+
+	// a new session so the count and the find don't share conditions
+	db := c.Db.Model(&models.Resource{}).Scopes(filter.Filter).Where("resource_type in ?", types).Session(&gorm.Session{})
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var results []models.Resource
+	if err := db.Scopes(pagination.Filter).Preload(clause.Associations).Order("id").Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var output []*models.ResourceOut
+	for _, result := range results {
+		r := result
+		href := fmt.Sprintf("%s/%d", c.BasePaths[result.ResourceType], result.ID)
+		out := models.NewResourceOut(&r, href)
+		output = append(output, out)
+	}
+
+	resp := &middleware.PagedResponse[*models.ResourceOut]{
+		PagedReponseMetadata: middleware.PagedReponseMetadata{
+			Page:  pagination.Page,
+			Size:  len(results),
+			Total: count,
+		},
+		Items: output,
+	}
+
+	render.JSON(w, r, resp)
+}
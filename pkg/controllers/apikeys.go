@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gorm.io/gorm"
+
+	"github.com/csams/common-inventory/pkg/authn/apikey"
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// ApiKeyController issues and manages the API keys authenticated by the apikey authenticator.  Secrets are only
+// returned when a key is issued or rotated.
+type ApiKeyController struct {
+	BasePath string
+	Db       *gorm.DB
+	Log      *slog.Logger
+}
+
+func NewApiKeyController(basePath string, db *gorm.DB, log *slog.Logger) *ApiKeyController {
+	return &ApiKeyController{
+		BasePath: basePath,
+		Db:       db,
+		Log:      log,
+	}
+}
+
+func (c ApiKeyController) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(middleware.Pagination).Get("/", c.List)
+	r.Post("/", c.Create)
+	r.Route("/{key_id}", func(r chi.Router) {
+		r.Get("/", c.Get)
+		r.Delete("/", c.Revoke)
+		r.Post("/rotate", c.Rotate)
+	})
+
+	return r
+}
+
+func (c *ApiKeyController) List(w http.ResponseWriter, r *http.Request) {
+	pagination, err := middleware.GetPaginationRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	var count int64
+	if err := c.Db.Model(&models.ApiKey{}).Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var results []models.ApiKey
+	if err := c.Db.Scopes(pagination.Filter).Order("id").Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var output []*models.ApiKeyOut
+	for _, result := range results {
+		k := result
+		output = append(output, models.NewApiKeyOut(&k, c.href(&k), ""))
+	}
+
+	resp := &middleware.PagedResponse[*models.ApiKeyOut]{
+		PagedReponseMetadata: middleware.PagedReponseMetadata{
+			Page:  pagination.Page,
+			Size:  len(results),
+			Total: count,
+		},
+		Items: output,
+	}
+
+	render.JSON(w, r, resp)
+}
+
+func (c *ApiKeyController) Get(w http.ResponseWriter, r *http.Request) {
+	key, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewApiKeyOut(key, c.href(key), ""))
+}
+
+func (c *ApiKeyController) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.ApiKeyIn
+	if err := render.Decode(r, &input); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("The request body is invalid: %v", err))
+		return
+	}
+
+	if errs := input.Validate(); errs != nil {
+		middleware.WriteProblem(w, r, cerrors.NewValidationProblem(errs))
+		return
+	}
+
+	keyId, secret, hash, err := apikey.Generate()
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, "Failed to generate a key")
+		return
+	}
+
+	key := models.NewApiKey(&input, keyId, hash)
+	if err := c.Db.Create(key).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	out := models.NewApiKeyOut(key, c.href(key), apikey.Token(keyId, secret))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, out)
+}
+
+// Rotate replaces the key's secret.  The old secret stops working once authenticators' caches expire.
+func (c *ApiKeyController) Rotate(w http.ResponseWriter, r *http.Request) {
+	key, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	if key.RevokedAt != nil {
+		writeProblem(w, r, http.StatusConflict, cerrors.CodeConflict, fmt.Sprintf("API key %s is revoked", key.KeyId))
+		return
+	}
+
+	_, secret, hash, err := apikey.Generate()
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, "Failed to generate a key")
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := c.Db.Model(key).Updates(models.ApiKey{Hash: hash, RotatedAt: &now}).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	render.JSON(w, r, models.NewApiKeyOut(key, c.href(key), apikey.Token(key.KeyId, secret)))
+}
+
+// Revoke disables the key.  The record is kept so it's clear who the key belonged to.
+func (c *ApiKeyController) Revoke(w http.ResponseWriter, r *http.Request) {
+	key, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		if err := c.Db.Model(key).Updates(models.ApiKey{RevokedAt: &now}).Error; err != nil {
+			writeDbProblem(w, r, err, "")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// find writes a problem and returns false if the key in the path doesn't exist.
+func (c *ApiKeyController) find(w http.ResponseWriter, r *http.Request) (*models.ApiKey, bool) {
+	keyId := chi.URLParam(r, "key_id")
+
+	key := &models.ApiKey{}
+	if err := c.Db.Where("key_id = ?", keyId).First(key).Error; err != nil {
+		writeDbProblem(w, r, err, fmt.Sprintf("No API key with id %s", keyId))
+		return nil, false
+	}
+
+	return key, true
+}
+
+func (c *ApiKeyController) href(key *models.ApiKey) string {
+	return fmt.Sprintf("%s/%s", c.BasePath, key.KeyId)
+}
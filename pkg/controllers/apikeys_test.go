@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/apikey"
+	"github.com/csams/common-inventory/pkg/authn/delegator"
+	"github.com/csams/common-inventory/pkg/authn/psk"
+	authzapi "github.com/csams/common-inventory/pkg/authz/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+func TestApiKeys(t *testing.T) {
+	const cacheTTL = 100 * time.Millisecond
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := newTestDb(t)
+
+	pskConfig, err := (&psk.Config{Keys: psk.KeyMap{
+		"admin": {Identity: authnapi.Identity{Principal: "admin@example.com"}},
+		"user":  {Identity: authnapi.Identity{Principal: "user@example.com"}},
+	}}).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	psks, err := psk.New(pskConfig, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikeyConfig, err := apikey.NewConfig(&apikey.Options{CacheTTL: cacheTTL}).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := delegator.New()
+	authenticator.Add(psks)
+	authenticator.Add(apikey.New(apikeyConfig, db, log))
+
+	srv := newTestServer(t, db, authenticator, &authzapi.Admins{Principals: []string{"admin@example.com"}})
+	keys := srv.URL + BasePath + "/admin/apikeys"
+	clusters := srv.URL + BasePath + "/resources/clusters"
+
+	expect := func(resp *http.Response, status int) []byte {
+		t.Helper()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Fatalf("%s %s: expected %d, got %d: %s", resp.Request.Method, resp.Request.URL, status, resp.StatusCode, b)
+		}
+		return b
+	}
+	issue := func() *models.ApiKeyOut {
+		t.Helper()
+		var key models.ApiKeyOut
+		if err := json.Unmarshal(expect(do(t, http.MethodPost, keys, "admin", `{"Principal": "acm-hub-1", "IsReporter": true}`), http.StatusCreated), &key); err != nil {
+			t.Fatal(err)
+		}
+		if key.Token == "" {
+			t.Fatal("expected the issued key's token")
+		}
+		return &key
+	}
+
+	// only admins manage keys
+	expect(do(t, http.MethodPost, keys, "user", `{"Principal": "user@example.com"}`), http.StatusForbidden)
+	expect(do(t, http.MethodGet, keys, "user", ""), http.StatusForbidden)
+
+	key := issue()
+	expect(do(t, http.MethodGet, clusters, key.Token, ""), http.StatusOK)
+
+	// the secret and its hash are never returned again
+	_, secret, _ := strings.Cut(key.Token, ".")
+	for _, url := range []string{keys, srv.URL + key.Href} {
+		body := string(expect(do(t, http.MethodGet, url, "admin", ""), http.StatusOK))
+		if strings.Contains(body, secret) || strings.Contains(body, `"Token"`) || strings.Contains(body, `"Hash"`) {
+			t.Errorf("GET %s returned a secret: %s", url, body)
+		}
+	}
+
+	// rotated secrets stop working once the authenticator's cache expires
+	var rotated models.ApiKeyOut
+	if err := json.Unmarshal(expect(do(t, http.MethodPost, srv.URL+key.Href+"/rotate", "admin", ""), http.StatusOK), &rotated); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(cacheTTL)
+	expect(do(t, http.MethodGet, clusters, key.Token, ""), http.StatusUnauthorized)
+	expect(do(t, http.MethodGet, clusters, rotated.Token, ""), http.StatusOK)
+
+	// and so do revoked keys
+	expect(do(t, http.MethodDelete, srv.URL+key.Href, "admin", ""), http.StatusNoContent)
+	time.Sleep(cacheTTL)
+	expect(do(t, http.MethodGet, clusters, rotated.Token, ""), http.StatusUnauthorized)
+}
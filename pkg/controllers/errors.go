@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// writeProblem writes a problem with the given status, code and detail.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	middleware.WriteProblem(w, r, cerrors.NewProblem(status, code, detail))
+}
+
+// writeForbidden reports that the caller's scopes don't allow the verb on the resource type.
+func writeForbidden(w http.ResponseWriter, r *http.Request, resourceType string, verb string) {
+	writeProblem(w, r, http.StatusForbidden, cerrors.CodeForbidden, fmt.Sprintf("Not allowed to %s %s resources", verb, resourceType))
+}
+
+// writeDbProblem maps a database error to a problem.  The error itself is logged but never sent to the client
+// since it may contain SQL or connection details.
+func writeDbProblem(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	p := DbProblem(err, notFound)
+
+	if p.Status >= http.StatusInternalServerError {
+		if log, lerr := middleware.GetRequestLogger(r.Context()); lerr == nil {
+			log.Error(fmt.Sprintf("Database error: %v", err))
+		}
+	}
+
+	middleware.WriteProblem(w, r, p)
+}
+
+// DbProblem is the problem a database error is reported as.  notFound is the detail for a missing record.
+func DbProblem(err error, notFound string) *cerrors.Problem {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return cerrors.NewProblem(http.StatusNotFound, cerrors.CodeNotFound, notFound)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return cerrors.NewProblem(http.StatusConflict, cerrors.CodeConflict, "The resource conflicts with an existing resource")
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return cerrors.NewProblem(http.StatusConflict, cerrors.CodeConflict, "The resource references or is referenced by another resource")
+	case isUnavailable(err):
+		return cerrors.NewProblem(http.StatusServiceUnavailable, cerrors.CodeUnavailable, "The database is unavailable")
+	default:
+		return cerrors.NewProblem(http.StatusInternalServerError, cerrors.CodeInternal, "An unexpected database error occurred")
+	}
+}
+
+// isUnavailable reports whether the error means the database couldn't be reached rather than that the query
+// failed.
+func isUnavailable(err error) bool {
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) ||
+		errors.As(err, &connectErr)
+}
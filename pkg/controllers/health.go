@@ -1,7 +1,51 @@
 package controllers
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+)
 
 func Ready(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
+
+// Readiness reports whether the server can take changes, which it can't while events can't be sent.  Unlike
+// /healthz, failing it shouldn't restart the server.
+func Readiness(eventingManager eventingapi.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if eventingManager == nil {
+			w.Write([]byte("OK"))
+			return
+		}
+		if err := eventingManager.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+// StatusResponse reports the runtime state of the server's components.
+type StatusResponse struct {
+	Authn    any `json:"authn,omitempty"`
+	Eventing any `json:"eventing,omitempty"`
+}
+
+// Status reports the state of components that implement a Status method, like when the pre-shared keys were
+// last loaded or how many events failed.
+func Status(authenticator authnapi.Authenticator, eventingManager eventingapi.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &StatusResponse{}
+		if s, ok := authenticator.(authnapi.StatusReporter); ok {
+			resp.Authn = s.Status()
+		}
+		if s, ok := eventingManager.(authnapi.StatusReporter); ok {
+			resp.Eventing = s.Status()
+		}
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	authzapi "github.com/csams/common-inventory/pkg/authz/api"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// RequireAdmin only lets admins through.  It must come after Authentication.
+func RequireAdmin(admins *authzapi.Admins) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, err := GetIdentity(r.Context())
+			if err != nil {
+				WriteProblem(w, r, cerrors.NewProblem(http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated"))
+				return
+			}
+
+			if !admins.IsAdmin(identity) {
+				WriteProblem(w, r, cerrors.NewProblem(http.StatusForbidden, cerrors.CodeForbidden, "Only admins may make this request"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectGuests only lets authenticated identities through.  It must come after Authentication.
+func RejectGuests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := GetIdentity(r.Context())
+		if err != nil {
+			WriteProblem(w, r, cerrors.NewProblem(http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated"))
+			return
+		}
+
+		if identity.IsGuest {
+			WriteProblem(w, r, cerrors.NewProblem(http.StatusForbidden, cerrors.CodeForbidden, "Guests may only read resources"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -3,25 +3,45 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
 
 	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/util"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
 )
 
+// Realm is the realm of the WWW-Authenticate challenges.
+const Realm = "common-inventory"
+
+// Authentication authenticates the request and writes an audit log line with the outcome.  Denied requests get a
+// problem with the reason and a bearer challenge.  See https://www.rfc-editor.org/rfc/rfc6750#section-3
 func Authentication(authenticator authnapi.Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			identity, decision := authenticator.Authenticate(r)
-			if decision != authnapi.Allow {
-				http.Error(w, "Not Authenticated", http.StatusUnauthorized)
+			logger, err := GetRequestLogger(r.Context())
+			if err != nil {
+				WriteProblem(w, r, cerrors.NewProblem(http.StatusInternalServerError, cerrors.CodeInternal, "Request logger not configured"))
 				return
 			}
 
-			if logger, err := GetRequestLogger(r.Context()); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			identity, result := authenticator.Authenticate(r)
+			audit(logger, r, identity, result)
+
+			if result.Decision != authnapi.Allow {
+				challenge := fmt.Sprintf("Bearer realm=%q", Realm)
+				if util.GetBearerToken(r) != "" {
+					challenge += fmt.Sprintf(`, error="invalid_token", error_description="%s"`, challengeText(result.Reason))
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+
+				detail := "Not Authenticated"
+				if result.Reason != "" {
+					detail = result.Reason
+				}
+				WriteProblem(w, r, cerrors.NewProblem(http.StatusUnauthorized, cerrors.CodeUnauthenticated, detail))
 				return
-			} else {
-				logger.Info(fmt.Sprintf("User: %v", identity))
 			}
 
 			ctx := context.WithValue(r.Context(), IdentityRequestKey, identity)
@@ -30,6 +50,43 @@ func Authentication(authenticator authnapi.Authenticator) func(http.Handler) htt
 	}
 }
 
+// audit logs the outcome of an authentication attempt.  The request logger already carries the request id.
+func audit(logger *slog.Logger, r *http.Request, identity *authnapi.Identity, result authnapi.Result) {
+	outcome := "allow"
+	if result.Decision != authnapi.Allow {
+		outcome = "deny"
+	}
+
+	principal, isGuest := "", false
+	if identity != nil {
+		principal, isGuest = identity.Principal, identity.IsGuest
+	}
+
+	logger.Info("Authentication",
+		slog.String("audit", "authn"),
+		slog.String("outcome", outcome),
+		slog.String("principal", principal),
+		slog.Bool("is_guest", isGuest),
+		slog.String("authenticator", result.Authenticator),
+		slog.String("reason", result.Reason),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+	)
+}
+
+// challengeText makes the reason safe for a quoted challenge parameter, which can't contain '"' or '\'.
+func challengeText(reason string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '"' || r == '\\':
+			return '\''
+		case r < 0x20 || r > 0x7e:
+			return ' '
+		}
+		return r
+	}, reason)
+}
+
 var (
 	IdentityRequestKey = &contextKey{"authnapi.Identity"}
 	GetIdentity        = GetFromContext[authnapi.Identity](IdentityRequestKey)
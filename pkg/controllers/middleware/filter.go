@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// FilterRequest holds the resource filters given as query parameters.  Empty values don't filter.
+type FilterRequest struct {
+	Workspace    string
+	ReporterType string
+	ReporterId   string
+	Filter       func(*gorm.DB) *gorm.DB
+}
+
+func Filtering(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		workspace := query.Get("workspace")
+		reporterType := query.Get("reporter_type")
+		reporterId := query.Get("reporter_id")
+
+		filterRequest := &FilterRequest{
+			Workspace:    workspace,
+			ReporterType: reporterType,
+			ReporterId:   reporterId,
+			Filter:       models.FilterResources(workspace, reporterType, reporterId),
+		}
+
+		ctx := context.WithValue(r.Context(), FilterRequestKey, filterRequest)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+var (
+	FilterRequestKey = &contextKey{"filterRequest"}
+	GetFilterRequest = GetFromContext[FilterRequest](FilterRequestKey)
+)
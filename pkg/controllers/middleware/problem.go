@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// WriteProblem writes the problem as an application/problem+json response.  It fills in the request id and the
+// request path as the problem instance.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *cerrors.Problem) {
+	if p.RequestId == "" {
+		p.RequestId = middleware.GetReqID(r.Context())
+	}
+
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", cerrors.ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// NotFound and MethodNotAllowed replace the router's plain text responses.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, cerrors.NewProblem(http.StatusNotFound, cerrors.CodeNotFound, "No route matches the request path"))
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, cerrors.NewProblem(http.StatusMethodNotAllowed, cerrors.CodeMethodNotAllowed, "The route doesn't support the request method"))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// Recoverer is like chi's middleware.Recoverer but logs with slog and responds with a Problem.
+func Recoverer(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rvr := recover(); rvr != nil {
+					if rvr == http.ErrAbortHandler {
+						// the client went away; let net/http handle it
+						panic(rvr)
+					}
+
+					log.Error(fmt.Sprintf("panic: %v", rvr), "id", middleware.GetReqID(r.Context()), "stack", string(debug.Stack()))
+
+					if r.Header.Get("Connection") != "Upgrade" {
+						WriteProblem(w, r, cerrors.NewProblem(http.StatusInternalServerError, cerrors.CodeInternal, "An unexpected error occurred"))
+					}
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/datatypes"
+
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+const OpenAPIPath = "/openapi.json"
+
+// EventSchemaPath is where the JSON Schema of the current version of the resource events is published.
+const EventSchemaPath = "/" + eventingapi.SchemaFile
+
+// ResyncSchemaPath is where the JSON Schema of the current version of the resync events is published.
+const ResyncSchemaPath = "/" + eventingapi.ResyncSchemaFile
+
+// EventSchema serves the JSON Schema in the file of eventingapi.Schemas.  It's public so consumers can validate
+// events without credentials.
+func EventSchema(file string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema, err := eventingapi.Schemas.ReadFile(file)
+		if err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, "The event schema is missing")
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(schema)
+	}
+}
+
+// OpenAPISpec is an OpenAPI 3 document.  It's built from maps so it serializes exactly as written.
+type OpenAPISpec map[string]any
+
+// NewOpenAPISpec describes the API served under basePath for the given resource types.  Schemas are generated
+// from the Go types the handlers encode and decode, so they can't drift from the implementation.
+func NewOpenAPISpec(basePath string, resourceTypes []ResourceType) OpenAPISpec {
+	g := &schemaGenerator{components: map[string]any{}}
+
+	resourceIn := g.ref("ResourceIn", reflect.TypeOf(models.ResourceIn{}))
+	resourceOut := g.ref("ResourceOut", reflect.TypeOf(models.ResourceOut{}))
+	pagedResponse := g.ref("PagedResponse", reflect.TypeOf(middleware.PagedResponse[*models.ResourceOut]{}))
+	g.ref("Problem", reflect.TypeOf(cerrors.Problem{}))
+
+	paths := map[string]any{}
+
+	listParams := []any{
+		queryParam("page", "integer", "The page to return, starting at 1."),
+		queryParam("size", "integer", "The number of items per page.  At most 100."),
+		queryParam("workspace", "string", "Only return resources in this workspace."),
+		queryParam("reporter_type", "string", "Only return resources reported by this type of reporter."),
+		queryParam("reporter_id", "string", "Only return resources reported by this reporter instance."),
+	}
+
+	var typeNames []string
+	for _, rt := range resourceTypes {
+		typeNames = append(typeNames, rt.Name)
+
+		tag := rt.Name
+		collection := fmt.Sprintf("/resources/%s", rt.Path)
+		paths[collection] = map[string]any{
+			"get": operation(tag, "List "+rt.Name+" resources", listParams, nil, map[string]any{
+				"200": jsonResponse("A page of resources", pagedResponse),
+			}),
+			"post": operation(tag, "Report a new "+rt.Name, nil, resourceIn, map[string]any{
+				"201": jsonResponse("The created resource", resourceOut),
+				"409": problemResponse("The reporter already reported a resource with the same local id"),
+				"503": problemResponse("The resource was saved, but its event couldn't be sent"),
+			}),
+		}
+
+		idParam := map[string]any{
+			"name":        "id",
+			"in":          "path",
+			"required":    true,
+			"description": "The resource id or hcrn:<reporter type>:<reporter id>:<local resource id>.  Updates and deletes require the resource id.",
+			"schema":      map[string]any{"type": "string"},
+		}
+		paths[collection+"/{id}"] = map[string]any{
+			"get": operation(tag, "Get a "+rt.Name, []any{idParam}, nil, map[string]any{
+				"200": jsonResponse("The resource", resourceOut),
+				"404": problemResponse("The resource doesn't exist"),
+			}),
+			"put": operation(tag, "Update a "+rt.Name, []any{idParam}, resourceIn, map[string]any{
+				"204": map[string]any{"description": "The resource was updated"},
+				"404": problemResponse("The resource doesn't exist"),
+				"409": problemResponse("The update conflicts with another resource"),
+				"503": problemResponse("The update was saved, but its event couldn't be sent"),
+			}),
+			"delete": operation(tag, "Delete a "+rt.Name, []any{idParam}, nil, map[string]any{
+				"204": map[string]any{"description": "The resource was deleted"},
+				"404": problemResponse("The resource doesn't exist"),
+				"503": problemResponse("The resource was deleted, but its event couldn't be sent"),
+			}),
+		}
+	}
+
+	allParams := append([]any{map[string]any{
+		"name":        "resource_type",
+		"in":          "query",
+		"description": "Only return resources of this type.",
+		"schema":      map[string]any{"type": "string", "enum": typeNames},
+	}}, listParams...)
+	paths["/resources"] = map[string]any{
+		"get": operation("resources", "List resources of every type", allParams, nil, map[string]any{
+			"200": jsonResponse("A page of resources", pagedResponse),
+		}),
+	}
+
+	paths["/status"] = map[string]any{
+		"get": operation("meta", "Runtime state of the server, like when pre-shared keys were last loaded and what happened to the events.  Only admins may see it.", nil, nil, map[string]any{
+			"200": jsonResponse("The server status", g.ref("StatusResponse", reflect.TypeOf(StatusResponse{}))),
+		}),
+	}
+
+	reporterIn := g.ref("ReporterIn", reflect.TypeOf(models.ReporterIn{}))
+	reporterOut := g.ref("ReporterOut", reflect.TypeOf(models.ReporterOut{}))
+	reporterIdParam := map[string]any{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "The reporter id.",
+		"schema":      map[string]any{"type": "integer"},
+	}
+	paths["/reporters"] = map[string]any{
+		"get": operation("reporters", "List registered reporters and when they were last seen", append(listParams[:2:2], queryParam("reporter_type", "string", "Only return reporters of this type.")), nil, map[string]any{
+			"200": jsonResponse("A page of reporters", g.ref("ReporterPagedResponse", reflect.TypeOf(middleware.PagedResponse[*models.ReporterOut]{}))),
+		}),
+		"post": operation("reporters", "Register a reporter.  Only admins may register reporters.", nil, reporterIn, map[string]any{
+			"201": jsonResponse("The registered reporter", reporterOut),
+			"409": problemResponse("The principal is already registered"),
+		}),
+	}
+	paths["/reporters/{id}"] = map[string]any{
+		"get": operation("reporters", "Get a reporter", []any{reporterIdParam}, nil, map[string]any{
+			"200": jsonResponse("The reporter", reporterOut),
+			"404": problemResponse("The reporter doesn't exist"),
+		}),
+		"put": operation("reporters", "Replace a reporter's registration.  Only admins may change reporters.", []any{reporterIdParam}, reporterIn, map[string]any{
+			"204": map[string]any{"description": "The reporter was updated"},
+			"404": problemResponse("The reporter doesn't exist"),
+		}),
+		"delete": operation("reporters", "Unregister a reporter.  Only admins may unregister reporters.", []any{reporterIdParam}, nil, map[string]any{
+			"204": map[string]any{"description": "The reporter was unregistered"},
+			"404": problemResponse("The reporter doesn't exist"),
+		}),
+	}
+
+	subscriptionIn := g.ref("SubscriptionIn", reflect.TypeOf(models.SubscriptionIn{}))
+	subscriptionOut := g.ref("SubscriptionOut", reflect.TypeOf(models.SubscriptionOut{}))
+	deliveryOut := g.ref("DeliveryOut", reflect.TypeOf(models.DeliveryOut{}))
+	subscriptionIdParam := map[string]any{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "The subscription id.",
+		"schema":      map[string]any{"type": "integer"},
+	}
+	deliveryIdParam := map[string]any{
+		"name":        "delivery_id",
+		"in":          "path",
+		"required":    true,
+		"description": "The delivery id.",
+		"schema":      map[string]any{"type": "integer"},
+	}
+	paths["/subscriptions"] = map[string]any{
+		"get": operation("subscriptions", "List your webhook subscriptions.  Admins see everyone's.", listParams[:2], nil, map[string]any{
+			"200": jsonResponse("A page of subscriptions", g.ref("SubscriptionPagedResponse", reflect.TypeOf(middleware.PagedResponse[*models.SubscriptionOut]{}))),
+		}),
+		"post": operation("subscriptions", "Subscribe a callback URL to resource events.  The signing secret is only returned in this response.", nil, subscriptionIn, map[string]any{
+			"201": jsonResponse("The subscription and its secret", subscriptionOut),
+		}),
+	}
+	paths["/subscriptions/{id}"] = map[string]any{
+		"get": operation("subscriptions", "Get a subscription without its secret", []any{subscriptionIdParam}, nil, map[string]any{
+			"200": jsonResponse("The subscription", subscriptionOut),
+			"404": problemResponse("The subscription doesn't exist or isn't yours"),
+		}),
+		"put": operation("subscriptions", "Replace a subscription's callback URL and filters", []any{subscriptionIdParam}, subscriptionIn, map[string]any{
+			"204": map[string]any{"description": "The subscription was updated"},
+			"404": problemResponse("The subscription doesn't exist or isn't yours"),
+		}),
+		"delete": operation("subscriptions", "Delete a subscription and its deliveries", []any{subscriptionIdParam}, nil, map[string]any{
+			"204": map[string]any{"description": "The subscription was deleted"},
+			"404": problemResponse("The subscription doesn't exist or isn't yours"),
+		}),
+	}
+	paths["/subscriptions/{id}/deliveries"] = map[string]any{
+		"get": operation("subscriptions", "List a subscription's deliveries, newest first", append(listParams[:2:2], queryParam("status", "string", "Only return deliveries with this status: pending, delivered or failed.")), nil, map[string]any{
+			"200": jsonResponse("A page of deliveries", g.ref("DeliveryPagedResponse", reflect.TypeOf(middleware.PagedResponse[*models.DeliveryOut]{}))),
+			"404": problemResponse("The subscription doesn't exist or isn't yours"),
+		}),
+	}
+	paths["/subscriptions/{id}/deliveries/{delivery_id}"] = map[string]any{
+		"get": operation("subscriptions", "Get a delivery", []any{subscriptionIdParam, deliveryIdParam}, nil, map[string]any{
+			"200": jsonResponse("The delivery", deliveryOut),
+			"404": problemResponse("The delivery doesn't exist"),
+		}),
+	}
+	paths["/subscriptions/{id}/deliveries/{delivery_id}/redeliver"] = map[string]any{
+		"post": operation("subscriptions", "Send a delivery again", []any{subscriptionIdParam, deliveryIdParam}, nil, map[string]any{
+			"202": jsonResponse("The delivery, which is pending again", deliveryOut),
+			"404": problemResponse("The delivery doesn't exist"),
+		}),
+	}
+
+	apiKeyIn := g.ref("ApiKeyIn", reflect.TypeOf(models.ApiKeyIn{}))
+	apiKeyOut := g.ref("ApiKeyOut", reflect.TypeOf(models.ApiKeyOut{}))
+	keyIdParam := map[string]any{
+		"name":        "key_id",
+		"in":          "path",
+		"required":    true,
+		"description": "The public part of the API key.",
+		"schema":      map[string]any{"type": "string"},
+	}
+	paths["/admin/apikeys"] = map[string]any{
+		"get": operation("admin", "List API keys without their secrets", listParams[:2], nil, map[string]any{
+			"200": jsonResponse("A page of API keys", g.ref("ApiKeyPagedResponse", reflect.TypeOf(middleware.PagedResponse[*models.ApiKeyOut]{}))),
+		}),
+		"post": operation("admin", "Issue an API key.  The token is only returned in this response.", nil, apiKeyIn, map[string]any{
+			"201": jsonResponse("The issued key and its token", apiKeyOut),
+		}),
+	}
+	paths["/admin/apikeys/{key_id}"] = map[string]any{
+		"get": operation("admin", "Get an API key without its secret", []any{keyIdParam}, nil, map[string]any{
+			"200": jsonResponse("The API key", apiKeyOut),
+			"404": problemResponse("The API key doesn't exist"),
+		}),
+		"delete": operation("admin", "Revoke an API key", []any{keyIdParam}, nil, map[string]any{
+			"204": map[string]any{"description": "The API key was revoked"},
+			"404": problemResponse("The API key doesn't exist"),
+		}),
+	}
+	paths["/admin/apikeys/{key_id}/rotate"] = map[string]any{
+		"post": operation("admin", "Replace an API key's secret.  The token is only returned in this response.", []any{keyIdParam}, nil, map[string]any{
+			"200": jsonResponse("The API key and its new token", apiKeyOut),
+			"404": problemResponse("The API key doesn't exist"),
+			"409": problemResponse("The API key is revoked"),
+		}),
+	}
+
+	resyncIn := g.ref("ResyncIn", reflect.TypeOf(models.ResyncIn{}))
+	resyncOut := g.ref("ResyncOut", reflect.TypeOf(models.ResyncOut{}))
+	resyncIdParam := map[string]any{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "The resync id.",
+		"schema":      map[string]any{"type": "integer"},
+	}
+	paths["/admin/resyncs"] = map[string]any{
+		"get": operation("admin", "List resyncs, newest first", append(listParams[:2:2], queryParam("status", "string", "Only return resyncs with this status: running, completed or failed.")), nil, map[string]any{
+			"200": jsonResponse("A page of resyncs", g.ref("ResyncPagedResponse", reflect.TypeOf(middleware.PagedResponse[*models.ResyncOut]{}))),
+		}),
+		"post": operation("admin", "Start sending a snapshot event for every resource that matches the filters, then an event that marks the end", nil, resyncIn, map[string]any{
+			"202": jsonResponse("The resync, which runs in the background", resyncOut),
+		}),
+	}
+	paths["/admin/resyncs/{id}"] = map[string]any{
+		"get": operation("admin", "Get a resync and how far it got", []any{resyncIdParam}, nil, map[string]any{
+			"200": jsonResponse("The resync", resyncOut),
+			"404": problemResponse("The resync doesn't exist"),
+		}),
+	}
+	paths["/admin/resyncs/{id}/resume"] = map[string]any{
+		"post": operation("admin", "Run a resync that stopped from the resource after the last one it sent", []any{resyncIdParam}, nil, map[string]any{
+			"202": jsonResponse("The resync, which runs in the background", resyncOut),
+			"404": problemResponse("The resync doesn't exist"),
+			"409": problemResponse("The resync is running on this server or completed"),
+		}),
+	}
+	paths["/admin/resyncs/{id}/cancel"] = map[string]any{
+		"post": operation("admin", "Stop a resync running on this server.  It can be resumed.", []any{resyncIdParam}, nil, map[string]any{
+			"202": map[string]any{"description": "The resync is stopping"},
+			"404": problemResponse("The resync doesn't exist"),
+			"409": problemResponse("The resync isn't running on this server"),
+		}),
+	}
+
+	paths[EventSchemaPath] = schemaOperation("The JSON Schema of the data of the resource events")
+	paths[ResyncSchemaPath] = schemaOperation("The JSON Schema of the data of the events that mark the end of a resync")
+
+	paths[OpenAPIPath] = map[string]any{
+		"get": map[string]any{
+			"tags":     []string{"meta"},
+			"summary":  "This document",
+			"security": []any{},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The OpenAPI document",
+					"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}},
+				},
+			},
+		},
+	}
+
+	return OpenAPISpec{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Common Inventory",
+			"version": "v1alpha1",
+		},
+		"servers":  []any{map[string]any{"url": basePath}},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": g.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "A pre-shared key, an API key or an OIDC token.  Clients may authenticate with a certificate instead.",
+				},
+			},
+		},
+	}
+}
+
+// Handler serves the spec as json.
+func (s OpenAPISpec) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, s)
+	}
+}
+
+// Verify returns an error unless the spec's operations are exactly the routes the router serves under basePath.
+func (s OpenAPISpec) Verify(router chi.Routes, basePath string) error {
+	documented := map[string]bool{}
+	for path, item := range s["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	routed := map[string]bool{}
+	err := chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, basePath+"/") {
+			return nil
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(route, basePath), "/")
+		routed[method+" "+path] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for op := range routed {
+		if !documented[op] {
+			errs = append(errs, fmt.Errorf("route %s is missing from the OpenAPI spec", op))
+		}
+	}
+	for op := range documented {
+		if !routed[op] {
+			errs = append(errs, fmt.Errorf("OpenAPI operation %s has no route", op))
+		}
+	}
+
+	if errs != nil {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return cerrors.NewAggregate(errs)
+	}
+	return nil
+}
+
+func operation(tag string, summary string, params []any, body map[string]any, responses map[string]any) map[string]any {
+	responses["400"] = problemResponse("The request is invalid")
+	unauthenticated := problemResponse("The caller isn't authenticated.  The detail says why.")
+	unauthenticated["headers"] = map[string]any{
+		"WWW-Authenticate": map[string]any{
+			"description": "A bearer challenge.  See RFC 6750.",
+			"schema":      map[string]any{"type": "string"},
+		},
+	}
+	responses["401"] = unauthenticated
+	responses["403"] = problemResponse("The caller's scopes or reporter registration don't allow the operation, or the caller isn't an admin")
+	responses["default"] = problemResponse("An unexpected error")
+
+	op := map[string]any{
+		"tags":      []string{tag},
+		"summary":   summary,
+		"responses": responses,
+	}
+
+	if params != nil {
+		op["parameters"] = params
+	}
+
+	if body != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": body}},
+		}
+	}
+
+	return op
+}
+
+func schemaOperation(summary string) map[string]any {
+	return map[string]any{
+		"get": map[string]any{
+			"tags":     []string{"meta"},
+			"summary":  summary,
+			"security": []any{},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The JSON Schema",
+					"content":     map[string]any{"application/schema+json": map[string]any{"schema": map[string]any{"type": "object"}}},
+				},
+			},
+		},
+	}
+}
+
+func queryParam(name string, typ string, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]any{"type": typ},
+	}
+}
+
+func jsonResponse(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+func problemResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			cerrors.ProblemContentType: map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+		},
+	}
+}
+
+// schemaGenerator builds JSON schemas from Go types the way encoding/json serializes them.
+type schemaGenerator struct {
+	components map[string]any
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+	gormJSON    = reflect.TypeOf(datatypes.JSON{})
+)
+
+// ref registers the named struct as a component and returns a reference to it.
+func (g *schemaGenerator) ref(name string, t reflect.Type) map[string]any {
+	if _, found := g.components[name]; !found {
+		// reserve the name first in case the type refers to itself
+		g.components[name] = nil
+		g.components[name] = g.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawJSONType, gormJSON:
+		return map[string]any{"description": "Arbitrary JSON specific to the reporter type"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if _, isRef := s["$ref"]; isRef {
+			return s
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Struct:
+		return g.ref(t.Name(), t)
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	g.addFields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// addFields adds the json fields of the struct to properties, flattening embedded structs like encoding/json.
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.addFields(ft, properties)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = g.schema(f.Type)
+	}
+}
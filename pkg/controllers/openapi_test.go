@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPISpecMatchesRouter(t *testing.T) {
+	router := NewRootHandler(nil, nil, nil, nil, false, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := NewOpenAPISpec(BasePath, ResourceTypes).Verify(router, BasePath); err != nil {
+		t.Fatalf("the OpenAPI spec has drifted from the router:\n%v", err)
+	}
+}
+
+func TestOpenAPISpecVerifyReportsDrift(t *testing.T) {
+	router := NewRootHandler(nil, nil, nil, nil, false, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	spec := NewOpenAPISpec(BasePath, ResourceTypes)
+	paths := spec["paths"].(map[string]any)
+	delete(paths, "/resources/hosts")
+	paths["/nowhere"] = map[string]any{"get": map[string]any{}}
+
+	err := spec.Verify(router, BasePath)
+	if err == nil {
+		t.Fatal("expected an error for a spec that doesn't match the router")
+	}
+	for _, want := range []string{"route GET /resources/hosts is missing", "OpenAPI operation GET /nowhere has no route"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
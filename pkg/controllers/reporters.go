@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gorm.io/gorm"
+
+	authzapi "github.com/csams/common-inventory/pkg/authz/api"
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// ReporterController registers reporter instances.  Any authenticated caller may read the registrations and
+// when each reporter was last seen, but only admins may change them.
+type ReporterController struct {
+	BasePath string
+	Db       *gorm.DB
+	Admins   *authzapi.Admins
+	Log      *slog.Logger
+}
+
+func NewReporterController(basePath string, db *gorm.DB, admins *authzapi.Admins, log *slog.Logger) *ReporterController {
+	return &ReporterController{
+		BasePath: basePath,
+		Db:       db,
+		Admins:   admins,
+		Log:      log,
+	}
+}
+
+func (c ReporterController) Routes() chi.Router {
+	r := chi.NewRouter()
+	admin := middleware.RequireAdmin(c.Admins)
+
+	r.With(middleware.Pagination).Get("/", c.List)
+	r.With(admin).Post("/", c.Create)
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", c.Get)
+		r.With(admin).Put("/", c.Update)
+		r.With(admin).Delete("/", c.Delete)
+	})
+
+	return r
+}
+
+func (c *ReporterController) List(w http.ResponseWriter, r *http.Request) {
+	pagination, err := middleware.GetPaginationRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	db := c.Db.Model(&models.Reporter{})
+	if reporterType := r.URL.Query().Get("reporter_type"); reporterType != "" {
+		db = db.Where("reporter_type = ?", reporterType)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var results []models.Reporter
+	if err := db.Scopes(pagination.Filter).Order("id").Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var output []*models.ReporterOut
+	for _, result := range results {
+		rep := result
+		output = append(output, models.NewReporterOut(&rep, c.href(&rep)))
+	}
+
+	resp := &middleware.PagedResponse[*models.ReporterOut]{
+		PagedReponseMetadata: middleware.PagedReponseMetadata{
+			Page:  pagination.Page,
+			Size:  len(results),
+			Total: count,
+		},
+		Items: output,
+	}
+
+	render.JSON(w, r, resp)
+}
+
+func (c *ReporterController) Get(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewReporterOut(reporter, c.href(reporter)))
+}
+
+func (c *ReporterController) Create(w http.ResponseWriter, r *http.Request) {
+	input, ok := c.decode(w, r)
+	if !ok {
+		return
+	}
+
+	reporter := models.NewReporter(input)
+	if err := c.Db.Create(reporter).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			writeProblem(w, r, http.StatusConflict, cerrors.CodeConflict, fmt.Sprintf("%s is already a registered reporter", input.Principal))
+		} else {
+			writeDbProblem(w, r, err, "")
+		}
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewReporterOut(reporter, c.href(reporter)))
+}
+
+// Update replaces a registration.  The principal can't be changed, since it identifies the reporter's data.
+func (c *ReporterController) Update(w http.ResponseWriter, r *http.Request) {
+	input, ok := c.decode(w, r)
+	if !ok {
+		return
+	}
+
+	reporter, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	if input.Principal != reporter.Principal {
+		middleware.WriteProblem(w, r, cerrors.NewValidationProblem([]error{
+			cerrors.NewFieldError("Principal", fmt.Sprintf("must be %s.  Register a new reporter to change it", reporter.Principal)),
+		}))
+		return
+	}
+
+	// only the registration, so a concurrent report's last seen time isn't overwritten
+	reporter.Update(input)
+	if err := c.Db.Model(reporter).Select("reporter_type", "reporter_version", "owning_team", "resource_types").Updates(reporter).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete unregisters a reporter.  The resources it reported are kept.
+func (c *ReporterController) Delete(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Db.Delete(reporter).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decode writes a problem and returns false if the body isn't a valid registration.
+func (c *ReporterController) decode(w http.ResponseWriter, r *http.Request) (*models.ReporterIn, bool) {
+	var input models.ReporterIn
+	if err := render.Decode(r, &input); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("The request body is invalid: %v", err))
+		return nil, false
+	}
+
+	errs := input.Validate()
+	for _, t := range input.ResourceTypes {
+		if !isResourceType(t) {
+			errs = append(errs, cerrors.NewFieldError("ResourceTypes", fmt.Sprintf("unknown resource type %s", t)))
+		}
+	}
+	if errs != nil {
+		middleware.WriteProblem(w, r, cerrors.NewValidationProblem(errs))
+		return nil, false
+	}
+
+	return &input, true
+}
+
+// find writes a problem and returns false if the reporter in the path doesn't exist.
+func (c *ReporterController) find(w http.ResponseWriter, r *http.Request) (*models.Reporter, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The id must be an integer")
+		return nil, false
+	}
+
+	reporter := &models.Reporter{}
+	if err := c.Db.First(reporter, id).Error; err != nil {
+		writeDbProblem(w, r, err, fmt.Sprintf("No reporter with id %d", id))
+		return nil, false
+	}
+
+	return reporter, true
+}
+
+func (c *ReporterController) href(reporter *models.Reporter) string {
+	return fmt.Sprintf("%s/%d", c.BasePath, reporter.ID)
+}
+
+func isResourceType(name string) bool {
+	for _, rt := range ResourceTypes {
+		if rt.Name == name {
+			return true
+		}
+	}
+	return false
+}
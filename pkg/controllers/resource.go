@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +33,9 @@ type ResourceController struct {
 	Authorizer      authzapi.Authorizer
 	EventingManager eventingapi.Manager
 	Log             *slog.Logger
+
+	// RequireRegisteredReporters rejects changes from callers that aren't registered reporters.
+	RequireRegisteredReporters bool
 }
 
 func NewResourceController(
@@ -39,21 +44,23 @@ func NewResourceController(
 	db *gorm.DB,
 	authorizer authzapi.Authorizer,
 	em eventingapi.Manager,
+	requireRegisteredReporters bool,
 	log *slog.Logger) *ResourceController {
 	return &ResourceController{
-		BasePath:        basePath,
-		ResourceType:    resourceType,
-		Db:              db,
-		Authorizer:      authorizer,
-		EventingManager: em,
-		Log:             log,
+		BasePath:                   basePath,
+		ResourceType:               resourceType,
+		Db:                         db,
+		Authorizer:                 authorizer,
+		EventingManager:            em,
+		RequireRegisteredReporters: requireRegisteredReporters,
+		Log:                        log,
 	}
 }
 
 func (c ResourceController) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(middleware.Pagination).Get("/", c.List)
+	r.With(middleware.Pagination, middleware.Filtering).Get("/", c.List)
 	r.Post("/", c.Create)
 	r.Route("/{id}", func(r chi.Router) {
 		r.Get("/", c.Get)
@@ -65,30 +72,41 @@ func (c ResourceController) Routes() chi.Router {
 }
 
 func (c *ResourceController) List(w http.ResponseWriter, r *http.Request) {
-	_, err := middleware.GetIdentity(r.Context())
+	identity, err := middleware.GetIdentity(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	if !identity.Allows(c.ResourceType, authnapi.VerbList) {
+		writeForbidden(w, r, c.ResourceType, authnapi.VerbList)
 		return
 	}
 
 	pagination, err := middleware.GetPaginationRequest(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
 		return
 	}
 
-	var model models.Resource
-	var count int64
-	if err := c.Db.Model(&model).Count(&count).Error; err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	filter, err := middleware.GetFilterRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
 		return
 	}
 
-	db := c.Db.Scopes(pagination.Filter)
+	// a new session so the count and the find don't share conditions
+	db := c.Db.Model(&models.Resource{}).Scopes(filter.Filter).Where("resource_type = ?", c.ResourceType).Session(&gorm.Session{})
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
 
 	var results []models.Resource
-	if err := db.Preload(clause.Associations).Where("resource_type = ?", c.ResourceType).Find(&results).Error; err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := db.Scopes(pagination.Filter).Preload(clause.Associations).Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
 		return
 	}
 
@@ -113,9 +131,14 @@ func (c *ResourceController) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *ResourceController) Get(w http.ResponseWriter, r *http.Request) {
-	_, err := middleware.GetIdentity(r.Context())
+	identity, err := middleware.GetIdentity(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	if !identity.Allows(c.ResourceType, authnapi.VerbGet) {
+		writeForbidden(w, r, c.ResourceType, authnapi.VerbGet)
 		return
 	}
 
@@ -123,24 +146,15 @@ func (c *ResourceController) Get(w http.ResponseWriter, r *http.Request) {
 	var model models.Resource
 	id, err := strconv.ParseInt(rawId, 10, 64)
 	if err == nil {
-		if err := c.Db.Preload(clause.Associations).First(&model, id).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				http.Error(w, err.Error(), http.StatusNotFound)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+		if err := c.Db.Preload(clause.Associations).Where("resource_type = ?", c.ResourceType).First(&model, id).Error; err != nil {
+			writeDbProblem(w, r, err, fmt.Sprintf("No %s with id %d", c.ResourceType, id))
 			return
 		}
 	} else {
 		// if the id isn't an integer, it's the special format
 		parts := strings.Split(rawId, ":")
-		if len(parts) != 4 {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		if parts[0] != "hcrn" {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if len(parts) != 4 || parts[0] != "hcrn" {
+			writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The id must be an integer or hcrn:<reporter type>:<reporter id>:<local resource id>")
 			return
 		}
 
@@ -150,11 +164,7 @@ func (c *ResourceController) Get(w http.ResponseWriter, r *http.Request) {
 			Joins("join reporter_data on reporter_data.resource_id = resources.id").
 			Where("reporter_data.reporter_id = ? and reporter_data.reporter_type = ? and reporter_data.local_resource_id = ? and resources.resource_type = ?", reporterInstanceId, reporterType, localResourceId, c.ResourceType).
 			First(&model).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				http.Error(w, err.Error(), http.StatusNotFound)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			writeDbProblem(w, r, err, fmt.Sprintf("No %s with id %s", c.ResourceType, rawId))
 			return
 		}
 	}
@@ -167,44 +177,27 @@ func (c *ResourceController) Get(w http.ResponseWriter, r *http.Request) {
 func (c *ResourceController) Create(w http.ResponseWriter, r *http.Request) {
 	identity, err := middleware.GetIdentity(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
 		return
 	}
 
-	var input models.ResourceIn
-	if err := render.Decode(r, &input); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !identity.Allows(c.ResourceType, authnapi.VerbCreate) {
+		writeForbidden(w, r, c.ResourceType, authnapi.VerbCreate)
 		return
 	}
 
-	if errs := input.Validate(); errs != nil {
-		http.Error(w, cerrors.NewAggregate(errs).Error(), http.StatusBadRequest)
+	var input models.ResourceIn
+	if err := render.Decode(r, &input); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("The request body is invalid: %v", err))
 		return
 	}
 
-	model, err := c.CreateResourceFromInput(&input, identity)
+	model, err := c.CreateResource(r.Context(), &input, identity)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		c.writeChangeError(w, r, err)
 		return
 	}
 
-	if err := c.Db.Create(model).Error; err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if c.EventingManager != nil {
-		// TODO: handle eventing errors
-		// TODO: Update the Object that's sent.  This is going to be what we actually emit.
-		producer, _ := c.EventingManager.Lookup(identity, model)
-		evt := &eventingapi.Event{
-			EventType:    "Create",
-			ResourceType: c.ResourceType,
-			Object:       model,
-		}
-		producer.Produce(r.Context(), evt)
-	}
-
 	href := fmt.Sprintf("%s/%d", c.BasePath, model.ID)
 	out := models.NewResourceOut(model, href)
 
@@ -215,115 +208,245 @@ func (c *ResourceController) Create(w http.ResponseWriter, r *http.Request) {
 func (c *ResourceController) Update(w http.ResponseWriter, r *http.Request) {
 	identity, err := middleware.GetIdentity(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	if !identity.Allows(c.ResourceType, authnapi.VerbUpdate) {
+		writeForbidden(w, r, c.ResourceType, authnapi.VerbUpdate)
 		return
 	}
 
 	var input models.ResourceIn
 	if err := render.Decode(r, &input); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("The request body is invalid: %v", err))
 		return
 	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The id must be an integer")
 		return
 	}
 
-	db := c.Db.Preload("ReporterData")
-
-	var model models.Resource
-	if err := db.First(&model, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	if _, err := c.UpdateResource(r.Context(), models.IDType(id), &input, identity); err != nil {
+		c.writeChangeError(w, r, err)
 		return
 	}
 
-	err = c.UpdateResourceFromInput(&input, &model, identity)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *ResourceController) Delete(w http.ResponseWriter, r *http.Request) {
+	identity, err := middleware.GetIdentity(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
 		return
 	}
 
-	if err := c.Db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&model).Error; err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !identity.Allows(c.ResourceType, authnapi.VerbDelete) {
+		writeForbidden(w, r, c.ResourceType, authnapi.VerbDelete)
 		return
 	}
 
-	if c.EventingManager != nil {
-		// TODO: handle eventing errors
-		// TODO: Update the Object that's sent.  This is going to be what we actually emit.
-		producer, _ := c.EventingManager.Lookup(identity, &model)
-		evt := &eventingapi.Event{
-			EventType:    "Update",
-			ResourceType: c.ResourceType,
-			Object:       &model,
-		}
-		producer.Produce(r.Context(), evt)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The id must be an integer")
+		return
+	}
+
+	if err := c.DeleteResource(r.Context(), models.IDType(id), identity); err != nil {
+		c.writeChangeError(w, r, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (c *ResourceController) Delete(w http.ResponseWriter, r *http.Request) {
-	identity, err := middleware.GetIdentity(r.Context())
+// EventError is a change that was saved but whose event couldn't be sent.  Err is what's left after the eventing
+// manager applied its failure policy.
+type EventError struct {
+	Err error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("the change was saved, but its event couldn't be sent: %v", e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// CreateResource validates the input, saves it as a new resource reported by the identity and sends the created
+// event.  The caller checks the identity's scopes.  Refusals are *cerrors.Problem, and an *EventError means the
+// resource was saved anyway.  Other errors are from the database.
+func (c *ResourceController) CreateResource(ctx context.Context, input *models.ResourceIn, identity *authnapi.Identity) (*models.Resource, error) {
+	if errs := input.Validate(); errs != nil {
+		return nil, cerrors.NewValidationProblem(errs)
+	}
+
+	reporter, err := c.checkReporter(identity)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		return
+		return nil, err
 	}
 
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	model, err := c.CreateResourceFromInput(input, identity, reporter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, cerrors.NewValidationProblem([]error{err})
 	}
 
-	var model models.Resource
-	if err := c.Db.Delete(&model, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	// gorm upserts associations, which would move an existing reporter's data to the new resource.  Insert the
+	// reporter data explicitly so the reporter_data constraints reject a local id that's already mapped.
+	err = c.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(model).Error; err != nil {
+			return err
 		}
-		return
+		for i := range model.ReporterData {
+			model.ReporterData[i].ResourceID = model.ID
+		}
+		return tx.Create(&model.ReporterData).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			msg := fmt.Sprintf("Resource for instance %s of ReporterType %s already exists", identity.Principal, model.ReporterData[0].ReporterType)
+			return nil, cerrors.NewProblem(http.StatusConflict, cerrors.CodeConflict, msg)
+		}
+		return nil, err
+	}
+	c.seen(reporter)
+
+	if err := c.emit(ctx, eventingapi.ResourceCreated, identity, reporter, model.ID, nil, model); err != nil {
+		return model, &EventError{Err: err}
 	}
+	return model, nil
+}
+
+// UpdateResource validates the input, replaces the identity's data about the resource with the id and sends the
+// updated event.  Errors are like CreateResource's.
+func (c *ResourceController) UpdateResource(ctx context.Context, id models.IDType, input *models.ResourceIn, identity *authnapi.Identity) (*models.Resource, error) {
+	if errs := input.Validate(); errs != nil {
+		return nil, cerrors.NewValidationProblem(errs)
+	}
+
+	reporter, err := c.checkReporter(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	var model models.Resource
+	if err := c.Db.WithContext(ctx).Preload("ReporterData").Where("resource_type = ?", c.ResourceType).First(&model, id).Error; err != nil {
+		return nil, c.notFound(err, id)
+	}
+
+	// a copy for the event, since the update changes the reporter data in place
+	before := model
+	before.ReporterData = slices.Clone(model.ReporterData)
+
+	if err := c.UpdateResourceFromInput(input, &model, identity, reporter); err != nil {
+		return nil, cerrors.NewValidationProblem([]error{err})
+	}
+
+	// replace the caller's reporter data with plain inserts for the same reason as in CreateResource
+	err = c.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Updates(&model).Error; err != nil {
+			return err
+		}
 
-	if c.EventingManager != nil {
-		// TODO: handle eventing errors
-		// TODO: Update the Object that's sent.  This is going to be what we actually emit.
-		producer, _ := c.EventingManager.Lookup(identity, &model)
-		evt := &eventingapi.Event{
-			EventType:    "Update",
-			ResourceType: c.ResourceType,
-			Object:       &model,
+		if err := tx.Where("resource_id = ? and reporter_id = ?", model.ID, identity.Principal).Delete(&models.ReporterData{}).Error; err != nil {
+			return err
 		}
-		producer.Produce(r.Context(), evt)
+
+		var reporterData []models.ReporterData
+		for _, r := range model.ReporterData {
+			if r.ReporterID == identity.Principal {
+				r.ResourceID = model.ID
+				reporterData = append(reporterData, r)
+			}
+		}
+		return tx.Create(&reporterData).Error
+	})
+	if err != nil {
+		return nil, err
 	}
+	c.seen(reporter)
 
-	w.WriteHeader(http.StatusNoContent)
+	if err := c.emit(ctx, eventingapi.ResourceUpdated, identity, reporter, model.ID, &before, &model); err != nil {
+		return &model, &EventError{Err: err}
+	}
+	return &model, nil
 }
 
-func (c *ResourceController) CreateResourceFromInput(input *models.ResourceIn, identity *authnapi.Identity) (*models.Resource, error) {
-	var reporterType string
-	if len(identity.Type) > 0 {
-		reporterType = identity.Type
-	} else if len(input.ReporterType) > 0 {
-		reporterType = input.ReporterType
-	} else {
-		return nil, fmt.Errorf("ReporterType must not be empty.")
+// DeleteResource deletes the resource with the id and sends the deleted event.  Errors are like CreateResource's.
+func (c *ResourceController) DeleteResource(ctx context.Context, id models.IDType, identity *authnapi.Identity) error {
+	reporter, err := c.checkReporter(identity)
+	if err != nil {
+		return err
 	}
 
-	var count int64
-	c.Db.Model(&models.ReporterData{}).
-		Where("reporter_id = ? and reporter_type = ? and local_resource_id = ?", identity.Principal, reporterType, input.LocalResourceId).
-		Count(&count)
+	// load it first so the event can say what was deleted
+	var model models.Resource
+	if err := c.Db.WithContext(ctx).Preload("ReporterData").Where("resource_type = ?", c.ResourceType).First(&model, id).Error; err != nil {
+		return c.notFound(err, id)
+	}
 
-	if count > 0 {
-		return nil, fmt.Errorf("Resource for instance %s of ReporterType %s already exists", identity.Principal, reporterType)
+	result := c.Db.WithContext(ctx).Where("resource_type = ?", c.ResourceType).Delete(&models.Resource{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = gorm.ErrRecordNotFound
+	}
+	if err := result.Error; err != nil {
+		return c.notFound(err, id)
 	}
+	c.seen(reporter)
+
+	if err := c.emit(ctx, eventingapi.ResourceDeleted, identity, reporter, model.ID, &model, nil); err != nil {
+		return &EventError{Err: err}
+	}
+	return nil
+}
+
+// FindReported returns the resource of the controller's type the identity reported, as its reporter type, with the
+// local id.
+func (c *ResourceController) FindReported(ctx context.Context, identity *authnapi.Identity, localResourceId string) (*models.Resource, error) {
+	var model models.Resource
+	err := c.Db.WithContext(ctx).
+		Joins("join reporter_data on reporter_data.resource_id = resources.id").
+		Where("reporter_data.reporter_id = ? and reporter_data.reporter_type = ? and reporter_data.local_resource_id = ? and resources.resource_type = ?", identity.Principal, identity.Type, localResourceId, c.ResourceType).
+		First(&model).Error
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// notFound is a problem for a missing resource with the id, or the error if it's something else.
+func (c *ResourceController) notFound(err error, id models.IDType) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return cerrors.NewProblem(http.StatusNotFound, cerrors.CodeNotFound, fmt.Sprintf("No %s with id %d", c.ResourceType, id))
+	}
+	return err
+}
+
+// writeChangeError writes the problem for an error from CreateResource, UpdateResource or DeleteResource.
+func (c *ResourceController) writeChangeError(w http.ResponseWriter, r *http.Request, err error) {
+	var problem *cerrors.Problem
+	var eventErr *EventError
+	switch {
+	case errors.As(err, &problem):
+		middleware.WriteProblem(w, r, problem)
+	case errors.As(err, &eventErr):
+		c.writeEventProblem(w, r, eventErr.Err)
+	default:
+		writeDbProblem(w, r, err, "")
+	}
+}
+
+func (c *ResourceController) CreateResourceFromInput(input *models.ResourceIn, identity *authnapi.Identity, reporter *models.Reporter) (*models.Resource, error) {
+	reporterType, err := resolveReporterType(input, identity, reporter)
+	if err != nil {
+		return nil, err
+	}
+
+	reporterVersion := resolveReporterVersion(input, reporter)
 
 	var localTime time.Time
 	if input.LocalTime != nil {
@@ -339,13 +462,14 @@ func (c *ResourceController) CreateResourceFromInput(input *models.ResourceIn, i
 		Workspace:    input.Workspace,
 		ReporterData: []models.ReporterData{{
 			ReporterID: identity.Principal,
+			Tenant:     identity.Tenant,
 
 			Created: localTime,
 			Updated: localTime,
 
 			LocalResourceId: input.LocalResourceId,
 			ReporterType:    reporterType,
-			ReporterVersion: input.ReporterVersion,
+			ReporterVersion: reporterVersion,
 
 			ConsoleHref: input.ConsoleHref,
 			ApiHref:     input.ApiHref,
@@ -355,7 +479,13 @@ func (c *ResourceController) CreateResourceFromInput(input *models.ResourceIn, i
 	}, nil
 }
 
-func (c *ResourceController) UpdateResourceFromInput(input *models.ResourceIn, model *models.Resource, identity *authnapi.Identity) error {
+func (c *ResourceController) UpdateResourceFromInput(input *models.ResourceIn, model *models.Resource, identity *authnapi.Identity, reporter *models.Reporter) error {
+	reporterType, err := resolveReporterType(input, identity, reporter)
+	if err != nil {
+		return err
+	}
+	reporterVersion := resolveReporterVersion(input, reporter)
+
 	model.DisplayName = input.DisplayName
 	if input.Workspace != nil {
 		model.Workspace = input.Workspace
@@ -375,7 +505,8 @@ func (c *ResourceController) UpdateResourceFromInput(input *models.ResourceIn, m
 			found = true
 
 			r.Updated = localTime
-			r.ReporterVersion = input.ReporterVersion
+			r.Tenant = identity.Tenant
+			r.ReporterVersion = reporterVersion
 			r.Data = datatypes.JSON(input.Data)
 
 			r.ConsoleHref = input.ConsoleHref
@@ -388,20 +519,137 @@ func (c *ResourceController) UpdateResourceFromInput(input *models.ResourceIn, m
 	if !found {
 		reporter := models.ReporterData{
 			ReporterID: identity.Principal,
+			Tenant:     identity.Tenant,
 
 			Created: localTime,
 			Updated: localTime,
 
 			LocalResourceId: input.LocalResourceId,
-			ReporterType:    identity.Type,
-			ReporterVersion: input.ReporterVersion,
+			ReporterType:    reporterType,
+			ReporterVersion: reporterVersion,
 
 			ConsoleHref: input.ConsoleHref,
 			ApiHref:     input.ApiHref,
 
 			Data: datatypes.JSON(input.Data),
 		}
-		model.ReporterData = []models.ReporterData{reporter}
+		model.ReporterData = append(model.ReporterData, reporter)
+	}
+	return nil
+}
+
+// resolveReporterType returns the type of the caller's reports.  A registered reporter's type takes precedence
+// over the identity's, and the input must not claim a different one.
+func resolveReporterType(input *models.ResourceIn, identity *authnapi.Identity, reporter *models.Reporter) (string, error) {
+	var reporterType string
+	switch {
+	case reporter != nil:
+		reporterType = reporter.ReporterType
+	case len(identity.Type) > 0:
+		reporterType = identity.Type
+	case len(input.ReporterType) > 0:
+		return input.ReporterType, nil
+	default:
+		return "", cerrors.NewFieldError("ReporterType", "must not be empty")
+	}
+
+	if len(input.ReporterType) > 0 && input.ReporterType != reporterType {
+		return "", cerrors.NewFieldError("ReporterType", fmt.Sprintf("must be %s, the type of reporter %s", reporterType, identity.Principal))
+	}
+	return reporterType, nil
+}
+
+// resolveReporterVersion returns the version of the caller's reports.  It defaults to the registered reporter's.
+func resolveReporterVersion(input *models.ResourceIn, reporter *models.Reporter) string {
+	if input.ReporterVersion == "" && reporter != nil {
+		return reporter.ReporterVersion
+	}
+	return input.ReporterVersion
+}
+
+// checkReporter returns a problem if the identity may not change resources of the controller's type.  It returns
+// the identity's registration, or nil if it isn't registered and registration isn't required.
+func (c *ResourceController) checkReporter(identity *authnapi.Identity) (*models.Reporter, error) {
+	// Find rather than First, since most callers may be unregistered and First logs every miss
+	var reporters []models.Reporter
+	if err := c.Db.Where("principal = ?", identity.Principal).Limit(1).Find(&reporters).Error; err != nil {
+		return nil, err
+	}
+
+	if len(reporters) == 0 {
+		if c.RequireRegisteredReporters {
+			return nil, cerrors.NewProblem(http.StatusForbidden, cerrors.CodeForbidden, fmt.Sprintf("%s isn't a registered reporter", identity.Principal))
+		}
+		return nil, nil
+	}
+	reporter := &reporters[0]
+
+	if len(identity.Type) > 0 && identity.Type != reporter.ReporterType {
+		msg := fmt.Sprintf("%s is registered with ReporterType %s but authenticated as %s", identity.Principal, reporter.ReporterType, identity.Type)
+		return nil, cerrors.NewProblem(http.StatusForbidden, cerrors.CodeForbidden, msg)
+	}
+
+	if !reporter.Allows(c.ResourceType) {
+		return nil, cerrors.NewProblem(http.StatusForbidden, cerrors.CodeForbidden, fmt.Sprintf("Reporter %s may not report %s resources", identity.Principal, c.ResourceType))
+	}
+
+	return reporter, nil
+}
+
+// seen records when a registered reporter last changed a resource.  Failures are only logged since the change
+// itself succeeded.
+func (c *ResourceController) seen(reporter *models.Reporter) {
+	if reporter == nil {
+		return
+	}
+	if err := c.Db.Model(reporter).UpdateColumn("last_seen_at", time.Now().UTC()).Error; err != nil {
+		c.Log.Warn(fmt.Sprintf("Failed to record when reporter %s was last seen: %v", reporter.Principal, err))
+	}
+}
+
+// emit sends an event about a change to the resource with the id.  before is nil for created resources and after
+// is nil for deleted ones.  The error is what's left after the eventing manager applied its failure policy.
+func (c *ResourceController) emit(ctx context.Context, eventType string, identity *authnapi.Identity, reporter *models.Reporter, id models.IDType, before *models.Resource, after *models.Resource) error {
+	if c.EventingManager == nil {
+		return nil
+	}
+
+	resource := after
+	if resource == nil {
+		resource = before
+	}
+
+	href := fmt.Sprintf("%s/%d", c.BasePath, id)
+	evt := eventingapi.NewResourceEvent(eventType, href, id, c.ResourceType, before, after, eventReporter(identity, reporter, resource), identity)
+
+	producer, err := c.EventingManager.Lookup(identity, resource)
+	if err != nil {
+		return err
+	}
+	return producer.Produce(ctx, evt)
+}
+
+// writeEventProblem reports a change that was saved but whose event couldn't be sent.
+func (c *ResourceController) writeEventProblem(w http.ResponseWriter, r *http.Request, err error) {
+	c.Log.Error(fmt.Sprintf("Failed to send the %s event: %v", c.ResourceType, err))
+	writeProblem(w, r, http.StatusServiceUnavailable, cerrors.CodeUnavailable, "The change was saved, but its event couldn't be sent")
+}
+
+// eventReporter describes the reporter that made a change from its registration, its data about the resource or
+// its identity.  It returns nil if the caller isn't known as a reporter.
+func eventReporter(identity *authnapi.Identity, reporter *models.Reporter, resource *models.Resource) *eventingapi.Reporter {
+	if reporter != nil {
+		return &eventingapi.Reporter{ReporterId: reporter.Principal, ReporterType: reporter.ReporterType, ReporterVersion: reporter.ReporterVersion}
+	}
+
+	for _, d := range resource.ReporterData {
+		if d.ReporterID == identity.Principal {
+			return &eventingapi.Reporter{ReporterId: d.ReporterID, ReporterType: d.ReporterType, ReporterVersion: d.ReporterVersion}
+		}
+	}
+
+	if len(identity.Type) > 0 {
+		return &eventingapi.Reporter{ReporterId: identity.Principal, ReporterType: identity.Type}
 	}
 	return nil
 }
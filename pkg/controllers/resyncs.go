@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gorm.io/gorm"
+
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	"github.com/csams/common-inventory/pkg/models"
+	"github.com/csams/common-inventory/pkg/resync"
+)
+
+// ResyncController starts resyncs, which send a snapshot event for every matching resource and then an event that
+// marks the end, and reports how far they got.  Resyncs run in the background on the server that started or
+// resumed them.
+type ResyncController struct {
+	BasePath string
+	Db       *gorm.DB
+	Resyncer *resync.Resyncer
+	Log      *slog.Logger
+}
+
+func NewResyncController(basePath string, db *gorm.DB, resyncer *resync.Resyncer, log *slog.Logger) *ResyncController {
+	return &ResyncController{
+		BasePath: basePath,
+		Db:       db,
+		Resyncer: resyncer,
+		Log:      log,
+	}
+}
+
+func (c ResyncController) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(middleware.Pagination).Get("/", c.List)
+	r.Post("/", c.Create)
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", c.Get)
+		r.Post("/resume", c.Resume)
+		r.Post("/cancel", c.Cancel)
+	})
+
+	return r
+}
+
+// List returns the resyncs, newest first.
+func (c *ResyncController) List(w http.ResponseWriter, r *http.Request) {
+	pagination, err := middleware.GetPaginationRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	db := c.Db.Model(&models.Resync{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var results []models.Resync
+	if err := db.Scopes(pagination.Filter).Order("id desc").Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var output []*models.ResyncOut
+	for _, result := range results {
+		rs := result
+		output = append(output, models.NewResyncOut(&rs, c.href(&rs)))
+	}
+
+	resp := &middleware.PagedResponse[*models.ResyncOut]{
+		PagedReponseMetadata: middleware.PagedReponseMetadata{
+			Page:  pagination.Page,
+			Size:  len(results),
+			Total: count,
+		},
+		Items: output,
+	}
+
+	render.JSON(w, r, resp)
+}
+
+func (c *ResyncController) Get(w http.ResponseWriter, r *http.Request) {
+	rs, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewResyncOut(rs, c.href(rs)))
+}
+
+// Create starts a resync of the resources that match the filters in the body.
+func (c *ResyncController) Create(w http.ResponseWriter, r *http.Request) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	var input models.ResyncIn
+	if err := render.Decode(r, &input); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("The request body is invalid: %v", err))
+		return
+	}
+
+	if errs := c.Resyncer.Validate(&input); errs != nil {
+		middleware.WriteProblem(w, r, cerrors.NewValidationProblem(errs))
+		return
+	}
+
+	rs, err := c.Resyncer.Create(r.Context(), &input, identity)
+	if err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	c.start(w, r, rs)
+}
+
+// Resume runs a resync that stopped, starting after the last resource it sent.
+func (c *ResyncController) Resume(w http.ResponseWriter, r *http.Request) {
+	rs, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	c.start(w, r, rs)
+}
+
+// Cancel stops a resync running on this server.  It's recorded as failed, so it can be resumed.
+func (c *ResyncController) Cancel(w http.ResponseWriter, r *http.Request) {
+	rs, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	if !c.Resyncer.Cancel(rs.ID) {
+		writeProblem(w, r, http.StatusConflict, cerrors.CodeConflict, fmt.Sprintf("Resync %d isn't running on this server", rs.ID))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// start runs the resync in the background and responds with its state when it started.
+func (c *ResyncController) start(w http.ResponseWriter, r *http.Request, rs *models.Resync) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	// the resync updates its own copy as it runs
+	running := *rs
+	if err := c.Resyncer.Start(&running, identity); err != nil {
+		switch {
+		case errors.Is(err, resync.ErrRunning), errors.Is(err, resync.ErrCompleted):
+			writeProblem(w, r, http.StatusConflict, cerrors.CodeConflict, fmt.Sprintf("Resync %d: %v", rs.ID, err))
+		default:
+			writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		}
+		return
+	}
+
+	rs.Status = models.ResyncRunning
+	rs.LastError = ""
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, models.NewResyncOut(rs, c.href(rs)))
+}
+
+// find writes a problem and returns false if the resync in the path doesn't exist.
+func (c *ResyncController) find(w http.ResponseWriter, r *http.Request) (*models.Resync, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The id must be an integer")
+		return nil, false
+	}
+
+	rs := &models.Resync{}
+	if err := c.Db.First(rs, id).Error; err != nil {
+		writeDbProblem(w, r, err, fmt.Sprintf("No resync with id %d", id))
+		return nil, false
+	}
+
+	return rs, true
+}
+
+func (c *ResyncController) href(rs *models.Resync) string {
+	return fmt.Sprintf("%s/%d", c.BasePath, rs.ID)
+}
@@ -16,19 +16,63 @@ import (
 	authzapi "github.com/csams/common-inventory/pkg/authz/api"
 	mw "github.com/csams/common-inventory/pkg/controllers/middleware"
 	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/subscriptions"
+	"github.com/csams/common-inventory/pkg/resync"
 )
 
-func NewRootHandler(db *gorm.DB, authenticator authnapi.Authenticator, authorizer authzapi.Authorizer, eventingManager eventingapi.Manager, log *slog.Logger) chi.Router {
-	basePath := "/api/inventory/v1alpha1"
+// ResourceType is a resource type served by a ResourceController at /resources/<Path>.
+type ResourceType struct {
+	Name string
+	Path string
+}
+
+var ResourceTypes = []ResourceType{
+	{Name: "host", Path: "hosts"},
+	{Name: "cluster", Path: "clusters"},
+	{Name: "acm-policy", Path: "acm-policies"},
+}
+
+// BasePath is the path under which the inventory API is served.
+const BasePath = "/api/inventory/v1alpha1"
+
+// ResourcePaths maps each resource type to the base path of its resources.
+func ResourcePaths() map[string]string {
+	paths := map[string]string{}
+	for _, rt := range ResourceTypes {
+		paths[rt.Name] = fmt.Sprintf("%s/resources/%s", BasePath, rt.Path)
+	}
+	return paths
+}
+
+// NewResourceControllers returns a ResourceController for each resource type by name.
+func NewResourceControllers(db *gorm.DB, authorizer authzapi.Authorizer, eventingManager eventingapi.Manager, requireRegisteredReporters bool, log *slog.Logger) map[string]*ResourceController {
+	controllers := map[string]*ResourceController{}
+	for name, path := range ResourcePaths() {
+		controllers[name] = NewResourceController(path, name, db, authorizer, eventingManager, requireRegisteredReporters, log)
+	}
+	return controllers
+}
+
+func NewRootHandler(db *gorm.DB, authenticator authnapi.Authenticator, authorizer authzapi.Authorizer, admins *authzapi.Admins, requireRegisteredReporters bool, eventingManager eventingapi.Manager, resyncer *resync.Resyncer, callbackTargets *subscriptions.Targets, log *slog.Logger) chi.Router {
+	basePath := BasePath
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
 	r.Use(slogchi.New(log))
-	r.Use(middleware.Recoverer)
+	r.Use(mw.Recoverer(log))
 	r.Use(middleware.CleanPath)
 
+	r.NotFound(mw.NotFound)
+	r.MethodNotAllowed(mw.MethodNotAllowed)
+
 	r.Get("/healthz", Ready)
+	r.Get("/readyz", Readiness(eventingManager))
+
+	// the spec is public so clients can be generated before they have credentials
+	r.Get(basePath+OpenAPIPath, NewOpenAPISpec(basePath, ResourceTypes).Handler())
+	r.Get(basePath+EventSchemaPath, EventSchema(eventingapi.SchemaFile))
+	r.Get(basePath+ResyncSchemaPath, EventSchema(eventingapi.ResyncSchemaFile))
 
 	r.With(
 		mw.Logger(log),
@@ -36,9 +80,21 @@ func NewRootHandler(db *gorm.DB, authenticator authnapi.Authenticator, authorize
 		render.SetContentType(render.ContentTypeJSON),
 	).
 		Route(basePath, func(r chi.Router) {
-			r.Mount("/resources/hosts", NewResourceController(fmt.Sprintf("%s/resources/hosts", basePath), "host", db, authorizer, eventingManager, log).Routes())
-			r.Mount("/resources/clusters", NewResourceController(fmt.Sprintf("%s/resources/clusters", basePath), "cluster", db, authorizer, eventingManager, log).Routes())
-			r.Mount("/resources/acm-policies", NewResourceController(fmt.Sprintf("%s/resources/acm-policies", basePath), "acm-policy", db, authorizer, eventingManager, log).Routes())
+			resourceControllers := NewResourceControllers(db, authorizer, eventingManager, requireRegisteredReporters, log)
+			for _, rt := range ResourceTypes {
+				r.Mount("/resources/"+rt.Path, resourceControllers[rt.Name].Routes())
+			}
+			r.Mount("/resources", NewAllResourcesController(ResourcePaths(), db, authorizer, log).Routes())
+			// the status has file paths and errors that only operators should see
+			r.With(mw.RejectGuests, mw.RequireAdmin(admins)).Get("/status", Status(authenticator, eventingManager))
+			r.With(mw.RejectGuests).Mount("/reporters", NewReporterController(basePath+"/reporters", db, admins, log).Routes())
+			r.With(mw.RejectGuests).Mount("/subscriptions", NewSubscriptionController(basePath+"/subscriptions", db, admins, callbackTargets, log).Routes())
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(mw.RequireAdmin(admins))
+				r.Mount("/apikeys", NewApiKeyController(basePath+"/admin/apikeys", db, log).Routes())
+				r.Mount("/resyncs", NewResyncController(basePath+"/admin/resyncs", db, resyncer, log).Routes())
+			})
 		})
 
 	return r
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/authn/psk"
+	"github.com/csams/common-inventory/pkg/authz/allow"
+	authzapi "github.com/csams/common-inventory/pkg/authz/api"
+	"github.com/csams/common-inventory/pkg/eventing/stdout"
+	"github.com/csams/common-inventory/pkg/models/migrations"
+)
+
+func newTestDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "inventory.db")), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestServer serves the API with the authenticator and lets every authenticated request through authorization.
+func newTestServer(t *testing.T, db *gorm.DB, authenticator authnapi.Authenticator, admins *authzapi.Admins) *httptest.Server {
+	em, err := stdout.New("urn:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := httptest.NewServer(NewRootHandler(db, authenticator, allow.New(), admins, false, em, nil, nil, log))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, method string, url string, token string, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestScopedKeys(t *testing.T) {
+	config, err := (&psk.Config{Keys: psk.KeyMap{
+		"scoped": {Identity: authnapi.Identity{
+			Principal:  "acm-hub-1",
+			Type:       "ACM",
+			IsReporter: true,
+			Scopes:     &authnapi.Scopes{ResourceTypes: []string{"cluster"}, Verbs: []string{authnapi.VerbList, authnapi.VerbCreate}},
+		}},
+	}}).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := psk.New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, newTestDb(t), authenticator, nil)
+
+	body := `{"DisplayName": "cluster 1", "ResourceType": "cluster", "LocalResourceId": "1", "Workspace": "prod", "Data": {}}`
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodPost, "/resources/clusters", http.StatusCreated},
+		{http.MethodGet, "/resources/clusters", http.StatusOK},
+		// a resource type and a verb the key isn't scoped to
+		{http.MethodPost, "/resources/hosts", http.StatusForbidden},
+		{http.MethodDelete, "/resources/clusters/1", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		resp := do(t, test.method, srv.URL+BasePath+test.path, "scoped", body)
+		if resp.StatusCode != test.status {
+			b, _ := io.ReadAll(resp.Body)
+			t.Errorf("%s %s: expected %d, got %d: %s", test.method, test.path, test.status, resp.StatusCode, b)
+		}
+	}
+}
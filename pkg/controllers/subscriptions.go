@@ -0,0 +1,377 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gorm.io/gorm"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	authzapi "github.com/csams/common-inventory/pkg/authz/api"
+	"github.com/csams/common-inventory/pkg/controllers/middleware"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/subscriptions"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// SubscriptionController lets callers register webhooks for resource events and see how their deliveries went.
+// Callers only see their own subscriptions, except admins, who see all of them.  The deliveries are sent by the
+// subscriptions eventer.
+type SubscriptionController struct {
+	BasePath string
+	Db       *gorm.DB
+	Admins   *authzapi.Admins
+	Log      *slog.Logger
+
+	// Targets are the addresses callbacks may be sent to.
+	Targets *subscriptions.Targets
+}
+
+func NewSubscriptionController(basePath string, db *gorm.DB, admins *authzapi.Admins, targets *subscriptions.Targets, log *slog.Logger) *SubscriptionController {
+	return &SubscriptionController{
+		BasePath: basePath,
+		Db:       db,
+		Admins:   admins,
+		Log:      log,
+		Targets:  targets,
+	}
+}
+
+func (c SubscriptionController) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(middleware.Pagination).Get("/", c.List)
+	r.Post("/", c.Create)
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", c.Get)
+		r.Put("/", c.Update)
+		r.Delete("/", c.Delete)
+		r.With(middleware.Pagination).Get("/deliveries", c.ListDeliveries)
+		r.Get("/deliveries/{delivery_id}", c.GetDelivery)
+		r.Post("/deliveries/{delivery_id}/redeliver", c.Redeliver)
+	})
+
+	return r
+}
+
+func (c *SubscriptionController) List(w http.ResponseWriter, r *http.Request) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	pagination, err := middleware.GetPaginationRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	db := c.owned(identity).Model(&models.Subscription{}).Session(&gorm.Session{})
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var results []models.Subscription
+	if err := db.Scopes(pagination.Filter).Order("id").Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var output []*models.SubscriptionOut
+	for _, result := range results {
+		s := result
+		output = append(output, models.NewSubscriptionOut(&s, c.href(&s), ""))
+	}
+
+	resp := &middleware.PagedResponse[*models.SubscriptionOut]{
+		PagedReponseMetadata: middleware.PagedReponseMetadata{
+			Page:  pagination.Page,
+			Size:  len(results),
+			Total: count,
+		},
+		Items: output,
+	}
+
+	render.JSON(w, r, resp)
+}
+
+func (c *SubscriptionController) Get(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewSubscriptionOut(subscription, c.href(subscription), ""))
+}
+
+// Create registers a subscription.  The secret that signs its deliveries is only returned in the response.
+func (c *SubscriptionController) Create(w http.ResponseWriter, r *http.Request) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	input, ok := c.decode(w, r, identity)
+	if !ok {
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, "Failed to generate a secret")
+		return
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	subscription := models.NewSubscription(input, identity.Principal, secret)
+	if err := c.Db.Create(subscription).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewSubscriptionOut(subscription, c.href(subscription), secret))
+}
+
+// Update replaces the callback URL and the filters.  The secret and the deliveries are kept.
+func (c *SubscriptionController) Update(w http.ResponseWriter, r *http.Request) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return
+	}
+
+	input, ok := c.decode(w, r, identity)
+	if !ok {
+		return
+	}
+
+	subscription, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	subscription.Update(input)
+	if err := c.Db.Model(subscription).Select("callback_url", "resource_types", "workspaces", "event_types").Updates(subscription).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete removes the subscription and its deliveries.
+func (c *SubscriptionController) Delete(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Db.Delete(subscription).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the subscription's deliveries, newest first.
+func (c *SubscriptionController) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := c.find(w, r)
+	if !ok {
+		return
+	}
+
+	pagination, err := middleware.GetPaginationRequest(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, cerrors.CodeInternal, err.Error())
+		return
+	}
+
+	db := c.Db.Model(&models.Delivery{}).Where("subscription_id = ?", subscription.ID)
+	if status := r.URL.Query().Get("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var results []models.Delivery
+	if err := db.Scopes(pagination.Filter).Order("id desc").Find(&results).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	var output []*models.DeliveryOut
+	for _, result := range results {
+		d := result
+		output = append(output, models.NewDeliveryOut(&d, c.deliveryHref(subscription, &d)))
+	}
+
+	resp := &middleware.PagedResponse[*models.DeliveryOut]{
+		PagedReponseMetadata: middleware.PagedReponseMetadata{
+			Page:  pagination.Page,
+			Size:  len(results),
+			Total: count,
+		},
+		Items: output,
+	}
+
+	render.JSON(w, r, resp)
+}
+
+func (c *SubscriptionController) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	subscription, delivery, ok := c.findDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewDeliveryOut(delivery, c.deliveryHref(subscription, delivery)))
+}
+
+// Redeliver sends the delivery again with a fresh set of attempts, whatever happened to it before.
+func (c *SubscriptionController) Redeliver(w http.ResponseWriter, r *http.Request) {
+	subscription, delivery, ok := c.findDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	if err := c.Db.Model(delivery).Select("status", "attempts", "next_attempt_at").Updates(delivery).Error; err != nil {
+		writeDbProblem(w, r, err, "")
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, models.NewDeliveryOut(delivery, c.deliveryHref(subscription, delivery)))
+}
+
+// decode writes a problem and returns false if the body isn't a valid subscription for the identity or its callback
+// isn't an allowed target.  Missing resource types default to the ones the identity may list.
+func (c *SubscriptionController) decode(w http.ResponseWriter, r *http.Request, identity *authnapi.Identity) (*models.SubscriptionIn, bool) {
+	var input models.SubscriptionIn
+	if err := render.Decode(r, &input); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, fmt.Sprintf("The request body is invalid: %v", err))
+		return nil, false
+	}
+
+	errs := input.Validate()
+	if errs == nil {
+		if err := c.Targets.CheckURL(r.Context(), input.CallbackURL); err != nil {
+			errs = append(errs, cerrors.NewFieldError("CallbackURL", err.Error()))
+		}
+	}
+	for _, t := range input.ResourceTypes {
+		if !isResourceType(t) {
+			errs = append(errs, cerrors.NewFieldError("ResourceTypes", fmt.Sprintf("unknown resource type %s", t)))
+		} else if !identity.Allows(t, authnapi.VerbList) {
+			errs = append(errs, cerrors.NewFieldError("ResourceTypes", fmt.Sprintf("you may not list %s resources", t)))
+		}
+	}
+	for _, t := range input.EventTypes {
+		if !slices.Contains(eventingapi.EventTypes, t) {
+			errs = append(errs, cerrors.NewFieldError("EventTypes", fmt.Sprintf("unknown event type %s", t)))
+		}
+	}
+
+	if len(input.ResourceTypes) == 0 {
+		for _, rt := range ResourceTypes {
+			if identity.Allows(rt.Name, authnapi.VerbList) {
+				input.ResourceTypes = append(input.ResourceTypes, rt.Name)
+			}
+		}
+		if len(input.ResourceTypes) == 0 {
+			errs = append(errs, cerrors.NewFieldError("ResourceTypes", "you may not list any resource types"))
+		}
+	}
+
+	if errs != nil {
+		middleware.WriteProblem(w, r, cerrors.NewValidationProblem(errs))
+		return nil, false
+	}
+
+	return &input, true
+}
+
+// find writes a problem and returns false if the subscription in the path doesn't exist or isn't the caller's.
+func (c *SubscriptionController) find(w http.ResponseWriter, r *http.Request) (*models.Subscription, bool) {
+	identity, err := middleware.GetIdentity(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, cerrors.CodeUnauthenticated, "Not Authenticated")
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The id must be an integer")
+		return nil, false
+	}
+
+	subscription := &models.Subscription{}
+	if err := c.owned(identity).First(subscription, id).Error; err != nil {
+		writeDbProblem(w, r, err, fmt.Sprintf("No subscription with id %d", id))
+		return nil, false
+	}
+
+	return subscription, true
+}
+
+// findDelivery writes a problem and returns false if the delivery in the path isn't one of the subscription's.
+func (c *SubscriptionController) findDelivery(w http.ResponseWriter, r *http.Request) (*models.Subscription, *models.Delivery, bool) {
+	subscription, ok := c.find(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "delivery_id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, cerrors.CodeInvalidRequest, "The delivery id must be an integer")
+		return nil, nil, false
+	}
+
+	delivery := &models.Delivery{}
+	if err := c.Db.Where("subscription_id = ?", subscription.ID).First(delivery, id).Error; err != nil {
+		writeDbProblem(w, r, err, fmt.Sprintf("No delivery with id %d", id))
+		return nil, nil, false
+	}
+
+	return subscription, delivery, true
+}
+
+// owned limits a query to the identity's subscriptions unless it's an admin.
+func (c *SubscriptionController) owned(identity *authnapi.Identity) *gorm.DB {
+	if c.Admins.IsAdmin(identity) {
+		return c.Db
+	}
+	return c.Db.Where("owner = ?", identity.Principal)
+}
+
+func (c *SubscriptionController) href(subscription *models.Subscription) string {
+	return fmt.Sprintf("%s/%d", c.BasePath, subscription.ID)
+}
+
+func (c *SubscriptionController) deliveryHref(subscription *models.Subscription, delivery *models.Delivery) string {
+	return fmt.Sprintf("%s/%d/deliveries/%d", c.BasePath, subscription.ID, delivery.ID)
+}
@@ -19,8 +19,3 @@ func (a Aggregate) Error() string {
 	}
 	return strings.Join(strs, "\n")
 }
-
-type HttpError struct {
-	Status  int    `json:"status"`
-	Message string `json:"messgae"`
-}
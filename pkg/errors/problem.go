@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Stable error codes carried by every Problem.  Clients should switch on these rather than on Title or Detail.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// ProblemTypePrefix is prepended to the Code to form a Problem's Type.
+const ProblemTypePrefix = "urn:common-inventory:problem:"
+
+// ProblemContentType is the media type of a serialized Problem.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document.  See https://www.rfc-editor.org/rfc/rfc7807
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// extension members
+	Code      string       `json:"code"`
+	RequestId string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+func NewProblem(status int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   ProblemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// NewValidationProblem describes the errors returned by a Validate method.  FieldErrors are reported
+// individually and any other errors are joined into the Detail.
+func NewValidationProblem(errs []error) *Problem {
+	p := NewProblem(http.StatusBadRequest, CodeValidationFailed, "")
+
+	var other []error
+	for _, err := range errs {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			p.Errors = append(p.Errors, *fe)
+		} else {
+			other = append(other, err)
+		}
+	}
+
+	if other != nil {
+		p.Detail = NewAggregate(other).Error()
+	} else {
+		p.Detail = "The request has invalid fields"
+	}
+
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("%s: %s", p.Title, p.Detail)
+	}
+	return p.Title
+}
+
+// FieldError is a validation error for a single field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func NewFieldError(field string, message string) *FieldError {
+	return &FieldError{Field: field, Message: message}
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is an error a Manager reports on Errs.  Fatal errors mean the manager can't send events any more and the
+// server should shut down.  The rest are transient: the manager recovers on its own and reports whether it has
+// through Ready.
+type Error struct {
+	Err   error
+	Fatal bool
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func Fatal(err error) error {
+	return &Error{Err: err, Fatal: true}
+}
+
+func Transient(err error) error {
+	return &Error{Err: err}
+}
+
+// IsFatal returns true for fatal errors and errors that weren't classified.
+func IsFatal(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fatal
+	}
+	return true
+}
+
+// DeliveryError is an event that was produced but never delivered, after the backend's own retries.
+type DeliveryError struct {
+	// Destination is the topic or subscriber the event was for.
+	Destination string
+	Err         error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("failed to deliver an event to %s: %v", e.Destination, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
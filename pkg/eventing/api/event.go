@@ -1,10 +1,292 @@
 package api
 
+import (
+	"embed"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// The types of the events about resources.
+const (
+	ResourceCreated = "com.redhat.inventory.resource.created"
+	ResourceUpdated = "com.redhat.inventory.resource.updated"
+	ResourceDeleted = "com.redhat.inventory.resource.deleted"
+
+	// ResourceSnapshot is the state of a resource, sent by a resync rather than for a change.
+	ResourceSnapshot = "com.redhat.inventory.resource.snapshot"
+)
+
+// ResyncCompleted marks the end of a resync.  Every snapshot of the resync was sent before it.
+const ResyncCompleted = "com.redhat.inventory.resync.completed"
+
+// EventTypes are the types above.
+var EventTypes = []string{ResourceCreated, ResourceUpdated, ResourceDeleted, ResourceSnapshot, ResyncCompleted}
+
+// SchemaVersion is the version of the event contract.  Changes that could break consumers need a new version.
+const SchemaVersion = "v1"
+
+// DataSchema identifies the JSON Schema of the resource events' data.  It's their dataschema attribute.
+const DataSchema = "urn:common-inventory:schema:resource-event:" + SchemaVersion
+
+// ResyncDataSchema identifies the JSON Schema of the data of the events that mark the end of a resync.
+const ResyncDataSchema = "urn:common-inventory:schema:resync-completed:" + SchemaVersion
+
+// Schemas holds the published JSON Schemas, like schemas/resource-event.v1.json, and the Avro and Protobuf schemas
+// of the same data.  They must be kept in sync with ResourceData and ResyncData.
+//
+//go:embed schemas
+var Schemas embed.FS
+
+// SchemaFile is the file in Schemas that describes the current version.
+const SchemaFile = "schemas/resource-event." + SchemaVersion + ".json"
+
+// ResyncSchemaFile is the file in Schemas that describes the current version of the resync events.
+const ResyncSchemaFile = "schemas/resync-completed." + SchemaVersion + ".json"
+
+// The files in Schemas with the Avro and Protobuf schemas of the current version, for serializers that register
+// them with a schema registry.  The proto file has a message for each kind of data.
+const (
+	AvroSchemaFile       = "schemas/resource-event." + SchemaVersion + ".avsc"
+	ResyncAvroSchemaFile = "schemas/resync-completed." + SchemaVersion + ".avsc"
+	ProtoSchemaFile      = "schemas/events." + SchemaVersion + ".proto"
+)
+
+// Event is a change to a resource.  Producers send it as a CloudEvent with the id, the time, the type, the server's
+// source, the subject, the data schema and the data.
 type Event struct {
-	// TODO: enumerate the predefined event types.
-	EventType string
+	// ID is the CloudEvent id.  It's set when the event is made, so every retry, replay from the spool and copy
+	// sent by another eventer has the same id, and consumers can drop the ones they've already seen.
+	ID string
+
+	// Time is when the event was made.
+	Time time.Time
+
+	// Type is one of the event types above.
+	Type string
+
+	// Subject is the href of the resource.
+	Subject string
 
 	// TODO: events may be sent for relationships as well as resource types.
 	ResourceType string
-	Object       interface{}
+
+	// Key orders the events.  Producers that partition them keep the ones with the same key in order.  It's the
+	// resource id for resource events.
+	Key string
+
+	// Broadcast sends the event to every partition of destinations that partition events, instead of the one its
+	// key picks, so the consumers of each partition see it after the events that were sent before it.
+	Broadcast bool `json:",omitempty"`
+
+	// DataSchema is the dataschema attribute.  It defaults to DataSchema for events spooled before it was set.
+	DataSchema string
+
+	// Data is a ResourceData for resource events and a ResyncData for the end of a resync.
+	Data any
+}
+
+// ResourceData is the data of resource events.  It's decoupled from the database models so the contract only
+// changes on purpose.
+type ResourceData struct {
+	ResourceId   string `json:"resource_id"`
+	ResourceType string `json:"resource_type"`
+
+	// Before is the resource before the change.  It's omitted from created events.
+	Before *Resource `json:"before,omitempty"`
+
+	// After is the resource after the change.  It's omitted from deleted events.
+	After *Resource `json:"after,omitempty"`
+
+	// Reporter is the reporter that made the change.
+	Reporter *Reporter `json:"reporter,omitempty"`
+
+	// Actor is who made the change.
+	Actor *Actor `json:"actor"`
+
+	// ResyncId is the resync that sent a snapshot event.
+	ResyncId string `json:"resync_id,omitempty"`
+}
+
+// ResyncData is the data of the event that marks the end of a resync.
+type ResyncData struct {
+	ResyncId string       `json:"resync_id"`
+	Filter   ResyncFilter `json:"filter"`
+
+	// Count is how many snapshot events the resync sent, including the ones sent before it was resumed.
+	Count int64 `json:"count"`
+
+	// Actor is who started or resumed the resync.
+	Actor *Actor `json:"actor"`
+}
+
+// ResyncFilter is what the resources of a resync matched.  Empty values matched every resource.
+type ResyncFilter struct {
+	ResourceType string `json:"resource_type,omitempty"`
+	Workspace    string `json:"workspace,omitempty"`
+	ReporterType string `json:"reporter_type,omitempty"`
+	ReporterId   string `json:"reporter_id,omitempty"`
+}
+
+type Resource struct {
+	DisplayName  string         `json:"display_name"`
+	ResourceType string         `json:"resource_type"`
+	Workspace    string         `json:"workspace,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	ReporterData []ReporterData `json:"reporter_data"`
+}
+
+// ReporterData is a reporter's representation of a resource.
+type ReporterData struct {
+	ReporterId      string          `json:"reporter_id"`
+	ReporterType    string          `json:"reporter_type"`
+	ReporterVersion string          `json:"reporter_version,omitempty"`
+	LocalResourceId string          `json:"local_resource_id"`
+	ConsoleHref     string          `json:"console_href,omitempty"`
+	ApiHref         string          `json:"api_href,omitempty"`
+	Created         time.Time       `json:"created"`
+	Updated         time.Time       `json:"updated"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+type Reporter struct {
+	ReporterId      string `json:"reporter_id"`
+	ReporterType    string `json:"reporter_type"`
+	ReporterVersion string `json:"reporter_version,omitempty"`
+}
+
+type Actor struct {
+	Principal  string `json:"principal"`
+	Type       string `json:"type,omitempty"`
+	Tenant     string `json:"tenant,omitempty"`
+	IsReporter bool   `json:"is_reporter"`
+}
+
+// NewResource copies the parts of the model that are in the event contract.  It returns nil for nil.
+func NewResource(m *models.Resource) *Resource {
+	if m == nil {
+		return nil
+	}
+
+	r := &Resource{
+		DisplayName:  m.DisplayName,
+		ResourceType: m.ResourceType,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+		ReporterData: []ReporterData{},
+	}
+	if m.Workspace != nil {
+		r.Workspace = *m.Workspace
+	}
+
+	for _, d := range m.ReporterData {
+		r.ReporterData = append(r.ReporterData, ReporterData{
+			ReporterId:      d.ReporterID,
+			ReporterType:    d.ReporterType,
+			ReporterVersion: d.ReporterVersion,
+			LocalResourceId: d.LocalResourceId,
+			ConsoleHref:     d.ConsoleHref,
+			ApiHref:         d.ApiHref,
+			Created:         d.Created,
+			Updated:         d.Updated,
+			Data:            json.RawMessage(d.Data),
+		})
+	}
+
+	return r
+}
+
+func NewActor(identity *authnapi.Identity) *Actor {
+	return &Actor{
+		Principal:  identity.Principal,
+		Type:       identity.Type,
+		Tenant:     identity.Tenant,
+		IsReporter: identity.IsReporter,
+	}
+}
+
+// NewResourceEvent describes a change to the resource with the id.  before is nil for created resources and after
+// is nil for deleted ones.
+func NewResourceEvent(eventType string, href string, id models.IDType, resourceType string, before *models.Resource, after *models.Resource, reporter *Reporter, identity *authnapi.Identity) *Event {
+	resourceId := strconv.FormatInt(int64(id), 10)
+	return &Event{
+		ID:           uuid.NewString(),
+		Time:         time.Now().UTC(),
+		Type:         eventType,
+		Subject:      href,
+		ResourceType: resourceType,
+		Key:          resourceId,
+		DataSchema:   DataSchema,
+		Data: &ResourceData{
+			ResourceId:   resourceId,
+			ResourceType: resourceType,
+			Before:       NewResource(before),
+			After:        NewResource(after),
+			Reporter:     reporter,
+			Actor:        NewActor(identity),
+		},
+	}
+}
+
+// NewSnapshotEvent is the state of the resource, sent by the resync with the id.  The identity is who started the
+// resync.
+func NewSnapshotEvent(href string, resource *models.Resource, resyncId string, identity *authnapi.Identity) *Event {
+	e := NewResourceEvent(ResourceSnapshot, href, resource.ID, resource.ResourceType, nil, resource, nil, identity)
+	e.Data.(*ResourceData).ResyncId = resyncId
+	return e
+}
+
+// NewResyncCompletedEvent marks the end of the resync with the id, which sent count snapshots.  Its subject is the
+// resync's href.
+func NewResyncCompletedEvent(href string, resyncId string, filter ResyncFilter, count int64, identity *authnapi.Identity) *Event {
+	return &Event{
+		ID:         uuid.NewString(),
+		Time:       time.Now().UTC(),
+		Type:       ResyncCompleted,
+		Subject:    href,
+		Key:        resyncId,
+		Broadcast:  true,
+		DataSchema: ResyncDataSchema,
+		Data: &ResyncData{
+			ResyncId: resyncId,
+			Filter:   filter,
+			Count:    count,
+			Actor:    NewActor(identity),
+		},
+	}
+}
+
+// NewCloudEvent converts the event to a CloudEvent from the source.  Events spooled before they had an id get a new
+// one each time.
+func NewCloudEvent(source string, event *Event) (cloudevents.Event, error) {
+	e := cloudevents.NewEvent()
+	if event.ID != "" {
+		e.SetID(event.ID)
+	} else {
+		e.SetID(uuid.NewString())
+	}
+	if !event.Time.IsZero() {
+		e.SetTime(event.Time)
+	} else {
+		e.SetTime(time.Now().UTC())
+	}
+	e.SetType(event.Type)
+	e.SetSource(source)
+	e.SetSubject(event.Subject)
+	if event.DataSchema != "" {
+		e.SetDataSchema(event.DataSchema)
+	} else {
+		e.SetDataSchema(DataSchema)
+	}
+	if err := e.SetData(cloudevents.ApplicationJSON, event.Data); err != nil {
+		return e, err
+	}
+	return e, e.Validate()
 }
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+func TestCloudEventsOfAnEventShareItsId(t *testing.T) {
+	identity := &authnapi.Identity{Principal: "acm-hub-1", Type: "ACM", IsReporter: true}
+	resource := &models.Resource{ID: 1, DisplayName: "cluster 1", ResourceType: "cluster"}
+	event := NewResourceEvent(ResourceCreated, "/api/inventory/v1alpha1/resources/clusters/1", resource.ID, "cluster", nil, resource, nil, identity)
+
+	first, err := NewCloudEvent("urn:test", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewCloudEvent("urn:test", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID() == "" || first.ID() != second.ID() || !first.Time().Equal(second.Time()) {
+		t.Errorf("expected every send to have the same id and time, got %s at %s and %s at %s", first.ID(), first.Time(), second.ID(), second.Time())
+	}
+
+	// like a replay from the spool
+	b, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded Event
+	if err := json.Unmarshal(b, &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := NewCloudEvent("urn:test", &reloaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.ID() != first.ID() || !replayed.Time().Equal(first.Time()) {
+		t.Errorf("expected the spooled event to keep its id and time, got %s at %s", replayed.ID(), replayed.Time())
+	}
+
+	other := NewResyncCompletedEvent("/api/inventory/v1alpha1/admin/resyncs/1", "1", ResyncFilter{}, 0, identity)
+	if other.ID == "" || other.ID == event.ID {
+		t.Errorf("expected every event to get its own id, got %q and %q", event.ID, other.ID)
+	}
+}
@@ -9,6 +9,16 @@ import (
 
 type Manager interface {
 	Lookup(identity *authnapi.Identity, resource *models.Resource) (Producer, error)
+
+	// LookupAll returns a producer that sends to every destination, for events that aren't about one resource,
+	// like the end of a resync.
+	LookupAll(identity *authnapi.Identity) (Producer, error)
+
+	// Errs reports errors that happen outside of Produce, like failed deliveries.  See Error.
 	Errs() <-chan error
+
+	// Ready returns an error while the manager can't send events, like when the brokers are unreachable.
+	Ready() error
+
 	Shutdown(ctx context.Context) error
 }
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// ErrorsBuffer is how many errors an ErrorReporter holds before new transient ones are dropped.
+const ErrorsBuffer = 64
+
+// ErrorReporter is the channel behind Errs.  Transient errors are dropped while nobody reads them, so a burst of
+// them doesn't hold up the manager, but fatal ones wait until they're read or the reporter is stopped.
+type ErrorReporter struct {
+	Log *slog.Logger
+
+	errs     chan error
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewErrorReporter(log *slog.Logger) *ErrorReporter {
+	return &ErrorReporter{
+		Log:  log,
+		errs: make(chan error, ErrorsBuffer),
+		stop: make(chan struct{}),
+	}
+}
+
+func (r *ErrorReporter) Errs() <-chan error {
+	return r.errs
+}
+
+// Report sends the error on Errs.  See IsFatal for which errors wait.
+func (r *ErrorReporter) Report(err error) {
+	if !IsFatal(err) {
+		select {
+		case r.errs <- err:
+		default:
+			r.Log.Warn(fmt.Sprintf("Dropped an error because nobody is reading them: %v", err))
+		}
+		return
+	}
+
+	select {
+	case r.errs <- err:
+	case <-r.stop:
+		r.Log.Warn(fmt.Sprintf("Dropped an error because the reporter is stopped: %v", err))
+	}
+}
+
+// Forward reports the errors from another channel, passed through wrap, until it's closed or the reporter is
+// stopped.
+func (r *ErrorReporter) Forward(errs <-chan error, wrap func(error) error) {
+	for {
+		select {
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			r.Report(wrap(err))
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Stop stops fatal errors from waiting and stops Forward.  Call it before waiting for the goroutines that report
+// errors, since nobody may read them during a shutdown.
+func (r *ErrorReporter) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newReporter() *ErrorReporter {
+	return NewErrorReporter(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestReportDropsTransientErrors(t *testing.T) {
+	r := newReporter()
+	for i := 0; i < ErrorsBuffer+10; i++ {
+		r.Report(Transient(fmt.Errorf("error %d", i)))
+	}
+	if n := len(r.Errs()); n != ErrorsBuffer {
+		t.Fatalf("expected %d errors to be kept, got %d", ErrorsBuffer, n)
+	}
+	if err := <-r.Errs(); err.Error() != "error 0" {
+		t.Errorf("expected the oldest errors to be kept, got %v", err)
+	}
+}
+
+func TestReportWaitsForFatalErrors(t *testing.T) {
+	r := newReporter()
+	for i := 0; i < ErrorsBuffer; i++ {
+		r.Report(Transient(errors.New("transient")))
+	}
+
+	reported := make(chan struct{})
+	go func() {
+		r.Report(Fatal(errors.New("fatal")))
+		close(reported)
+	}()
+
+	select {
+	case <-reported:
+		t.Fatal("the fatal error was dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-r.Errs()
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fatal error wasn't sent once there was room")
+	}
+}
+
+func TestStopReleasesFatalErrors(t *testing.T) {
+	r := newReporter()
+	for i := 0; i < ErrorsBuffer; i++ {
+		r.Report(Transient(errors.New("transient")))
+	}
+
+	reported := make(chan struct{})
+	go func() {
+		r.Report(Fatal(errors.New("fatal")))
+		close(reported)
+	}()
+	r.Stop()
+	r.Stop()
+
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop didn't release the fatal error")
+	}
+}
+
+func TestForward(t *testing.T) {
+	r := newReporter()
+	src := make(chan error)
+	done := make(chan struct{})
+	go func() {
+		r.Forward(src, func(err error) error { return fmt.Errorf("backend: %w", err) })
+		close(done)
+	}()
+
+	src <- Transient(errors.New("failed"))
+	err := <-r.Errs()
+	if err.Error() != "backend: failed" || IsFatal(err) {
+		t.Errorf("expected the wrapped transient error, got %v", err)
+	}
+
+	r.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Forward didn't return after Stop")
+	}
+}
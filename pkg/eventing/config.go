@@ -1,17 +1,37 @@
 package eventing
 
 import (
+	"slices"
+
+	"github.com/csams/common-inventory/pkg/eventing/delivery"
+	"github.com/csams/common-inventory/pkg/eventing/jetstream"
 	"github.com/csams/common-inventory/pkg/eventing/kafka"
+	"github.com/csams/common-inventory/pkg/eventing/subscriptions"
+	"github.com/csams/common-inventory/pkg/eventing/webhook"
 )
 
 type Config struct {
-	Eventer string
-	Kafka   *kafka.Config
+	Eventers      []string
+	Source        string
+	Kafka         *kafka.Config
+	JetStream     *jetstream.Config
+	Webhook       *webhook.Config
+	Subscriptions *subscriptions.Config
+	FilePath      string
+
+	// Delivery is the failure policy of each eventer.
+	Delivery map[string]*delivery.Config
 }
 
 type completedConfig struct {
-	Eventer string
-	Kafka   kafka.CompletedConfig
+	Eventers      []string
+	Source        string
+	Kafka         kafka.CompletedConfig
+	JetStream     jetstream.CompletedConfig
+	Webhook       webhook.CompletedConfig
+	Subscriptions subscriptions.CompletedConfig
+	FilePath      string
+	Delivery      map[string]delivery.CompletedConfig
 }
 
 type CompletedConfig struct {
@@ -20,28 +40,81 @@ type CompletedConfig struct {
 
 func NewConfig(o *Options) *Config {
 	cfg := &Config{
-		Eventer: o.Eventer,
+		Eventers: o.Eventers,
+		Source:   o.Source,
+		FilePath: o.File.Path,
+		Delivery: map[string]*delivery.Config{},
+	}
+
+	for _, e := range o.Eventers {
+		cfg.Delivery[e] = delivery.NewConfig(o.Policy(e))
 	}
 
-	if o.Eventer == "kafka" {
+	if slices.Contains(o.Eventers, "kafka") {
 		cfg.Kafka = kafka.NewConfig(o.Kafka)
 	}
 
+	if slices.Contains(o.Eventers, "jetstream") {
+		cfg.JetStream = jetstream.NewConfig(o.JetStream)
+	}
+
+	if slices.Contains(o.Eventers, "webhook") {
+		cfg.Webhook = webhook.NewConfig(o.Webhook)
+	}
+
+	// the subscriptions API checks callbacks against the allowed networks even when the eventer is off
+	cfg.Subscriptions = subscriptions.NewConfig(o.Subscriptions)
+
 	return cfg
 }
 
 func (c *Config) Complete() (CompletedConfig, []error) {
 	cfg := &completedConfig{
-		Eventer: c.Eventer,
+		Eventers: c.Eventers,
+		Source:   c.Source,
+		FilePath: c.FilePath,
+		Delivery: map[string]delivery.CompletedConfig{},
 	}
 
-	if c.Eventer == "kafka" {
+	if c.Kafka != nil {
 		if k, err := c.Kafka.Complete(); err != nil {
-			return CompletedConfig{}, nil
+			return CompletedConfig{}, []error{err}
 		} else {
 			cfg.Kafka = k
 		}
 	}
 
+	if c.JetStream != nil {
+		if j, err := c.JetStream.Complete(); err != nil {
+			return CompletedConfig{}, []error{err}
+		} else {
+			cfg.JetStream = j
+		}
+	}
+
+	if c.Webhook != nil {
+		if w, err := c.Webhook.Complete(); err != nil {
+			return CompletedConfig{}, []error{err}
+		} else {
+			cfg.Webhook = w
+		}
+	}
+
+	if c.Subscriptions != nil {
+		if s, err := c.Subscriptions.Complete(); err != nil {
+			return CompletedConfig{}, []error{err}
+		} else {
+			cfg.Subscriptions = s
+		}
+	}
+
+	for e, d := range c.Delivery {
+		if dc, err := d.Complete(); err != nil {
+			return CompletedConfig{}, []error{err}
+		} else {
+			cfg.Delivery[e] = dc
+		}
+	}
+
 	return CompletedConfig{cfg}, nil
 }
@@ -0,0 +1,39 @@
+package delivery
+
+import (
+	"time"
+)
+
+type Config struct {
+	*Options
+}
+
+type completedConfig struct {
+	FailurePolicy string
+	RetryAttempts int
+	RetryBackoff  time.Duration
+	SpoolFile     string
+	SpoolInterval time.Duration
+	SpoolMaxAge   time.Duration
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	return CompletedConfig{&completedConfig{
+		FailurePolicy: c.FailurePolicy,
+		RetryAttempts: c.RetryAttempts,
+		RetryBackoff:  c.RetryBackoff,
+		SpoolFile:     c.SpoolFile,
+		SpoolInterval: c.SpoolInterval,
+		SpoolMaxAge:   c.SpoolMaxAge,
+	}}, nil
+}
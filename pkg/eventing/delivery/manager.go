@@ -0,0 +1,351 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// Manager applies the failure policy to the producers of another manager and counts what happens to the events.
+// Delivery failures the other manager reports asynchronously are counted and passed on through Errs.
+type Manager struct {
+	Config  CompletedConfig
+	Manager api.Manager
+	Log     *slog.Logger
+
+	spool *spool
+	errs  *api.ErrorReporter
+
+	// lastErr is why the last spooled event couldn't be sent
+	mu      sync.Mutex
+	lastErr error
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	metrics metrics
+}
+
+type metrics struct {
+	produced         atomic.Int64
+	failed           atomic.Int64
+	retried          atomic.Int64
+	spooled          atomic.Int64
+	replayed         atomic.Int64
+	dropped          atomic.Int64
+	deliveryFailures atomic.Int64
+}
+
+// Status is what the Manager reports in /status.
+type Status struct {
+	FailurePolicy string `json:"failure_policy"`
+	Produced      int64  `json:"produced"`
+	Failed        int64  `json:"failed"`
+	Retried       int64  `json:"retried"`
+	Spooled       int64  `json:"spooled"`
+	Replayed      int64  `json:"replayed"`
+	Dropped       int64  `json:"dropped"`
+	SpoolDepth    int    `json:"spool_depth"`
+
+	// SpoolOldest is when the oldest spooled event was spooled.
+	SpoolOldest      *time.Time `json:"spool_oldest,omitempty"`
+	DeliveryFailures int64      `json:"delivery_failures"`
+}
+
+func New(config CompletedConfig, manager api.Manager, log *slog.Logger) (*Manager, error) {
+	m := &Manager{
+		Config:  config,
+		Manager: manager,
+		Log:     log,
+		errs:    api.NewErrorReporter(log),
+		stop:    make(chan struct{}),
+	}
+
+	if config.FailurePolicy == Spool {
+		s, err := openSpool(config.SpoolFile)
+		if err != nil {
+			return nil, err
+		}
+		m.spool = s
+
+		m.stopped.Add(1)
+		go m.replayLoop()
+	}
+
+	m.stopped.Add(1)
+	go func() {
+		defer m.stopped.Done()
+		m.errs.Forward(manager.Errs(), m.countDeliveryFailures)
+	}()
+
+	return m, nil
+}
+
+// Lookup returns a producer that applies the failure policy to the other manager's producer.
+func (m *Manager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	if m.spool != nil {
+		// the event is spooled if the lookup fails, so the other manager is only consulted when it's sent
+		return &producer{Manager: m, Identity: identity, Resource: resource}, nil
+	}
+
+	p, err := m.Manager.Lookup(identity, resource)
+	if err != nil {
+		m.metrics.failed.Add(1)
+		return nil, err
+	}
+	return &producer{Manager: m, Identity: identity, Resource: resource, Producer: p}, nil
+}
+
+// LookupAll is Lookup for the other manager's producer for every destination.
+func (m *Manager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	if m.spool != nil {
+		return &producer{Manager: m, Identity: identity, All: true}, nil
+	}
+
+	p, err := m.Manager.LookupAll(identity)
+	if err != nil {
+		m.metrics.failed.Add(1)
+		return nil, err
+	}
+	return &producer{Manager: m, Identity: identity, All: true, Producer: p}, nil
+}
+
+// lookup looks up the other manager's producer for the event.
+func (m *Manager) lookup(identity *authnapi.Identity, resource *models.Resource, all bool) (api.Producer, error) {
+	if all {
+		return m.Manager.LookupAll(identity)
+	}
+	return m.Manager.Lookup(identity, resource)
+}
+
+func (m *Manager) Errs() <-chan error {
+	return m.errs.Errs()
+}
+
+// Ready is the other manager's readiness, except that the spool policy is ready until the oldest spooled event has
+// waited longer than spool-max-age, since it keeps the events until they can be sent.
+func (m *Manager) Ready() error {
+	if m.spool == nil {
+		return m.Manager.Ready()
+	}
+
+	oldest, found := m.spool.Oldest()
+	if !found {
+		return nil
+	}
+	if age := time.Since(oldest); age > m.Config.SpoolMaxAge {
+		m.mu.Lock()
+		lastErr := m.lastErr
+		m.mu.Unlock()
+		return fmt.Errorf("%d events are spooled and the oldest has waited %s: %v", m.spool.Len(), age.Round(time.Second), lastErr)
+	}
+	return nil
+}
+
+// Shutdown makes a last attempt to send the spooled events and shuts down the other manager.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.errs.Stop()
+	close(m.stop)
+	m.stopped.Wait()
+	if m.spool != nil {
+		m.replay(ctx)
+	}
+
+	err := m.Manager.Shutdown(ctx)
+	if m.spool != nil {
+		if cerr := m.spool.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (m *Manager) Status() any {
+	s := &Status{
+		FailurePolicy:    m.Config.FailurePolicy,
+		Produced:         m.metrics.produced.Load(),
+		Failed:           m.metrics.failed.Load(),
+		Retried:          m.metrics.retried.Load(),
+		Spooled:          m.metrics.spooled.Load(),
+		Replayed:         m.metrics.replayed.Load(),
+		Dropped:          m.metrics.dropped.Load(),
+		DeliveryFailures: m.metrics.deliveryFailures.Load(),
+	}
+	if m.spool != nil {
+		s.SpoolDepth = m.spool.Len()
+		if oldest, found := m.spool.Oldest(); found {
+			s.SpoolOldest = &oldest
+		}
+	}
+	return s
+}
+
+// countDeliveryFailures counts the delivery failures among the other manager's errors.
+func (m *Manager) countDeliveryFailures(err error) error {
+	var delivery *api.DeliveryError
+	if errors.As(err, &delivery) {
+		m.metrics.deliveryFailures.Add(1)
+	}
+	return err
+}
+
+func (m *Manager) replayLoop() {
+	defer m.stopped.Done()
+	ticker := time.NewTicker(m.Config.SpoolInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), m.Config.SpoolInterval)
+			m.replay(ctx)
+			cancel()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// replay sends the spooled events in order until one fails.  The rest stay in the spool for the next attempt.  An
+// event that can't be sent however often it's tried is dropped and reported, so it doesn't hold up the rest.
+func (m *Manager) replay(ctx context.Context) {
+	dropped := 0
+	sent, err := m.spool.Drain(func(e *spooledEvent) error {
+		err := func() error {
+			p, err := m.lookup(e.Identity, e.Resource, e.All)
+			if err != nil {
+				return err
+			}
+			return p.Produce(ctx, e.Event)
+		}()
+		if err != nil && !spoolable(err) {
+			m.Log.Error(fmt.Sprintf("Dropping spooled event %s about %s, which can't be sent: %v", e.Event.ID, e.Event.Subject, err))
+			m.errs.Report(api.Transient(fmt.Errorf("dropped spooled event %s: %w", e.Event.ID, err)))
+			dropped++
+			return nil
+		}
+		return err
+	})
+	m.metrics.replayed.Add(int64(sent - dropped))
+	m.metrics.produced.Add(int64(sent - dropped))
+	m.metrics.dropped.Add(int64(dropped))
+	m.metrics.failed.Add(int64(dropped))
+
+	m.mu.Lock()
+	m.lastErr = err
+	m.mu.Unlock()
+	if err != nil {
+		m.Log.Warn(fmt.Sprintf("Failed to send spooled events, %d remain: %v", m.spool.Len(), err))
+	}
+}
+
+// spoolable reports whether sending the event again may succeed: it wasn't delivered, or the error is transient.
+// Other errors, like data that doesn't match the schema or a failed lookup, would fail every time.
+func spoolable(err error) bool {
+	var delivery *api.DeliveryError
+	return errors.As(err, &delivery) || !api.IsFatal(err)
+}
+
+type producer struct {
+	Manager  *Manager
+	Identity *authnapi.Identity
+	Resource *models.Resource
+	All      bool
+	Producer api.Producer
+}
+
+func (p *producer) Produce(ctx context.Context, event *api.Event) error {
+	m := p.Manager
+
+	var err error
+	switch m.Config.FailurePolicy {
+	case Retry:
+		err = p.retry(ctx, event)
+	case Spool:
+		err = p.sendOrSpool(ctx, event)
+	default:
+		if err = p.Producer.Produce(ctx, event); err == nil {
+			m.metrics.produced.Add(1)
+		}
+	}
+
+	if err != nil {
+		m.metrics.failed.Add(1)
+		return err
+	}
+	return nil
+}
+
+// retry tries up to the configured number of times, doubling the wait after each failure.
+func (p *producer) retry(ctx context.Context, event *api.Event) error {
+	m := p.Manager
+	backoff := m.Config.RetryBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = p.Producer.Produce(ctx, event); err == nil {
+			m.metrics.produced.Add(1)
+			return nil
+		}
+		if attempt >= m.Config.RetryAttempts {
+			return fmt.Errorf("failed to send the event after %d attempts: %w", attempt, err)
+		}
+
+		m.Log.Warn(fmt.Sprintf("Failed to send the event, retrying in %s: %v", backoff, err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("gave up sending the event: %w", err)
+		}
+		m.metrics.retried.Add(1)
+		backoff *= 2
+	}
+}
+
+// sendOrSpool sends the event unless older events are still spooled, so the events about a resource stay in
+// order.  The event is spooled if it wasn't delivered, and other errors are returned.
+func (p *producer) sendOrSpool(ctx context.Context, event *api.Event) error {
+	m := p.Manager
+	e := &spooledEvent{Identity: p.Identity, Resource: p.Resource, All: p.All, Event: event}
+
+	appended, err := m.spool.AppendIfPending(e)
+	if err != nil {
+		return err
+	}
+	if appended {
+		m.metrics.spooled.Add(1)
+		return nil
+	}
+
+	sendErr := func() error {
+		producer, err := m.lookup(p.Identity, p.Resource, p.All)
+		if err != nil {
+			return err
+		}
+		return producer.Produce(ctx, event)
+	}()
+	if sendErr == nil {
+		m.metrics.produced.Add(1)
+		return nil
+	}
+	if !spoolable(sendErr) {
+		return sendErr
+	}
+
+	m.mu.Lock()
+	m.lastErr = sendErr
+	m.mu.Unlock()
+	m.Log.Warn(fmt.Sprintf("Failed to send the event, spooling it: %v", sendErr))
+	if err := m.spool.Append(e); err != nil {
+		return fmt.Errorf("failed to spool the event after failing to send it (%v): %w", sendErr, err)
+	}
+	m.metrics.spooled.Add(1)
+	return nil
+}
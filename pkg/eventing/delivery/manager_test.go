@@ -0,0 +1,154 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// backend fails the events whose subject is in fail with the error, and keeps the subjects of the others.
+type backend struct {
+	fail map[string]error
+	sent []string
+	errs chan error
+}
+
+func (b *backend) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return b, nil
+}
+
+func (b *backend) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return b, nil
+}
+
+func (b *backend) Produce(ctx context.Context, event *api.Event) error {
+	if err := b.fail[event.Subject]; err != nil {
+		return err
+	}
+	b.sent = append(b.sent, event.Subject)
+	return nil
+}
+
+func (b *backend) Errs() <-chan error                 { return b.errs }
+func (b *backend) Ready() error                       { return nil }
+func (b *backend) Shutdown(ctx context.Context) error { return nil }
+
+func newSpoolManager(t *testing.T, b *backend, maxAge time.Duration) *Manager {
+	o := NewOptions()
+	o.FailurePolicy = Spool
+	o.SpoolFile = filepath.Join(t.TempDir(), "spool.jsonl")
+	o.SpoolInterval = time.Hour
+	o.SpoolMaxAge = maxAge
+	if errs := o.Validate(); errs != nil {
+		t.Fatal(errs)
+	}
+	config, err := NewConfig(o).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.errs = make(chan error)
+	m, err := New(config, b, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	return m
+}
+
+func produce(t *testing.T, m *Manager, subject string) error {
+	p, err := m.Lookup(nil, &models.Resource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Produce(context.Background(), &api.Event{ID: subject, Type: api.ResourceCreated, Subject: subject})
+}
+
+func TestSpoolOnlySpoolsDeliveryFailures(t *testing.T) {
+	b := &backend{fail: map[string]error{
+		"undelivered": &api.DeliveryError{Destination: "events", Err: errors.New("timed out")},
+		"invalid":     errors.New("the data doesn't match the schema"),
+	}}
+	m := newSpoolManager(t, b, time.Minute)
+
+	if err := produce(t, m, "invalid"); err == nil {
+		t.Error("expected the error of an event that can't be sent")
+	}
+	if m.spool.Len() != 0 {
+		t.Fatalf("expected the invalid event not to be spooled, got %d events", m.spool.Len())
+	}
+
+	if err := produce(t, m, "undelivered"); err != nil {
+		t.Errorf("expected the undelivered event to be spooled, got %v", err)
+	}
+	if m.spool.Len() != 1 {
+		t.Fatalf("expected the undelivered event to be spooled, got %d events", m.spool.Len())
+	}
+}
+
+func TestReplayDropsEventsThatCantBeSent(t *testing.T) {
+	b := &backend{fail: map[string]error{"a": &api.DeliveryError{Destination: "events", Err: errors.New("timed out")}}}
+	m := newSpoolManager(t, b, time.Minute)
+
+	// b is queued behind a without being tried, and turns out to be invalid when it's replayed
+	for _, subject := range []string{"a", "b", "c"} {
+		if err := produce(t, m, subject); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.fail = map[string]error{"b": errors.New("the data doesn't match the schema")}
+
+	m.replay(context.Background())
+	if strings.Join(b.sent, ",") != "a,c" {
+		t.Errorf("expected a and c to be sent, got %v", b.sent)
+	}
+	if m.spool.Len() != 0 {
+		t.Errorf("expected the spool to be empty, got %d events", m.spool.Len())
+	}
+	status := m.Status().(*Status)
+	if status.Dropped != 1 || status.Replayed != 2 {
+		t.Errorf("expected 1 dropped and 2 replayed, got %+v", status)
+	}
+
+	select {
+	case err := <-m.Errs():
+		if api.IsFatal(err) || !strings.Contains(err.Error(), "dropped spooled event b") {
+			t.Errorf("expected a transient error about b, got %v", err)
+		}
+	default:
+		t.Error("the dropped event wasn't reported")
+	}
+}
+
+func TestNotReadyWhileTheSpoolIsStalled(t *testing.T) {
+	b := &backend{fail: map[string]error{"a": &api.DeliveryError{Destination: "events", Err: errors.New("timed out")}}}
+	m := newSpoolManager(t, b, 50*time.Millisecond)
+
+	if err := produce(t, m, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Ready(); err != nil {
+		t.Fatalf("expected a fresh spool to be ready, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	err := m.Ready()
+	if err == nil || !strings.Contains(err.Error(), "1 events are spooled") || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected the stalled spool to be reported, got %v", err)
+	}
+
+	b.fail = nil
+	m.replay(context.Background())
+	if err := m.Ready(); err != nil {
+		t.Errorf("expected the drained spool to be ready, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// The failure policies.
+const (
+	// Fail returns the error to the caller.
+	Fail = "fail"
+
+	// Retry retries with exponential backoff before returning the error.
+	Retry = "retry"
+
+	// Spool appends the event to a local file and sends it later.
+	Spool = "spool"
+)
+
+type Options struct {
+	FailurePolicy string        `mapstructure:"failure-policy"`
+	RetryAttempts int           `mapstructure:"retry-attempts"`
+	RetryBackoff  time.Duration `mapstructure:"retry-backoff"`
+	SpoolFile     string        `mapstructure:"spool-file"`
+	SpoolInterval time.Duration `mapstructure:"spool-interval"`
+	SpoolMaxAge   time.Duration `mapstructure:"spool-max-age"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		FailurePolicy: Fail,
+		RetryAttempts: 3,
+		RetryBackoff:  100 * time.Millisecond,
+		SpoolInterval: 10 * time.Second,
+		SpoolMaxAge:   5 * time.Minute,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.FailurePolicy, prefix+"failure-policy", o.FailurePolicy, "What to do when an event can't be sent.  Either fail, retry or spool.")
+	fs.IntVar(&o.RetryAttempts, prefix+"retry-attempts", o.RetryAttempts, "How many times the retry policy tries to send an event.")
+	fs.DurationVar(&o.RetryBackoff, prefix+"retry-backoff", o.RetryBackoff, "How long the retry policy waits before the first retry.  The wait doubles after each one.")
+	fs.StringVar(&o.SpoolFile, prefix+"spool-file", o.SpoolFile, "The file the spool policy keeps unsent events in.")
+	fs.DurationVar(&o.SpoolInterval, prefix+"spool-interval", o.SpoolInterval, "How often the spool policy tries to send the spooled events.")
+	fs.DurationVar(&o.SpoolMaxAge, prefix+"spool-max-age", o.SpoolMaxAge, "How long the oldest spooled event may wait before the server reports it isn't ready.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	switch o.FailurePolicy {
+	case Fail:
+	case Retry:
+		if o.RetryAttempts < 1 {
+			errs = append(errs, fmt.Errorf("retry-attempts must be at least 1: %d", o.RetryAttempts))
+		}
+		if o.RetryBackoff <= 0 {
+			errs = append(errs, fmt.Errorf("retry-backoff must be positive: %s", o.RetryBackoff))
+		}
+	case Spool:
+		if o.SpoolFile == "" {
+			errs = append(errs, fmt.Errorf("the spool failure-policy needs a spool-file"))
+		}
+		if o.SpoolInterval <= 0 {
+			errs = append(errs, fmt.Errorf("spool-interval must be positive: %s", o.SpoolInterval))
+		}
+		if o.SpoolMaxAge <= 0 {
+			errs = append(errs, fmt.Errorf("spool-max-age must be positive: %s", o.SpoolMaxAge))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid failure-policy %s.  Options are fail, retry and spool", o.FailurePolicy))
+	}
+
+	return errs
+}
+
+// Complete gives the unset fields their defaults, since the options of each eventer's policy in the config file
+// don't start from NewOptions.
+func (o *Options) Complete() []error {
+	d := NewOptions()
+	if o.FailurePolicy == "" {
+		o.FailurePolicy = d.FailurePolicy
+	}
+	if o.RetryAttempts == 0 {
+		o.RetryAttempts = d.RetryAttempts
+	}
+	if o.RetryBackoff == 0 {
+		o.RetryBackoff = d.RetryBackoff
+	}
+	if o.SpoolInterval == 0 {
+		o.SpoolInterval = d.SpoolInterval
+	}
+	if o.SpoolMaxAge == 0 {
+		o.SpoolMaxAge = d.SpoolMaxAge
+	}
+	return nil
+}
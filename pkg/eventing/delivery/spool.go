@@ -0,0 +1,206 @@
+package delivery
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// spooledEvent is an event that couldn't be sent.  The identity and resource are kept so it's routed like it
+// would have been.  All events go to every destination.
+type spooledEvent struct {
+	Identity *authnapi.Identity
+	Resource *models.Resource
+	All      bool `json:",omitempty"`
+	Event    *api.Event
+
+	// Spooled is when the event was spooled.  Events spooled before it was recorded get the time they're loaded.
+	Spooled time.Time
+}
+
+// spool is a durable queue of events in a file with one json document per line.  Appends are synced before they
+// return, and the file is replaced after events are removed, so a crash loses no spooled event.
+type spool struct {
+	// draining is held by Drain, so only one sends the events at a time
+	draining sync.Mutex
+
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	events []*spooledEvent
+}
+
+// openSpool opens the spool at the path and loads the events left by a previous run.
+func openSpool(path string) (*spool, error) {
+	s := &spool{path: path}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			var e spooledEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to read line %d of the spool %s: %w", line, path, err)
+			}
+			if e.Spooled.IsZero() {
+				e.Spooled = time.Now()
+			}
+			s.events = append(s.events, &e)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read the spool %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open the spool %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the spool %s: %w", path, err)
+	}
+	s.file = f
+
+	return s, nil
+}
+
+func (s *spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// Oldest returns when the oldest event was spooled, and false if the spool is empty.
+func (s *spool) Oldest() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return time.Time{}, false
+	}
+	return s.events[0].Spooled, true
+}
+
+func (s *spool) Append(e *spooledEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(e)
+}
+
+// AppendIfPending appends the event and returns true if the spool isn't empty.
+func (s *spool) AppendIfPending(e *spooledEvent) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return false, nil
+	}
+	return true, s.append(e)
+}
+
+func (s *spool) append(e *spooledEvent) error {
+	if e.Spooled.IsZero() {
+		e.Spooled = time.Now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+// Drain calls send with the events in order until it fails and removes the ones that were sent.  It returns how
+// many were sent and the error from send.  The events are sent without holding the lock, so appends don't wait
+// for the network.  They only add to the end, and the spool isn't empty until the sent events are removed, so new
+// events still queue up behind the ones being sent.
+func (s *spool) Drain(send func(*spooledEvent) error) (int, error) {
+	s.draining.Lock()
+	defer s.draining.Unlock()
+
+	s.mu.Lock()
+	events := slices.Clone(s.events)
+	s.mu.Unlock()
+
+	sent := 0
+	var sendErr error
+	for _, e := range events {
+		if sendErr = send(e); sendErr != nil {
+			break
+		}
+		sent++
+	}
+	if sent == 0 {
+		return 0, sendErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = s.events[sent:]
+	if err := s.rewrite(); err != nil {
+		// the sent events are still in the file and will be sent again after a restart
+		return sent, fmt.Errorf("failed to remove sent events from the spool %s: %w", s.path, err)
+	}
+	return sent, sendErr
+}
+
+// rewrite replaces the file with the remaining events.
+func (s *spool) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range s.events {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = f
+	return nil
+}
+
+func (s *spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,102 @@
+package delivery
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csams/common-inventory/pkg/eventing/api"
+)
+
+func spooled(subject string) *spooledEvent {
+	return &spooledEvent{Event: &api.Event{Type: api.ResourceCreated, Subject: subject}}
+}
+
+func TestDrainDoesNotBlockAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.jsonl")
+	s, err := openSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(spooled("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := s.Drain(func(e *spooledEvent) error {
+			close(sending)
+			<-release
+			return nil
+		})
+		done <- err
+	}()
+	<-sending
+
+	appended := make(chan bool)
+	go func() {
+		ok, err := s.AppendIfPending(spooled("b"))
+		if err != nil {
+			t.Error(err)
+		}
+		appended <- ok
+	}()
+	select {
+	case ok := <-appended:
+		if !ok {
+			t.Fatal("the event wasn't queued behind the one being sent")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("AppendIfPending waited for Drain to send")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the event appended while draining is left, in memory and in the file
+	if len(s.events) != 1 || s.events[0].Event.Subject != "b" {
+		t.Fatalf("expected only b to be left, got %d events", len(s.events))
+	}
+	reopened, err := openSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if len(reopened.events) != 1 || reopened.events[0].Event.Subject != "b" {
+		t.Fatalf("expected only b in the file, got %d events", len(reopened.events))
+	}
+}
+
+func TestDrainKeepsTheEventsAfterAFailure(t *testing.T) {
+	s, err := openSpool(filepath.Join(t.TempDir(), "spool.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, subject := range []string{"a", "b", "c"} {
+		if err := s.Append(spooled(subject)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent, err := s.Drain(func(e *spooledEvent) error {
+		if e.Event.Subject == "b" {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+	if sent != 1 || err == nil {
+		t.Fatalf("expected 1 sent and an error, got %d and %v", sent, err)
+	}
+	if s.Len() != 2 || s.events[0].Event.Subject != "b" {
+		t.Fatalf("expected b and c to be left, got %d events", s.Len())
+	}
+}
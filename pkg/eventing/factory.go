@@ -1,22 +1,73 @@
 package eventing
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 
+	"gorm.io/gorm"
+
 	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/delivery"
+	"github.com/csams/common-inventory/pkg/eventing/fanout"
+	"github.com/csams/common-inventory/pkg/eventing/file"
+	"github.com/csams/common-inventory/pkg/eventing/jetstream"
 	"github.com/csams/common-inventory/pkg/eventing/kafka"
 	"github.com/csams/common-inventory/pkg/eventing/stdout"
+	"github.com/csams/common-inventory/pkg/eventing/subscriptions"
+	"github.com/csams/common-inventory/pkg/eventing/webhook"
 )
 
-func New(c CompletedConfig, log *slog.Logger) (api.Manager, error) {
+// New creates the eventers, each wrapped with its failure policy.  More than one are fanned out to.  The db is for
+// the subscriptions eventer.
+func New(c CompletedConfig, db *gorm.DB, log *slog.Logger) (api.Manager, error) {
+	var backends []fanout.Backend
+	for _, e := range c.Eventers {
+		manager, err := newEventer(c, e, db, log)
+		if err != nil {
+			// the ones already created may have connections and goroutines
+			for _, b := range backends {
+				b.Manager.Shutdown(context.Background())
+			}
+			return nil, err
+		}
+		backends = append(backends, fanout.Backend{Name: e, Manager: manager})
+	}
+
+	if len(backends) == 1 {
+		return backends[0].Manager, nil
+	}
+	return fanout.New(backends, log), nil
+}
+
+func newEventer(c CompletedConfig, eventer string, db *gorm.DB, log *slog.Logger) (api.Manager, error) {
+	var manager api.Manager
+	var err error
 
-	switch c.Eventer {
+	switch eventer {
 	case "stdout":
-		return stdout.New()
+		manager, err = stdout.New(c.Source)
+	case "file":
+		manager, err = file.New(c.FilePath, c.Source)
 	case "kafka":
-		return kafka.New(c.Kafka, log)
+		manager, err = kafka.New(c.Kafka, c.Source, log)
+	case "jetstream":
+		manager, err = jetstream.New(c.JetStream, c.Source, log)
+	case "webhook":
+		manager, err = webhook.New(c.Webhook, c.Source, log)
+	case "subscriptions":
+		manager, err = subscriptions.New(c.Subscriptions, db, c.Source, log)
+	default:
+		return nil, fmt.Errorf("unrecognized eventer type: %s", eventer)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("unrecognized eventer type: %s", c.Eventer)
 
+	d, err := delivery.New(c.Delivery[eventer], manager, log)
+	if err != nil {
+		manager.Shutdown(context.Background())
+		return nil, err
+	}
+	return d, nil
 }
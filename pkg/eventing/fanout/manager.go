@@ -0,0 +1,157 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// Backend is a manager and the name of its eventer, which prefixes its errors.
+type Backend struct {
+	Name    string
+	Manager api.Manager
+}
+
+// FanoutManager sends every event to every backend.  Each backend applies its own failure policy, so Produce only
+// fails if one of them gave up, and the event may still have been sent by the others.  A backend that can't look
+// up a producer is skipped.
+type FanoutManager struct {
+	Backends []Backend
+
+	errs    *api.ErrorReporter
+	stopped sync.WaitGroup
+}
+
+func New(backends []Backend, log *slog.Logger) *FanoutManager {
+	m := &FanoutManager{
+		Backends: backends,
+		errs:     api.NewErrorReporter(log),
+	}
+
+	// the errors are passed on with the backend's name
+	for _, b := range backends {
+		m.stopped.Add(1)
+		go func(b Backend) {
+			defer m.stopped.Done()
+			m.errs.Forward(b.Manager.Errs(), func(err error) error {
+				return fmt.Errorf("%s: %w", b.Name, err)
+			})
+		}(b)
+	}
+
+	return m
+}
+
+func (m *FanoutManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return m.lookup(func(bm api.Manager) (api.Producer, error) {
+		return bm.Lookup(identity, resource)
+	})
+}
+
+func (m *FanoutManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return m.lookup(func(bm api.Manager) (api.Producer, error) {
+		return bm.LookupAll(identity)
+	})
+}
+
+// lookup combines the producers the function looks up from each backend.  A backend whose lookup fails is left
+// out and reported on Errs, so the others still get the event.  It only fails if every lookup did.
+func (m *FanoutManager) lookup(lookup func(api.Manager) (api.Producer, error)) (api.Producer, error) {
+	p := &producer{}
+	var errs []error
+	for _, b := range m.Backends {
+		bp, err := lookup(b.Manager)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", b.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.names = append(p.names, b.Name)
+		p.producers = append(p.producers, bp)
+	}
+	if p.producers == nil {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		m.errs.Log.Error(fmt.Sprintf("Failed to look up a producer, the event won't be sent with it: %v", err))
+		m.errs.Report(api.Transient(err))
+	}
+	return p, nil
+}
+
+func (m *FanoutManager) Errs() <-chan error {
+	return m.errs.Errs()
+}
+
+// Ready returns the errors of the backends that aren't ready.
+func (m *FanoutManager) Ready() error {
+	var errs []error
+	for _, b := range m.Backends {
+		if err := b.Manager.Ready(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown shuts the backends down at the same time and returns their errors.
+func (m *FanoutManager) Shutdown(ctx context.Context) error {
+	m.errs.Stop()
+	m.stopped.Wait()
+
+	errs := make([]error, len(m.Backends))
+	var wg sync.WaitGroup
+	for i, b := range m.Backends {
+		wg.Add(1)
+		go func(i int, b Backend) {
+			defer wg.Done()
+			if err := b.Manager.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", b.Name, err)
+			}
+		}(i, b)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Status reports the status of each backend that has one by name.
+func (m *FanoutManager) Status() any {
+	status := map[string]any{}
+	for _, b := range m.Backends {
+		if s, ok := b.Manager.(authnapi.StatusReporter); ok {
+			status[b.Name] = s.Status()
+		}
+	}
+	return status
+}
+
+type producer struct {
+	names     []string
+	producers []api.Producer
+}
+
+// Produce sends the event with every backend's producer at the same time, so one that's retrying doesn't hold up
+// the others.  It returns the errors of the ones that failed.
+func (p *producer) Produce(ctx context.Context, event *api.Event) error {
+	errs := make([]error, len(p.producers))
+	var wg sync.WaitGroup
+	for i, bp := range p.producers {
+		wg.Add(1)
+		go func(i int, bp api.Producer) {
+			defer wg.Done()
+			if err := bp.Produce(ctx, event); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", p.names[i], err)
+			}
+		}(i, bp)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
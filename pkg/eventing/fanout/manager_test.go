@@ -0,0 +1,106 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// backend counts the events it's sent.  Its lookups fail with lookupErr.
+type backend struct {
+	lookupErr error
+	produced  int
+	errs      chan error
+}
+
+func newBackend(lookupErr error) *backend {
+	return &backend{lookupErr: lookupErr, errs: make(chan error)}
+}
+
+func (b *backend) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return b.LookupAll(identity)
+}
+
+func (b *backend) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	if b.lookupErr != nil {
+		return nil, b.lookupErr
+	}
+	return b, nil
+}
+
+func (b *backend) Produce(ctx context.Context, event *api.Event) error {
+	b.produced++
+	return nil
+}
+
+func (b *backend) Errs() <-chan error                 { return b.errs }
+func (b *backend) Ready() error                       { return nil }
+func (b *backend) Shutdown(ctx context.Context) error { return nil }
+
+func newManager(backends map[string]*backend) *FanoutManager {
+	var bs []Backend
+	for _, name := range []string{"kafka", "webhook"} {
+		bs = append(bs, Backend{Name: name, Manager: backends[name]})
+	}
+	return New(bs, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestLookupSkipsFailingBackends(t *testing.T) {
+	kafka, webhook := newBackend(errors.New("no route")), newBackend(nil)
+	m := newManager(map[string]*backend{"kafka": kafka, "webhook": webhook})
+	defer m.Shutdown(context.Background())
+
+	p, err := m.Lookup(nil, &models.Resource{})
+	if err != nil {
+		t.Fatalf("expected the webhook producer, got %v", err)
+	}
+	if err := p.Produce(context.Background(), &api.Event{}); err != nil {
+		t.Fatal(err)
+	}
+	if webhook.produced != 1 || kafka.produced != 0 {
+		t.Errorf("expected only the webhook to get the event, got kafka %d and webhook %d", kafka.produced, webhook.produced)
+	}
+
+	select {
+	case err := <-m.Errs():
+		if !strings.HasPrefix(err.Error(), "kafka: ") || api.IsFatal(err) {
+			t.Errorf("expected a transient kafka error, got %v", err)
+		}
+	default:
+		t.Error("the failed lookup wasn't reported")
+	}
+}
+
+func TestLookupFailsIfEveryBackendFails(t *testing.T) {
+	m := newManager(map[string]*backend{
+		"kafka":   newBackend(errors.New("no route")),
+		"webhook": newBackend(errors.New("closed")),
+	})
+	defer m.Shutdown(context.Background())
+
+	_, err := m.LookupAll(nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "kafka: no route") || !strings.Contains(err.Error(), "webhook: closed") {
+		t.Errorf("expected both errors, got %v", err)
+	}
+}
+
+func TestErrsArePrefixedWithTheBackend(t *testing.T) {
+	kafka, webhook := newBackend(nil), newBackend(nil)
+	m := newManager(map[string]*backend{"kafka": kafka, "webhook": webhook})
+	defer m.Shutdown(context.Background())
+
+	webhook.errs <- api.Transient(errors.New("dead-lettered"))
+	if err := <-m.Errs(); err.Error() != "webhook: dead-lettered" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
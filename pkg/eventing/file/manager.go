@@ -0,0 +1,59 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/stdout"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// FileManager appends the events to a file like the stdout eventer writes them.  It's meant for development.
+type FileManager struct {
+	stdout.StdOutManager
+	File *os.File
+
+	mu sync.Mutex
+}
+
+func New(path string, source string) (*FileManager, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the events file %s: %w", path, err)
+	}
+
+	return &FileManager{
+		StdOutManager: stdout.StdOutManager{
+			Source:  source,
+			Encoder: json.NewEncoder(f),
+			Errors:  make(chan error),
+		},
+		File: f,
+	}, nil
+}
+
+// Lookup returns the manager, so Produce goes through its lock.
+func (m *FileManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return m, nil
+}
+
+func (m *FileManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return m, nil
+}
+
+func (m *FileManager) Produce(ctx context.Context, event *api.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.StdOutManager.Produce(ctx, event)
+}
+
+func (m *FileManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.File.Close()
+}
@@ -0,0 +1,34 @@
+package file
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Path string `mapstructure:"path"`
+}
+
+func NewOptions() *Options {
+	return &Options{}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.Path, prefix+"path", o.Path, "The file the events are appended to, one structured mode CloudEvent per line.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Path == "" {
+		errs = append(errs, fmt.Errorf("the file eventer needs a path"))
+	}
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
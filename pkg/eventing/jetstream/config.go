@@ -0,0 +1,46 @@
+package jetstream
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+type Config struct {
+	*Options
+}
+
+type completedConfig struct {
+	URL             string
+	Subject         string
+	CredentialsFile string
+	ConnectTimeout  time.Duration
+	PublishTimeout  time.Duration
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+// Complete checks the credentials file can be read, since the client only reads it when it connects.
+func (c *Config) Complete() (CompletedConfig, error) {
+	if c.CredentialsFile != "" {
+		if _, err := os.ReadFile(c.CredentialsFile); err != nil {
+			return CompletedConfig{}, fmt.Errorf("failed to read the jetstream credentials: %w", err)
+		}
+	}
+
+	return CompletedConfig{&completedConfig{
+		URL:             c.URL,
+		Subject:         c.Subject,
+		CredentialsFile: c.CredentialsFile,
+		ConnectTimeout:  c.ConnectTimeout,
+		PublishTimeout:  c.PublishTimeout,
+	}}, nil
+}
@@ -0,0 +1,142 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/nats-io/nats.go"
+	natsjs "github.com/nats-io/nats.go/jetstream"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// JetStreamManager publishes every event on one subject as a structured mode CloudEvent and waits for JetStream
+// to store it.  The stream keeps the order they were published in, so the events about a resource stay in order.
+type JetStreamManager struct {
+	Config    CompletedConfig
+	Source    string
+	Conn      *nats.Conn
+	JetStream natsjs.JetStream
+	Errors    *api.ErrorReporter
+	Log       *slog.Logger
+
+	// Stream is the name of the stream that captures the subject.
+	Stream string
+
+	shutdownOnce sync.Once
+}
+
+// New connects to NATS and checks a stream captures the subject, since events published on a subject no stream
+// captures are lost.
+func New(config CompletedConfig, source string, log *slog.Logger) (*JetStreamManager, error) {
+	m := &JetStreamManager{
+		Config: config,
+		Source: source,
+		Errors: api.NewErrorReporter(log),
+		Log:    log,
+	}
+
+	opts := []nats.Option{
+		nats.Name("common-inventory"),
+		nats.Timeout(config.ConnectTimeout),
+		// the client reconnects until it's closed, so Ready is the only sign NATS is down
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				m.Log.Warn(fmt.Sprintf("Disconnected from NATS: %v", err))
+				m.Errors.Report(api.Transient(fmt.Errorf("disconnected from nats: %w", err)))
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			m.Log.Info(fmt.Sprintf("Reconnected to NATS at %s", nc.ConnectedUrlRedacted()))
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			m.Log.Warn(fmt.Sprintf("NATS error: %v", err))
+			m.Errors.Report(api.Transient(err))
+		}),
+	}
+	if config.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(config.CredentialsFile))
+	}
+
+	nc, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := natsjs.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+	stream, err := js.StreamNameBySubject(ctx, config.Subject)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("no jetstream stream captures the subject %s: %w", config.Subject, err)
+	}
+
+	m.Conn = nc
+	m.JetStream = js
+	m.Stream = stream
+	return m, nil
+}
+
+// Lookup returns the manager, since every event is published on the subject.
+func (m *JetStreamManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return m, nil
+}
+
+func (m *JetStreamManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return m, nil
+}
+
+// Produce publishes the event and waits for the stream to acknowledge it.  The message id is the event id, so the
+// stream drops a duplicate published within its duplicate window.
+func (m *JetStreamManager) Produce(ctx context.Context, event *api.Event) error {
+	e, err := api.NewCloudEvent(m.Source, event)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(m.Config.Subject)
+	msg.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsJSON)
+	msg.Data = data
+
+	ctx, cancel := context.WithTimeout(ctx, m.Config.PublishTimeout)
+	defer cancel()
+	if _, err := m.JetStream.PublishMsg(ctx, msg, natsjs.WithMsgID(e.ID()), natsjs.WithExpectStream(m.Stream)); err != nil {
+		return &api.DeliveryError{Destination: m.Config.Subject, Err: err}
+	}
+	return nil
+}
+
+func (m *JetStreamManager) Errs() <-chan error {
+	return m.Errors.Errs()
+}
+
+func (m *JetStreamManager) Ready() error {
+	if status := m.Conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats is unavailable: %s", status)
+	}
+	return nil
+}
+
+// Shutdown closes the connection.  Produce waits for every event to be stored, so none are in flight.
+func (m *JetStreamManager) Shutdown(ctx context.Context) error {
+	m.Errors.Stop()
+	m.shutdownOnce.Do(m.Conn.Close)
+	return nil
+}
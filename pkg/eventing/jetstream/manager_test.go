@@ -0,0 +1,242 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	natsjs "github.com/nats-io/nats.go/jetstream"
+
+	"github.com/csams/common-inventory/pkg/eventing/api"
+)
+
+// startServer runs an embedded NATS server with JetStream and a stream that captures inventory.events.
+func startServer(t *testing.T) (*server.Server, natsjs.Stream) {
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		t.Fatal("the nats server didn't start")
+	}
+	t.Cleanup(s.Shutdown)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	js, err := natsjs.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.CreateStream(context.Background(), natsjs.StreamConfig{
+		Name:     "INVENTORY",
+		Subjects: []string{"inventory.events"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, stream
+}
+
+func newManager(t *testing.T, url string, subject string) (*JetStreamManager, error) {
+	o := NewOptions()
+	o.URL = url
+	o.Subject = subject
+	o.ConnectTimeout = 2 * time.Second
+	o.PublishTimeout = 2 * time.Second
+	if errs := o.Validate(); errs != nil {
+		t.Fatal(errs)
+	}
+	config, err := NewConfig(o).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := New(config, "urn:test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err == nil {
+		t.Cleanup(func() { m.Shutdown(context.Background()) })
+	}
+	return m, err
+}
+
+func testEvent(id string) *api.Event {
+	return &api.Event{
+		Type:    api.ResourceCreated,
+		Subject: "/api/inventory/v1alpha1/resources/hosts/" + id,
+		Key:     id,
+		Data:    map[string]any{"resource_id": id},
+	}
+}
+
+func TestProduce(t *testing.T) {
+	s, stream := startServer(t)
+	m, err := newManager(t, s.ClientURL(), "inventory.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Stream != "INVENTORY" {
+		t.Errorf("expected the stream INVENTORY, got %s", m.Stream)
+	}
+	if err := m.Ready(); err != nil {
+		t.Fatalf("expected the manager to be ready, got %v", err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := m.Produce(context.Background(), testEvent(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	consumer, err := stream.OrderedConsumer(context.Background(), natsjs.OrderedConsumerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := consumer.Fetch(3, natsjs.FetchMaxWait(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	for msg := range batch.Messages() {
+		if ct := msg.Headers().Get("Content-Type"); ct != "application/cloudevents+json" {
+			t.Errorf("expected a structured event, got the content type %s", ct)
+		}
+		var e struct {
+			ID      string         `json:"id"`
+			Type    string         `json:"type"`
+			Source  string         `json:"source"`
+			Subject string         `json:"subject"`
+			Data    map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(msg.Data(), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ID == "" || msg.Headers().Get(natsjs.MsgIDHeader) != e.ID {
+			t.Errorf("expected the message id to be the event id %q, got %q", e.ID, msg.Headers().Get(natsjs.MsgIDHeader))
+		}
+		if e.Type != api.ResourceCreated || e.Source != "urn:test" {
+			t.Errorf("unexpected event attributes: %+v", e)
+		}
+		subjects = append(subjects, e.Subject)
+	}
+	if err := batch.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "/api/inventory/v1alpha1/resources/hosts/1,/api/inventory/v1alpha1/resources/hosts/2,/api/inventory/v1alpha1/resources/hosts/3"
+	if got := strings.Join(subjects, ","); got != expected {
+		t.Errorf("expected the events in order %s, got %s", expected, got)
+	}
+}
+
+func TestNewNeedsAStream(t *testing.T) {
+	s, _ := startServer(t)
+	if _, err := newManager(t, s.ClientURL(), "inventory.other"); err == nil {
+		t.Fatal("expected an error for a subject no stream captures")
+	}
+}
+
+func TestNewNeedsAServer(t *testing.T) {
+	s, _ := startServer(t)
+	url := s.ClientURL()
+	s.Shutdown()
+
+	if _, err := newManager(t, url, "inventory.events"); err == nil {
+		t.Fatal("expected an error when nats is unreachable")
+	}
+}
+
+func TestNotReadyWhileDisconnected(t *testing.T) {
+	s, _ := startServer(t)
+	m, err := newManager(t, s.ClientURL(), "inventory.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Shutdown()
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Ready() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := m.Ready(); err == nil {
+		t.Fatal("expected the manager to be unready while nats is down")
+	}
+
+	select {
+	case err := <-m.Errs():
+		if api.IsFatal(err) {
+			t.Errorf("expected a transient error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the disconnect wasn't reported")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err = m.Produce(ctx, testEvent("1"))
+	var delivery *api.DeliveryError
+	if !errors.As(err, &delivery) || delivery.Destination != "inventory.events" {
+		t.Errorf("expected a delivery error, got %v", err)
+	}
+}
+
+func TestShutdownTwice(t *testing.T) {
+	s, _ := startServer(t)
+	m, err := newManager(t, s.ClientURL(), "inventory.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Produce(context.Background(), testEvent("1")); err == nil {
+		t.Error("expected Produce to fail after shutdown")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *Options)
+		invalid bool
+	}{
+		{"defaults", func(o *Options) {}, false},
+		{"cluster", func(o *Options) { o.URL = "nats://a:4222, nats://b:4222" }, false},
+		{"no url", func(o *Options) { o.URL = "" }, true},
+		{"no host", func(o *Options) { o.URL = "nats://a:4222,b" }, true},
+		{"no subject", func(o *Options) { o.Subject = "" }, true},
+		{"wildcard", func(o *Options) { o.Subject = "inventory.>" }, true},
+		{"empty token", func(o *Options) { o.Subject = "inventory..events" }, true},
+		{"no publish timeout", func(o *Options) { o.PublishTimeout = 0 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOptions()
+			tt.modify(o)
+			if errs := o.Validate(); (errs != nil) != tt.invalid {
+				t.Errorf("expected invalid %t, got %v", tt.invalid, errs)
+			}
+		})
+	}
+}
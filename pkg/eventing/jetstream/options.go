@@ -0,0 +1,74 @@
+package jetstream
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// URL is the NATS server, or a comma-separated list of servers in the same cluster.
+	URL string `mapstructure:"url"`
+
+	// Subject is the subject the events are published on.  A JetStream stream must capture it.
+	Subject string `mapstructure:"subject"`
+
+	CredentialsFile string        `mapstructure:"credentials-file"`
+	ConnectTimeout  time.Duration `mapstructure:"connect-timeout"`
+	PublishTimeout  time.Duration `mapstructure:"publish-timeout"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		URL:            "nats://127.0.0.1:4222",
+		Subject:        "inventory.events",
+		ConnectTimeout: 10 * time.Second,
+		PublishTimeout: 10 * time.Second,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.URL, prefix+"url", o.URL, "The NATS server, or a comma-separated list of the servers of a cluster.")
+	fs.StringVar(&o.Subject, prefix+"subject", o.Subject, "The subject the events are published on.  A JetStream stream must capture it.")
+	fs.StringVar(&o.CredentialsFile, prefix+"credentials-file", o.CredentialsFile, "A NATS credentials file with the user's JWT and NKey seed.")
+	fs.DurationVar(&o.ConnectTimeout, prefix+"connect-timeout", o.ConnectTimeout, "How long to wait for the NATS server at startup.")
+	fs.DurationVar(&o.PublishTimeout, prefix+"publish-timeout", o.PublishTimeout, "How long to wait for JetStream to acknowledge an event.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if o.URL == "" {
+		errs = append(errs, fmt.Errorf("the jetstream url must not be empty"))
+	}
+	for _, s := range strings.Split(o.URL, ",") {
+		if u, err := url.Parse(strings.TrimSpace(s)); o.URL != "" && (err != nil || u.Host == "") {
+			errs = append(errs, fmt.Errorf("the jetstream url must be a list of server URLs like nats://host:4222: %s", s))
+		}
+	}
+
+	if o.Subject == "" {
+		errs = append(errs, fmt.Errorf("the jetstream subject must not be empty"))
+	} else if strings.ContainsAny(o.Subject, "*> \t") || strings.HasPrefix(o.Subject, ".") || strings.HasSuffix(o.Subject, ".") || strings.Contains(o.Subject, "..") {
+		errs = append(errs, fmt.Errorf("the jetstream subject must be dot-separated tokens without wildcards: %s", o.Subject))
+	}
+
+	if o.ConnectTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("the jetstream connect-timeout must be positive: %s", o.ConnectTimeout))
+	}
+	if o.PublishTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("the jetstream publish-timeout must be positive: %s", o.PublishTimeout))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
@@ -1,7 +1,14 @@
 package kafka
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/csams/common-inventory/pkg/eventing/kafka/registry"
 )
 
 type Config struct {
@@ -12,8 +19,16 @@ type Config struct {
 }
 
 type completedConfig struct {
-	DefaultTopic string
-	KafkaConfig  *kafka.ConfigMap
+	DefaultTopic          string
+	Routes                []*Route
+	TopicCheckTimeoutMs   int
+	HealthCheckIntervalMs int
+	Serialization         string
+	KafkaConfig           *kafka.ConfigMap
+
+	// SchemaRegistry is nil for json.
+	SchemaRegistry      *registry.Client
+	AutoRegisterSchemas bool
 }
 
 type CompletedConfig struct {
@@ -119,10 +134,31 @@ func (c *Config) Complete() (CompletedConfig, error) {
 		config.SetKey("client.rack", c.ClientRack)
 		config.SetKey("retry.backoff.ms", c.RetryBackoffMs)
 		config.SetKey("retry.backoff.max.ms", c.RetryBackoffMaxMs)
+		config.SetKey("message.timeout.ms", c.DeliveryTimeoutMs)
+	}
+
+	var client *registry.Client
+	if c.Serialization != SerializationJSON {
+		r := c.SchemaRegistry
+		var password string
+		if r.PasswordFile != "" {
+			b, err := os.ReadFile(r.PasswordFile)
+			if err != nil {
+				return CompletedConfig{}, fmt.Errorf("failed to read the schema registry password: %w", err)
+			}
+			password = strings.TrimSpace(string(b))
+		}
+		client = registry.NewClient(r.URL, r.Username, password, time.Duration(r.TimeoutMs)*time.Millisecond)
 	}
 
 	return CompletedConfig{&completedConfig{
-		DefaultTopic: c.DefaultTopic,
-		KafkaConfig:  config,
+		DefaultTopic:          c.DefaultTopic,
+		Routes:                c.Routes,
+		TopicCheckTimeoutMs:   c.TopicCheckTimeoutMs,
+		HealthCheckIntervalMs: c.HealthCheckIntervalMs,
+		Serialization:         c.Serialization,
+		KafkaConfig:           config,
+		SchemaRegistry:        client,
+		AutoRegisterSchemas:   c.SchemaRegistry.AutoRegister,
 	}}, nil
 }
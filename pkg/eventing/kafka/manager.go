@@ -2,12 +2,16 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	confluent "github.com/cloudevents/sdk-go/protocol/kafka_confluent/v2"
-	cloudevents "github.com/cloudevents/sdk-go/v2"
-	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/cloudevents/sdk-go/v2/binding"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 
 	authnapi "github.com/csams/common-inventory/pkg/authn/api"
@@ -17,81 +21,178 @@ import (
 
 type KafkaManager struct {
 	Config   CompletedConfig
+	Source   string
+	Producer *kafka.Producer
 	Protocol *confluent.Protocol
-	Client   cloudevents.Client
-	Errors   <-chan error
+	Errors   *api.ErrorReporter
+	Log      *slog.Logger
+
+	// Serializer encodes the data with the registered schemas.  It's nil for json.
+	Serializer *serializer
+
+	health health
+	stop   chan struct{}
+}
+
+// health is whether the brokers can be reached.  err is nil while they can.
+type health struct {
+	mu    sync.Mutex
+	err   error
+	fatal bool
 }
 
-func New(config CompletedConfig, log *slog.Logger) (*KafkaManager, error) {
-	if sender, err := confluent.New(
+// New checks the topics exist and the schemas are registered.
+func New(config CompletedConfig, source string, log *slog.Logger) (*KafkaManager, error) {
+	serializer, err := newSerializer(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up %s serialization: %w", config.Serialization, err)
+	}
+
+	producer, err := kafka.NewProducer(config.KafkaConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTopics(producer, config); err != nil {
+		producer.Close()
+		return nil, err
+	}
+
+	sender, err := confluent.New(
 		confluent.WithSenderTopic(config.DefaultTopic),
-		confluent.WithConfigMap(config.KafkaConfig),
-	); err != nil {
+		confluent.WithSender(producer),
+	)
+	if err != nil {
+		producer.Close()
 		return nil, err
-	} else {
-		client, err := cloudevents.NewClient(sender, cloudevents.WithTimeNow(), cloudevents.WithUUIDs())
-		if err != nil {
-			return nil, err
+	}
+
+	eventChan, err := sender.Events()
+	if err != nil {
+		producer.Close()
+		return nil, fmt.Errorf("failed to get events channel for sender, %w", err)
+	}
+
+	m := &KafkaManager{
+		Config:     config,
+		Source:     source,
+		Producer:   producer,
+		Protocol:   sender,
+		Serializer: serializer,
+		Errors:     api.NewErrorReporter(log),
+		Log:        log,
+		stop:       make(chan struct{}),
+	}
+
+	go m.handleEvents(eventChan)
+	go m.checkHealth()
+
+	return m, nil
+}
+
+// handleEvents reads the producer's events until it's closed.  The delivery reports go to the producers that sent
+// the messages instead.  librdkafka reconnects to the brokers on its own, so only fatal errors are reported as
+// fatal.
+func (m *KafkaManager) handleEvents(eventChan chan kafka.Event) {
+	for e := range eventChan {
+		switch ev := e.(type) {
+		case kafka.Error:
+			switch {
+			case ev.IsFatal():
+				m.Log.Error(fmt.Sprintf("Fatal kafka error: %v", ev))
+				m.setHealth(ev, true)
+				m.Errors.Report(api.Fatal(ev))
+			case ev.Code() == kafka.ErrAllBrokersDown:
+				m.Log.Warn(fmt.Sprintf("Kafka brokers are unreachable: %v", ev))
+				m.setHealth(ev, false)
+				m.Errors.Report(api.Transient(ev))
+			default:
+				m.Log.Info(fmt.Sprintf("Error: %v", ev))
+			}
+		default:
+			m.Log.Info(fmt.Sprintf("Ignored event: %v", ev))
 		}
+	}
+}
+
+// checkHealth asks the brokers for metadata while they're unreachable, so the manager becomes ready again as soon
+// as librdkafka has reconnected.
+func (m *KafkaManager) checkHealth() {
+	ticker := time.NewTicker(time.Duration(m.Config.HealthCheckIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
 
-		errChan := make(chan error)
-
-		go func() {
-			eventChan, err := sender.Events()
-			if err != nil {
-				log.Error(fmt.Sprintf("failed to get events channel for sender, %v", err))
-				errChan <- err
-			} else {
-				for e := range eventChan {
-					switch ev := e.(type) {
-					case *kafka.Message:
-						// The message delivery report, indicating success or permanent failure after retries have
-						// been exhausted. Application level retries won't help since the client is already
-						// configured to do that.
-						m := ev
-						if m.TopicPartition.Error != nil {
-							log.Error(fmt.Sprintf("Delivery failed: %v\n", m.TopicPartition.Error))
-							errChan <- err
-						} else {
-							log.Info(fmt.Sprintf("Delivered message to topic %s [%d] at offset %v\n",
-								*m.TopicPartition.Topic, m.TopicPartition.Partition, m.TopicPartition.Offset))
-						}
-					case kafka.Error:
-						e := ev
-						if e.IsFatal() {
-							log.Error(fmt.Sprintf("Error: %v\n", ev))
-							errChan <- e
-						} else {
-							log.Info(fmt.Sprintf("Error: %v\n", ev))
-						}
-					default:
-						log.Info(fmt.Sprintf("Ignored event: %v\n", ev))
-					}
-				}
+	for {
+		select {
+		case <-ticker.C:
+			if fatal, err := m.getHealth(); err == nil || fatal {
+				continue
 			}
-		}()
+			if _, err := m.Producer.GetMetadata(nil, false, m.Config.TopicCheckTimeoutMs); err == nil {
+				m.Log.Info("Kafka brokers are reachable again")
+				m.setHealth(nil, false)
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
 
-		return &KafkaManager{
-			Config:   config,
-			Protocol: sender,
-			Client:   client,
-			Errors:   errChan,
-		}, nil
+// setHealth records the brokers' state.  A fatal error is never cleared.
+func (m *KafkaManager) setHealth(err error, fatal bool) {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+	if !m.health.fatal {
+		m.health.err, m.health.fatal = err, fatal
 	}
 }
 
+func (m *KafkaManager) getHealth() (bool, error) {
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+	return m.health.fatal, m.health.err
+}
+
 func (m *KafkaManager) Errs() <-chan error {
-	return m.Errors
+	return m.Errors.Errs()
 }
 
-// Lookup figures out which topic should be used for the given identity and resource.
-func (m *KafkaManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+func (m *KafkaManager) Ready() error {
+	if _, err := m.getHealth(); err != nil {
+		return fmt.Errorf("kafka is unavailable: %w", err)
+	}
+	return nil
+}
 
-	// there is no complicated topic dispatch logic... for now.
+// Lookup figures out which topic should be used for the given identity and resource.  It's the topic of the first
+// route that matches or the default topic.
+func (m *KafkaManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	for _, r := range m.Config.Routes {
+		if r.Matches(identity, resource) {
+			return NewProducer(m, r.Topic, identity), nil
+		}
+	}
 	return NewProducer(m, m.Config.DefaultTopic, identity), nil
 }
 
+// LookupAll returns a producer for the default topic and the topics of the routes.
+func (m *KafkaManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	topics := []string{m.Config.DefaultTopic}
+	for _, r := range m.Config.Routes {
+		if !slices.Contains(topics, r.Topic) {
+			topics = append(topics, r.Topic)
+		}
+	}
+
+	var p topicsProducer
+	for _, t := range topics {
+		p = append(p, NewProducer(m, t, identity))
+	}
+	return p, nil
+}
+
 func (m *KafkaManager) Shutdown(ctx context.Context) error {
+	m.Errors.Stop()
+	close(m.stop)
 	return m.Protocol.Close(ctx)
 }
 
@@ -110,9 +211,150 @@ func NewProducer(manager *KafkaManager, topic string, identity *authnapi.Identit
 	}
 }
 
-// Produce creates the cloud event and sends it on the Kafka Topic
+// Produce creates the cloud event, sends it on the Kafka Topic and waits for the brokers to acknowledge it.  A
+// message librdkafka couldn't deliver within delivery-timeout-ms is returned as a DeliveryError, so the failure
+// policy can retry or spool it.  The message key is the event's key, so the events about a resource land on one
+// partition in order.  Broadcast events are sent to every partition of the topic instead.  The data is JSON unless
+// the manager has a serializer, and then the dataschema is the URL of the registered schema.
 func (p *kafkaProducer) Produce(ctx context.Context, event *api.Event) error {
-	e := cloudevents.NewEvent()
-	e.SetData(cloudevents.ApplicationJSON, event)
-	return p.Manager.Client.Send(cecontext.WithTopic(ctx, p.Topic), e)
+	e, err := api.NewCloudEvent(p.Manager.Source, event)
+	if err != nil {
+		return err
+	}
+	if s := p.Manager.Serializer; s != nil {
+		data, err := s.Serialize(event)
+		if err != nil {
+			return err
+		}
+		if err := e.SetData(s.ContentType, data); err != nil {
+			return err
+		}
+		dataSchema, err := s.DataSchema(event)
+		if err != nil {
+			return err
+		}
+		e.SetDataSchema(dataSchema)
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.Topic, Partition: kafka.PartitionAny},
+	}
+	if event.Key != "" {
+		msg.Key = []byte(event.Key)
+	}
+	if err := confluent.WriteProducerMessage(ctx, binding.ToMessage(&e), msg); err != nil {
+		return fmt.Errorf("failed to create the kafka message: %w", err)
+	}
+
+	if !event.Broadcast {
+		return p.deliver(msg)
+	}
+
+	partitions, err := p.partitions()
+	if err != nil {
+		return &api.DeliveryError{Destination: p.Topic, Err: err}
+	}
+	for _, partition := range partitions {
+		m := *msg
+		m.TopicPartition.Partition = partition
+		if err := p.deliver(&m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver sends the message and waits for its delivery report.  It waits even if the context is done, since the
+// message is librdkafka's once it's produced and is usually still delivered.  Giving up would have it retried or
+// spooled and sent twice.  message.timeout.ms bounds the wait.
+func (p *kafkaProducer) deliver(msg *kafka.Message) error {
+	delivered := make(chan kafka.Event, 1)
+	if err := p.Manager.Producer.Produce(msg, delivered); err != nil {
+		return p.deliveryError(err)
+	}
+
+	ev := <-delivered
+	m, ok := ev.(*kafka.Message)
+	if !ok {
+		return &api.DeliveryError{Destination: p.Topic, Err: fmt.Errorf("unexpected delivery report: %v", ev)}
+	}
+	tp := m.TopicPartition
+	if tp.Error != nil {
+		return p.deliveryError(tp.Error)
+	}
+	p.Manager.setHealth(nil, false)
+	p.Manager.Log.Info(fmt.Sprintf("Delivered message to topic %s [%d] at offset %v", *tp.Topic, tp.Partition, tp.Offset))
+	return nil
+}
+
+// deliveryError is a DeliveryError for the error, unless sending the message again can't help, like when it's too
+// large.  Then it's a plain error, so the failure policy doesn't retry or spool it.
+func (p *kafkaProducer) deliveryError(err error) error {
+	var kerr kafka.Error
+	if errors.As(err, &kerr) {
+		switch kerr.Code() {
+		case kafka.ErrMsgSizeTooLarge, kafka.ErrRecordListTooLarge, kafka.ErrInvalidMsg, kafka.ErrInvalidMsgSize,
+			kafka.ErrInvalidRecord, kafka.ErrInvalidArg:
+			return fmt.Errorf("the message can't be sent on topic %s: %w", p.Topic, err)
+		}
+	}
+	return &api.DeliveryError{Destination: p.Topic, Err: err}
+}
+
+// partitions returns the ids of the topic's partitions from the brokers.
+func (p *kafkaProducer) partitions() ([]int32, error) {
+	metadata, err := p.Manager.Producer.GetMetadata(&p.Topic, false, p.Manager.Config.TopicCheckTimeoutMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the partitions: %w", err)
+	}
+	md, found := metadata.Topics[p.Topic]
+	if !found || md.Error.Code() != kafka.ErrNoError || len(md.Partitions) == 0 {
+		return nil, fmt.Errorf("failed to look up the partitions: the topic isn't known: %v", md.Error)
+	}
+
+	var partitions []int32
+	for _, pm := range md.Partitions {
+		partitions = append(partitions, pm.ID)
+	}
+	slices.Sort(partitions)
+	return partitions, nil
+}
+
+// topicsProducer sends the event on each of several topics.
+type topicsProducer []*kafkaProducer
+
+func (p topicsProducer) Produce(ctx context.Context, event *api.Event) error {
+	var errs []error
+	for _, tp := range p {
+		if err := tp.Produce(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", tp.Topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkTopics returns an error unless the brokers know the default topic and the topics of the routes.
+func checkTopics(producer *kafka.Producer, config CompletedConfig) error {
+	metadata, err := producer.GetMetadata(nil, true, config.TopicCheckTimeoutMs)
+	if err != nil {
+		return fmt.Errorf("failed to check the kafka topics exist: %w", err)
+	}
+
+	topics := []string{config.DefaultTopic}
+	for _, r := range config.Routes {
+		topics = append(topics, r.Topic)
+	}
+
+	var missing []string
+	for _, t := range topics {
+		md, found := metadata.Topics[t]
+		if (!found || md.Error.Code() != kafka.ErrNoError) && !slices.Contains(missing, t) {
+			missing = append(missing, t)
+		}
+	}
+	if missing != nil {
+		return fmt.Errorf("the kafka topics don't exist: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
@@ -0,0 +1,189 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/csams/common-inventory/pkg/eventing/api"
+)
+
+func TestProduceReturnsDeliveryFailures(t *testing.T) {
+	// nothing listens on the port, so librdkafka gives up on the message after message.timeout.ms
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers":  "127.0.0.1:1",
+		"message.timeout.ms": 500,
+		"log_level":          0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	m := &KafkaManager{
+		Source:   "urn:test",
+		Producer: producer,
+		Log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	event := &api.Event{
+		Type:    api.ResourceCreated,
+		Subject: "/api/inventory/v1alpha1/resources/hosts/1",
+		Key:     "1",
+		Data:    map[string]any{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	err = NewProducer(m, "events", nil).Produce(ctx, event)
+
+	var delivery *api.DeliveryError
+	if !errors.As(err, &delivery) {
+		t.Fatalf("expected a delivery error, got %v", err)
+	}
+	if delivery.Destination != "events" {
+		t.Errorf("expected the destination to be the topic, got %q", delivery.Destination)
+	}
+	var kerr kafka.Error
+	if !errors.As(err, &kerr) || kerr.Code() != kafka.ErrMsgTimedOut {
+		t.Errorf("expected the message to time out, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Produce waited for the context instead of the delivery report: %s", time.Since(start))
+	}
+}
+
+func TestProduceWaitsForTheDeliveryReportAfterTheContextIsDone(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("events", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": cluster.BootstrapServers(),
+		"log_level":         0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	m := &KafkaManager{
+		Source:   "urn:test",
+		Producer: producer,
+		Log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	event := &api.Event{
+		Type:    api.ResourceCreated,
+		Subject: "/api/inventory/v1alpha1/resources/hosts/1",
+		Data:    map[string]any{},
+	}
+
+	// like a request whose client went away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewProducer(m, "events", nil).Produce(ctx, event); err != nil {
+		t.Fatalf("expected the delivered message to succeed, got %v", err)
+	}
+
+	low, high, err := producer.QueryWatermarkOffsets("events", 0, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high-low != 1 {
+		t.Errorf("expected the message to be delivered once, got %d messages", high-low)
+	}
+}
+
+func TestProduceBroadcastsToEveryPartition(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("events", 3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": cluster.BootstrapServers(),
+		"log_level":         0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	m := &KafkaManager{
+		Config:   CompletedConfig{&completedConfig{TopicCheckTimeoutMs: 5000}},
+		Source:   "urn:test",
+		Producer: producer,
+		Log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	event := &api.Event{
+		Type:      api.ResyncCompleted,
+		Subject:   "/api/inventory/v1alpha1/admin/resyncs/1",
+		Key:       "1",
+		Broadcast: true,
+		Data:      map[string]any{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := NewProducer(m, "events", nil).Produce(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	for partition := int32(0); partition < 3; partition++ {
+		low, high, err := producer.QueryWatermarkOffsets("events", partition, 5000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if high-low != 1 {
+			t.Errorf("expected one message on partition %d, got %d", partition, high-low)
+		}
+	}
+}
+
+func TestProduceDoesNotRetryMessagesThatAreTooLarge(t *testing.T) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": "127.0.0.1:1",
+		"message.max.bytes": 1000,
+		"log_level":         0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	m := &KafkaManager{
+		Source:   "urn:test",
+		Producer: producer,
+		Log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	event := &api.Event{
+		Type:    api.ResourceCreated,
+		Subject: "/api/inventory/v1alpha1/resources/hosts/1",
+		Data:    map[string]any{"padding": strings.Repeat("x", 2000)},
+	}
+
+	err = NewProducer(m, "events", nil).Produce(context.Background(), event)
+	var delivery *api.DeliveryError
+	if err == nil || errors.As(err, &delivery) {
+		t.Fatalf("expected an error that isn't a delivery error, got %v", err)
+	}
+	var kerr kafka.Error
+	if !errors.As(err, &kerr) || kerr.Code() != kafka.ErrMsgSizeTooLarge {
+		t.Errorf("expected the message to be too large, got %v", err)
+	}
+}
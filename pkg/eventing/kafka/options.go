@@ -1,11 +1,33 @@
 package kafka
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/spf13/pflag"
 )
 
 type Options struct {
-	DefaultTopic                       string `mapstructure:"default-topic"`
+	DefaultTopic string `mapstructure:"default-topic"`
+
+	// Routes pick the topic of each event.  The first that matches wins, and DefaultTopic is used if none do.
+	Routes []*Route `mapstructure:"routes"`
+
+	// TopicCheckTimeoutMs is how long to wait at startup for the brokers to confirm the topics exist.
+	TopicCheckTimeoutMs int `mapstructure:"topic-check-timeout-ms"`
+
+	// HealthCheckIntervalMs is how often to ask the brokers for metadata while they're unreachable.
+	HealthCheckIntervalMs int `mapstructure:"health-check-interval-ms"`
+
+	// DeliveryTimeoutMs is how long librdkafka tries to deliver a message, and so how long Produce can wait.
+	DeliveryTimeoutMs int `mapstructure:"delivery-timeout-ms"`
+
+	// Serialization is how the data of events is encoded: json, or avro or protobuf with the schemas registered
+	// with SchemaRegistry.
+	Serialization  string                 `mapstructure:"serialization"`
+	SchemaRegistry *SchemaRegistryOptions `mapstructure:"schema-registry"`
+
 	BuiltInFeatures                    string `mapstructure:"builtin-features"`
 	ClientId                           string `mapstructure:"client-id"`
 	MetadataBrokerList                 string `mapstructure:"metadata-broker-list"`
@@ -93,6 +115,11 @@ type Options struct {
 func NewOptions() *Options {
 	return &Options{
 		DefaultTopic:                       "common-inventory",
+		TopicCheckTimeoutMs:                10000,
+		HealthCheckIntervalMs:              5000,
+		DeliveryTimeoutMs:                  30000,
+		Serialization:                      SerializationJSON,
+		SchemaRegistry:                     NewSchemaRegistryOptions(),
 		BuiltInFeatures:                    "gzip, snappy, ssl, sasl, regex, lz4, sasl_plain, sasl_scram, plugins, zstd, sasl_oauthbearer, http, oidc",
 		ClientId:                           "rdkafka",
 		MetadataBrokerList:                 "",
@@ -183,7 +210,12 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 		prefix = prefix + "."
 	}
 
-	fs.StringVar(&o.DefaultTopic, prefix+"default-topic", o.DefaultTopic, "The topic to use.")
+	fs.StringVar(&o.DefaultTopic, prefix+"default-topic", o.DefaultTopic, "The topic to use for events that don't match a route.")
+	fs.IntVar(&o.TopicCheckTimeoutMs, prefix+"topic-check-timeout-ms", o.TopicCheckTimeoutMs, "How long to wait at startup for the brokers to confirm the default topic and the topics of the routes exist.")
+	fs.IntVar(&o.HealthCheckIntervalMs, prefix+"health-check-interval-ms", o.HealthCheckIntervalMs, "How often to check whether unreachable brokers are back.  The server isn't ready until they are.")
+	fs.IntVar(&o.DeliveryTimeoutMs, prefix+"delivery-timeout-ms", o.DeliveryTimeoutMs, "How long to try to deliver an event before it fails and gets the failure policy.  Sets message.timeout.ms.")
+	fs.StringVar(&o.Serialization, prefix+"serialization", o.Serialization, fmt.Sprintf("How to encode the data of events: one of %s.  avro and protobuf use the schemas registered with the schema registry.", strings.Join(Serializations, ", ")))
+	o.SchemaRegistry.AddFlags(fs, prefix+"schema-registry")
 
 	fs.StringVar(&o.BuiltInFeatures, prefix+"builtin-features", o.BuiltInFeatures, "Indicates the builtin features for this build of librdkafka. An application can either query this value or attempt to set it with its list of required features to check for library support. \n*Type: CSV flags*")
 	fs.StringVar(&o.ClientId, prefix+"client-id", o.BuiltInFeatures, "Client identifier. \n*Type: string*")
@@ -272,6 +304,34 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 func (o *Options) Validate() []error {
 	var errs []error
 
+	if o.DefaultTopic == "" {
+		errs = append(errs, fmt.Errorf("the kafka default-topic must not be empty"))
+	}
+
+	for i, r := range o.Routes {
+		for _, err := range r.Validate() {
+			errs = append(errs, fmt.Errorf("kafka routes[%d]: %w", i, err))
+		}
+	}
+
+	if o.TopicCheckTimeoutMs <= 0 {
+		errs = append(errs, fmt.Errorf("the kafka topic-check-timeout-ms must be positive: %d", o.TopicCheckTimeoutMs))
+	}
+
+	if o.HealthCheckIntervalMs <= 0 {
+		errs = append(errs, fmt.Errorf("the kafka health-check-interval-ms must be positive: %d", o.HealthCheckIntervalMs))
+	}
+
+	if o.DeliveryTimeoutMs <= 0 {
+		errs = append(errs, fmt.Errorf("the kafka delivery-timeout-ms must be positive: %d", o.DeliveryTimeoutMs))
+	}
+
+	if !slices.Contains(Serializations, o.Serialization) {
+		errs = append(errs, fmt.Errorf("the kafka serialization must be one of %s: %q", strings.Join(Serializations, ", "), o.Serialization))
+	} else if o.Serialization != SerializationJSON {
+		errs = append(errs, o.SchemaRegistry.Validate()...)
+	}
+
 	return errs
 }
 
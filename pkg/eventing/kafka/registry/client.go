@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The schema types.  An empty type means Avro.
+const (
+	Avro     = "AVRO"
+	Protobuf = "PROTOBUF"
+)
+
+// Schema is a schema as the registry API sends and receives it.
+type Schema struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// Error is an error response from the registry.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry error %d: %s", e.Code, e.Message)
+}
+
+// NotFound reports whether the subject, version or schema doesn't exist.
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// Client calls the parts of the Confluent Schema Registry API that producers need.
+type Client struct {
+	URL      string
+	Username string
+	Password string
+	HTTP     *http.Client
+}
+
+func NewClient(registryURL string, username string, password string, timeout time.Duration) *Client {
+	return &Client{
+		URL:      strings.TrimSuffix(registryURL, "/"),
+		Username: username,
+		Password: password,
+		HTTP:     &http.Client{Timeout: timeout},
+	}
+}
+
+// Lookup returns the id of the schema if it's registered under the subject.  The error is an *Error that's
+// NotFound if it isn't.
+func (c *Client) Lookup(ctx context.Context, subject string, schema *Schema) (int, error) {
+	var result struct {
+		ID int `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), schema, &result)
+	return result.ID, err
+}
+
+// Compatible reports whether the schema is compatible with the subject's versions under the subject's
+// compatibility level.  A subject without versions is compatible with anything.
+func (c *Client) Compatible(ctx context.Context, subject string, schema *Schema) (bool, error) {
+	var result struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	err := c.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", schema, &result)
+	if e, ok := err.(*Error); ok && e.NotFound() {
+		return true, nil
+	}
+	return result.IsCompatible, err
+}
+
+// Register adds the schema as a new version of the subject and returns its id.  Registering a schema that's
+// already registered returns its id.
+func (c *Client) Register(ctx context.Context, subject string, schema *Schema) (int, error) {
+	var result struct {
+		ID int `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schema, &result)
+	return result.ID, err
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body any, result any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Accept", ContentType)
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{Status: resp.StatusCode, Code: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(e); err != nil || e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		return e
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
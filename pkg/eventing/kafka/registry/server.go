@@ -0,0 +1,300 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hamba/avro/v2"
+)
+
+// ContentType is the media type of the registry API.
+const ContentType = "application/vnd.schemaregistry.v1+json"
+
+// The registry API's error codes.
+const (
+	codeSubjectNotFound    = 40401
+	codeVersionNotFound    = 40402
+	codeSchemaNotFound     = 40403
+	codeIncompatibleSchema = 409
+	codeInvalidSchema      = 42201
+	codeInvalidVersion     = 42202
+)
+
+// compatibilityLevel is the only compatibility level the server checks.
+const compatibilityLevel = "BACKWARD"
+
+// Server is an in-memory stand-in for a Confluent-compatible schema registry, for development and tests.  It
+// checks new versions of Avro schemas like a registry with BACKWARD compatibility, and accepts any new version of
+// other schemas.  Everything is lost when it stops.
+type Server struct {
+	mu       sync.Mutex
+	schemas  []*Schema
+	subjects map[string][]int
+}
+
+func NewServer() *Server {
+	return &Server{subjects: map[string][]int{}}
+}
+
+type version struct {
+	Subject string `json:"subject"`
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Schema
+}
+
+func (s *Server) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/config", s.config)
+	r.Get("/schemas/ids/{id}", s.getSchema)
+	r.Get("/subjects", s.listSubjects)
+	r.Post("/subjects/{subject}", s.lookup)
+	r.Get("/subjects/{subject}/versions", s.listVersions)
+	r.Post("/subjects/{subject}/versions", s.register)
+	r.Get("/subjects/{subject}/versions/{version}", s.getVersion)
+	r.Post("/compatibility/subjects/{subject}/versions/{version}", s.compatibility)
+
+	return r
+}
+
+func (s *Server) config(w http.ResponseWriter, r *http.Request) {
+	write(w, map[string]string{"compatibilityLevel": compatibilityLevel})
+}
+
+func (s *Server) getSchema(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 || id > len(s.schemas) {
+		writeError(w, http.StatusNotFound, codeSchemaNotFound, "Schema not found")
+		return
+	}
+	write(w, s.schemas[id-1])
+}
+
+func (s *Server) listSubjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subjects := []string{}
+	for subject := range s.subjects {
+		subjects = append(subjects, subject)
+	}
+	slices.Sort(subjects)
+	write(w, subjects)
+}
+
+func (s *Server) listVersions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, found := s.subjects[chi.URLParam(r, "subject")]
+	if !found {
+		writeError(w, http.StatusNotFound, codeSubjectNotFound, "Subject not found")
+		return
+	}
+
+	versions := []int{}
+	for i := range ids {
+		versions = append(versions, i+1)
+	}
+	write(w, versions)
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.version(w, chi.URLParam(r, "subject"), chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+	write(w, v)
+}
+
+// lookup finds the version of the subject with the schema.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) {
+	schema, ok := readSchema(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject := chi.URLParam(r, "subject")
+	ids, found := s.subjects[subject]
+	if !found {
+		writeError(w, http.StatusNotFound, codeSubjectNotFound, "Subject not found")
+		return
+	}
+
+	for i, id := range ids {
+		if same(s.schemas[id-1], schema) {
+			write(w, &version{Subject: subject, ID: id, Version: i + 1, Schema: *s.schemas[id-1]})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, codeSchemaNotFound, "Schema not found")
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	schema, ok := readSchema(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject := chi.URLParam(r, "subject")
+	ids := s.subjects[subject]
+	for _, id := range ids {
+		if same(s.schemas[id-1], schema) {
+			write(w, map[string]int{"id": id})
+			return
+		}
+	}
+
+	if len(ids) > 0 {
+		if err := compatible(schema, s.schemas[ids[len(ids)-1]-1]); err != nil {
+			writeError(w, http.StatusConflict, codeIncompatibleSchema, fmt.Sprintf("Schema being registered is incompatible with an earlier schema: %v", err))
+			return
+		}
+	}
+
+	// the same schema under another subject has the same id
+	id := slices.IndexFunc(s.schemas, func(other *Schema) bool { return same(other, schema) }) + 1
+	if id == 0 {
+		s.schemas = append(s.schemas, schema)
+		id = len(s.schemas)
+	}
+	s.subjects[subject] = append(ids, id)
+	write(w, map[string]int{"id": id})
+}
+
+func (s *Server) compatibility(w http.ResponseWriter, r *http.Request) {
+	schema, ok := readSchema(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.version(w, chi.URLParam(r, "subject"), chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	err := compatible(schema, &v.Schema)
+	result := map[string]any{"is_compatible": err == nil}
+	if err != nil {
+		result["messages"] = []string{err.Error()}
+	}
+	write(w, result)
+}
+
+// version finds the version of the subject, which is a number or latest.  It writes an error and returns false if
+// there's no such version.
+func (s *Server) version(w http.ResponseWriter, subject string, number string) (*version, bool) {
+	ids, found := s.subjects[subject]
+	if !found {
+		writeError(w, http.StatusNotFound, codeSubjectNotFound, "Subject not found")
+		return nil, false
+	}
+
+	n := len(ids)
+	if number != "latest" {
+		var err error
+		if n, err = strconv.Atoi(number); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, codeInvalidVersion, "The version must be a number or latest")
+			return nil, false
+		}
+	}
+	if n < 1 || n > len(ids) {
+		writeError(w, http.StatusNotFound, codeVersionNotFound, "Version not found")
+		return nil, false
+	}
+
+	id := ids[n-1]
+	return &version{Subject: subject, ID: id, Version: n, Schema: *s.schemas[id-1]}, true
+}
+
+// readSchema reads the schema in the body.  It writes an error and returns false if it's not valid.
+func readSchema(w http.ResponseWriter, r *http.Request) (*Schema, bool) {
+	var schema Schema
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidSchema, fmt.Sprintf("Invalid request: %v", err))
+		return nil, false
+	}
+	if schema.SchemaType == Avro {
+		schema.SchemaType = ""
+	}
+
+	if schema.SchemaType == "" {
+		if _, err := parseAvro(schema.Schema); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, codeInvalidSchema, fmt.Sprintf("Invalid schema: %v", err))
+			return nil, false
+		}
+	}
+	return &schema, true
+}
+
+// same reports whether two schemas are the same.  Avro schemas are compared by their canonical form.
+func same(a *Schema, b *Schema) bool {
+	if a.SchemaType != b.SchemaType {
+		return false
+	}
+	if a.SchemaType != "" {
+		return a.Schema == b.Schema
+	}
+
+	sa, errA := parseAvro(a.Schema)
+	sb, errB := parseAvro(b.Schema)
+	return errA == nil && errB == nil && sa.Fingerprint() == sb.Fingerprint()
+}
+
+// compatible returns an error unless data written with the old schema can be read with the new one.  Only Avro
+// schemas are checked.
+func compatible(schema *Schema, old *Schema) error {
+	if schema.SchemaType != old.SchemaType {
+		return fmt.Errorf("the schema type changed from %q to %q", old.SchemaType, schema.SchemaType)
+	}
+	if schema.SchemaType != "" {
+		return nil
+	}
+
+	reader, err := parseAvro(schema.Schema)
+	if err != nil {
+		return err
+	}
+	writer, err := parseAvro(old.Schema)
+	if err != nil {
+		return err
+	}
+	return avro.NewSchemaCompatibility().Compatible(reader, writer)
+}
+
+// parseAvro parses the schema with its own cache, so versions that redefine the same names don't clash.
+func parseAvro(schema string) (avro.Schema, error) {
+	return avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+}
+
+func write(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", ContentType)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&Error{Code: code, Message: message})
+}
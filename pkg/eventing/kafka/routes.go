@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"fmt"
+	"slices"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// Route sends the events that match every one of its non-empty lists to its topic.  Routes can only be set in the
+// config file.
+type Route struct {
+	Topic string `mapstructure:"topic"`
+
+	ResourceTypes []string `mapstructure:"resource-types"`
+	ReporterTypes []string `mapstructure:"reporter-types"`
+	Workspaces    []string `mapstructure:"workspaces"`
+	Tenants       []string `mapstructure:"tenants"`
+}
+
+func (r *Route) Validate() []error {
+	var errs []error
+
+	if r.Topic == "" {
+		errs = append(errs, fmt.Errorf("every route needs a topic"))
+	}
+
+	return errs
+}
+
+// Matches reports whether the route applies to a change to the resource by the identity.
+func (r *Route) Matches(identity *authnapi.Identity, resource *models.Resource) bool {
+	workspace := ""
+	if resource.Workspace != nil {
+		workspace = *resource.Workspace
+	}
+
+	return matches(r.ResourceTypes, resource.ResourceType) &&
+		matches(r.ReporterTypes, reporterType(identity, resource)) &&
+		matches(r.Workspaces, workspace) &&
+		matches(r.Tenants, identity.Tenant)
+}
+
+func matches(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, value)
+}
+
+// reporterType is the type of the identity's data about the resource, or the identity's type if it has none.
+func reporterType(identity *authnapi.Identity, resource *models.Resource) string {
+	for _, d := range resource.ReporterData {
+		if d.ReporterID == identity.Principal {
+			return d.ReporterType
+		}
+	}
+	return identity.Type
+}
@@ -0,0 +1,411 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/hamba/avro/v2"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/kafka/registry"
+)
+
+// The ways the data of the events can be encoded.  Avro and Protobuf use the schemas in api.Schemas, registered
+// with a schema registry.
+const (
+	SerializationJSON     = "json"
+	SerializationAvro     = "avro"
+	SerializationProtobuf = "protobuf"
+)
+
+var Serializations = []string{SerializationJSON, SerializationAvro, SerializationProtobuf}
+
+// The content types of the data of events encoded with a registered schema.
+const (
+	AvroContentType     = "application/avro"
+	ProtobufContentType = "application/x-protobuf"
+)
+
+// SchemaRegistryOptions is the Confluent-compatible schema registry that Avro and Protobuf schemas are registered
+// with.
+type SchemaRegistryOptions struct {
+	URL          string `mapstructure:"url"`
+	Username     string `mapstructure:"username"`
+	PasswordFile string `mapstructure:"password-file"`
+
+	// AutoRegister registers the schemas if they aren't registered yet and they're compatible with the versions
+	// that are.  Otherwise they must already be registered.
+	AutoRegister bool `mapstructure:"auto-register"`
+
+	TimeoutMs int `mapstructure:"timeout-ms"`
+}
+
+func NewSchemaRegistryOptions() *SchemaRegistryOptions {
+	return &SchemaRegistryOptions{
+		AutoRegister: true,
+		TimeoutMs:    10000,
+	}
+}
+
+func (o *SchemaRegistryOptions) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.URL, prefix+"url", o.URL, "The URL of the schema registry.")
+	fs.StringVar(&o.Username, prefix+"username", o.Username, "The username for basic authentication to the schema registry.")
+	fs.StringVar(&o.PasswordFile, prefix+"password-file", o.PasswordFile, "A file with the password for basic authentication to the schema registry.")
+	fs.BoolVar(&o.AutoRegister, prefix+"auto-register", o.AutoRegister, "Register the schemas if they're compatible with the registered versions.  Otherwise they must already be registered.")
+	fs.IntVar(&o.TimeoutMs, prefix+"timeout-ms", o.TimeoutMs, "How long to wait for the schema registry.")
+}
+
+func (o *SchemaRegistryOptions) Validate() []error {
+	var errs []error
+
+	if o.URL == "" {
+		errs = append(errs, fmt.Errorf("the kafka schema-registry url must not be empty"))
+	}
+	if (o.Username == "") != (o.PasswordFile == "") {
+		errs = append(errs, fmt.Errorf("the kafka schema-registry username and password-file must be set together"))
+	}
+	if o.TimeoutMs <= 0 {
+		errs = append(errs, fmt.Errorf("the kafka schema-registry timeout-ms must be positive: %d", o.TimeoutMs))
+	}
+
+	return errs
+}
+
+// serializer encodes the data of events in the Confluent wire format: a zero byte, the big-endian id of the
+// registered schema and the encoded data.  Protobuf data also has the index of its message in the proto file.
+type serializer struct {
+	ContentType string
+
+	// schemas are the registered schemas by the data schema of the events they encode.
+	schemas map[string]*registeredSchema
+}
+
+type registeredSchema struct {
+	Subject string
+	ID      int
+
+	// URL is where the registry serves the schema by id.  It's the dataschema of the events it encodes.
+	URL string
+
+	// header is the start of every message with the schema
+	header []byte
+
+	// encode encodes the JSON form of the data
+	encode func(data []byte) ([]byte, error)
+}
+
+// newSerializer registers the schemas for the configured serialization, or checks they're registered, and returns
+// a serializer for them.  It returns nil for JSON.
+func newSerializer(config CompletedConfig) (*serializer, error) {
+	switch config.Serialization {
+	case SerializationAvro:
+		return newAvroSerializer(config)
+	case SerializationProtobuf:
+		return newProtobufSerializer(config)
+	default:
+		return nil, nil
+	}
+}
+
+// Serialize encodes the event's data with the schema for its data schema.
+func (s *serializer) Serialize(event *api.Event) ([]byte, error) {
+	schema, err := s.schema(event)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := schema.encode(data)
+	if err != nil {
+		return nil, fmt.Errorf("the data doesn't match the schema of subject %s: %w", schema.Subject, err)
+	}
+	return append(slices.Clone(schema.header), payload...), nil
+}
+
+// DataSchema is the URL of the registered schema that encodes the event's data.
+func (s *serializer) DataSchema(event *api.Event) (string, error) {
+	schema, err := s.schema(event)
+	if err != nil {
+		return "", err
+	}
+	return schema.URL, nil
+}
+
+// schema is the registered schema for the event's data schema.
+func (s *serializer) schema(event *api.Event) (*registeredSchema, error) {
+	dataSchema := event.DataSchema
+	if dataSchema == "" {
+		dataSchema = api.DataSchema
+	}
+	schema, found := s.schemas[dataSchema]
+	if !found {
+		return nil, fmt.Errorf("no registered schema for %s", dataSchema)
+	}
+	return schema, nil
+}
+
+func newAvroSerializer(config CompletedConfig) (*serializer, error) {
+	s := &serializer{ContentType: AvroContentType, schemas: map[string]*registeredSchema{}}
+
+	files := map[string]string{
+		api.DataSchema:       api.AvroSchemaFile,
+		api.ResyncDataSchema: api.ResyncAvroSchemaFile,
+	}
+	for dataSchema, file := range files {
+		text, err := api.Schemas.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		// the files redefine shared records like Actor, so each gets its own cache
+		schema, err := avro.ParseWithCache(string(text), "", &avro.SchemaCache{})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		subject := subjectName(schema.(avro.NamedSchema).FullName(), SerializationAvro)
+
+		id, err := register(config, subject, &registry.Schema{Schema: string(text)})
+		if err != nil {
+			return nil, err
+		}
+
+		s.schemas[dataSchema] = &registeredSchema{
+			Subject: subject,
+			ID:      id,
+			URL:     schemaURL(config, id),
+			header:  wireHeader(id),
+			encode: func(data []byte) ([]byte, error) {
+				v, err := avroValue(schema, data)
+				if err != nil {
+					return nil, err
+				}
+				return avro.Marshal(schema, v)
+			},
+		}
+	}
+
+	return s, nil
+}
+
+// avroValue converts the JSON form of a value to the form the avro encoder takes for the schema.  Timestamps are
+// parsed, and strings flagged with "json" hold the JSON text of their value.
+func avroValue(schema avro.Schema, data json.RawMessage) (any, error) {
+	null := len(data) == 0 || string(data) == "null"
+
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return avroValue(s.Schema(), data)
+
+	case *avro.RecordSchema:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		record := map[string]any{}
+		for _, f := range s.Fields() {
+			raw := fields[f.Name()]
+			if f.Prop("json") == true && len(raw) > 0 && string(raw) != "null" {
+				// the field holds the JSON text of the value
+				raw, _ = json.Marshal(string(raw))
+			}
+			v, err := avroValue(f.Type(), raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", s.Name(), f.Name(), err)
+			}
+			record[f.Name()] = v
+		}
+		return record, nil
+
+	case *avro.UnionSchema:
+		// the schemas only use unions of null and one other type
+		if null {
+			return map[string]any(nil), nil
+		}
+		for _, t := range s.Types() {
+			if t.Type() == avro.Null {
+				continue
+			}
+			v, err := avroValue(t, data)
+			if err != nil {
+				return nil, err
+			}
+			name := string(t.Type())
+			if n, ok := t.(avro.NamedSchema); ok {
+				name = n.FullName()
+			} else if r, ok := t.(*avro.RefSchema); ok {
+				name = r.Schema().FullName()
+			}
+			return map[string]any{name: v}, nil
+		}
+		return nil, errors.New("the union has no type but null")
+
+	case *avro.ArraySchema:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		values := []any{}
+		for _, item := range items {
+			v, err := avroValue(s.Items(), item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+
+	case *avro.PrimitiveSchema:
+		if null {
+			return nil, errors.New("the value is required")
+		}
+		switch {
+		case s.Logical() != nil && s.Logical().Type() == avro.TimestampMicros:
+			var t time.Time
+			err := json.Unmarshal(data, &t)
+			return t, err
+		case s.Type() == avro.String:
+			var v string
+			err := json.Unmarshal(data, &v)
+			return v, err
+		case s.Type() == avro.Long:
+			var v int64
+			err := json.Unmarshal(data, &v)
+			return v, err
+		case s.Type() == avro.Boolean:
+			var v bool
+			err := json.Unmarshal(data, &v)
+			return v, err
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported avro type %s", schema.Type())
+}
+
+func newProtobufSerializer(config CompletedConfig) (*serializer, error) {
+	s := &serializer{ContentType: ProtobufContentType, schemas: map[string]*registeredSchema{}}
+
+	text, err := api.Schemas.ReadFile(api.ProtoSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{api.ProtoSchemaFile: string(text)}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), api.ProtoSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := map[string]protoreflect.Name{
+		api.DataSchema:       "ResourceEvent",
+		api.ResyncDataSchema: "ResyncCompleted",
+	}
+	for dataSchema, name := range messages {
+		md := files[0].Messages().ByName(name)
+		if md == nil {
+			return nil, fmt.Errorf("%s has no message %s", api.ProtoSchemaFile, name)
+		}
+		subject := subjectName(string(md.FullName()), SerializationProtobuf)
+
+		id, err := register(config, subject, &registry.Schema{Schema: string(text), SchemaType: registry.Protobuf})
+		if err != nil {
+			return nil, err
+		}
+
+		// the message indexes are a count and the index of each message in its parent.  [0] is shortened to 0.
+		header := wireHeader(id)
+		if md.Index() == 0 {
+			header = append(header, 0)
+		} else {
+			header = binary.AppendVarint(header, 1)
+			header = binary.AppendVarint(header, int64(md.Index()))
+		}
+
+		s.schemas[dataSchema] = &registeredSchema{
+			Subject: subject,
+			ID:      id,
+			URL:     schemaURL(config, id),
+			header:  header,
+			encode: func(data []byte) ([]byte, error) {
+				msg := dynamicpb.NewMessage(md)
+				if err := protojson.Unmarshal(data, msg); err != nil {
+					return nil, err
+				}
+				return proto.Marshal(msg)
+			},
+		}
+	}
+
+	return s, nil
+}
+
+// subjectName is the subject of the schema for a record or message, like com.redhat.inventory.v1.ResourceEvent-avro.
+// Each serialization has its own subjects, since registries don't let a subject change its schema type.
+func subjectName(name string, serialization string) string {
+	return name + "-" + serialization
+}
+
+// register returns the id of the schema under the subject.  A schema that isn't registered yet is registered if
+// auto-register is on and it's compatible with the subject's versions.
+func register(config CompletedConfig, subject string, schema *registry.Schema) (int, error) {
+	ctx := context.Background()
+	client := config.SchemaRegistry
+
+	id, err := client.Lookup(ctx, subject, schema)
+	var rerr *registry.Error
+	switch {
+	case err == nil:
+		return id, nil
+	case !errors.As(err, &rerr) || !rerr.NotFound():
+		return 0, fmt.Errorf("failed to look up the schema of subject %s: %w", subject, err)
+	case !config.AutoRegisterSchemas:
+		return 0, fmt.Errorf("the schema isn't registered under subject %s, and auto-register is off", subject)
+	}
+
+	compatible, err := client.Compatible(ctx, subject, schema)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check the schema of subject %s: %w", subject, err)
+	}
+	if !compatible {
+		return 0, fmt.Errorf("the schema isn't compatible with the registered versions of subject %s", subject)
+	}
+
+	id, err = client.Register(ctx, subject, schema)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register the schema of subject %s: %w", subject, err)
+	}
+	return id, nil
+}
+
+// schemaURL is where the registry serves the schema with the id.
+func schemaURL(config CompletedConfig, id int) string {
+	return fmt.Sprintf("%s/schemas/ids/%d", config.SchemaRegistry.URL, id)
+}
+
+func wireHeader(id int) []byte {
+	var b bytes.Buffer
+	b.WriteByte(0)
+	binary.Write(&b, binary.BigEndian, uint32(id))
+	return b.Bytes()
+}
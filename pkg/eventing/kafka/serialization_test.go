@@ -0,0 +1,337 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gorm.io/datatypes"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/kafka/registry"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+func newRegistry(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(registry.NewServer().Routes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestSerializer(t *testing.T, registryURL string, serialization string) *serializer {
+	config := CompletedConfig{&completedConfig{
+		Serialization:       serialization,
+		SchemaRegistry:      registry.NewClient(registryURL, "", "", 5*time.Second),
+		AutoRegisterSchemas: true,
+	}}
+	s, err := newSerializer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// testEvents are a change with every optional part set and the end of a resync.
+func testEvents() []*api.Event {
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	workspace := "prod"
+	resource := &models.Resource{
+		ID:           7,
+		CreatedAt:    created,
+		UpdatedAt:    created.Add(time.Hour),
+		DisplayName:  "cluster 7",
+		ResourceType: "cluster",
+		Workspace:    &workspace,
+		ReporterData: []models.ReporterData{{
+			ReporterID:      "acm-hub-1",
+			ReporterType:    "ACM",
+			ReporterVersion: "2.11",
+			LocalResourceId: "7",
+			Created:         created,
+			Updated:         created.Add(time.Hour),
+			Data:            datatypes.JSON(`{"ApiServer":"https://api.example.com","nodes":3}`),
+		}},
+	}
+	identity := &authnapi.Identity{Principal: "acm-hub-1", Type: "ACM", IsReporter: true}
+	reporter := &api.Reporter{ReporterId: "acm-hub-1", ReporterType: "ACM", ReporterVersion: "2.11"}
+
+	return []*api.Event{
+		api.NewResourceEvent(api.ResourceUpdated, "/api/inventory/v1alpha1/resources/clusters/7", resource.ID, "cluster", resource, resource, reporter, identity),
+		api.NewResyncCompletedEvent("/api/inventory/v1alpha1/admin/resyncs/1", "1", api.ResyncFilter{Workspace: "prod"}, 12, identity),
+	}
+}
+
+// spooled is the event after a round trip through the spool, which keeps events as JSON.
+func spooled(t *testing.T, event *api.Event) *api.Event {
+	b, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded api.Event
+	if err := json.Unmarshal(b, &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	return &reloaded
+}
+
+// registeredSchemaText reads the header of the message and fetches its schema from the registry by id, like a
+// consumer's deserializer.
+func registeredSchemaText(t *testing.T, registryURL string, message []byte) (*registry.Schema, []byte) {
+	if len(message) < 5 || message[0] != 0 {
+		t.Fatalf("the message doesn't start with the wire format header: %x", message)
+	}
+	id := binary.BigEndian.Uint32(message[1:5])
+
+	resp, err := http.Get(fmt.Sprintf("%s/schemas/ids/%d", registryURL, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("schema %d isn't registered: %s", id, resp.Status)
+	}
+	var schema registry.Schema
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil {
+		t.Fatal(err)
+	}
+	return &schema, message[5:]
+}
+
+func TestAvroRoundTrip(t *testing.T) {
+	srv := newRegistry(t)
+	s := newTestSerializer(t, srv.URL, SerializationAvro)
+
+	for _, event := range testEvents() {
+		message, err := s.Serialize(event)
+		if err != nil {
+			t.Fatalf("%s: %v", event.Type, err)
+		}
+
+		reloaded, err := s.Serialize(spooled(t, event))
+		if err != nil {
+			t.Fatalf("%s after spooling: %v", event.Type, err)
+		}
+		if !bytes.Equal(message, reloaded) {
+			t.Errorf("%s: a spooled event is encoded differently", event.Type)
+		}
+
+		text, payload := registeredSchemaText(t, srv.URL, message)
+		if text.SchemaType != "" {
+			t.Fatalf("expected an avro schema, got %s", text.SchemaType)
+		}
+		schema, err := avro.ParseWithCache(text.Schema, "", &avro.SchemaCache{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]any
+		if err := avro.Unmarshal(schema, payload, &decoded); err != nil {
+			t.Fatalf("%s: %v", event.Type, err)
+		}
+
+		switch data := event.Data.(type) {
+		case *api.ResourceData:
+			after := decoded["after"].(map[string]any)["com.redhat.inventory.v1.Resource"].(map[string]any)
+			if decoded["resource_id"] != data.ResourceId || after["display_name"] != data.After.DisplayName {
+				t.Errorf("unexpected resource event: %v", decoded)
+			}
+			d := after["reporter_data"].([]any)[0].(map[string]any)["data"].(map[string]any)["string"]
+			if d != `{"ApiServer":"https://api.example.com","nodes":3}` {
+				t.Errorf("expected the reporter's data as JSON text, got %v", d)
+			}
+			if !after["updated_at"].(time.Time).Equal(data.After.UpdatedAt) {
+				t.Errorf("expected updated_at %s, got %v", data.After.UpdatedAt, after["updated_at"])
+			}
+		case *api.ResyncData:
+			if decoded["resync_id"] != data.ResyncId || decoded["count"] != data.Count {
+				t.Errorf("unexpected resync event: %v", decoded)
+			}
+		}
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	srv := newRegistry(t)
+	s := newTestSerializer(t, srv.URL, SerializationProtobuf)
+
+	for _, event := range testEvents() {
+		message, err := s.Serialize(event)
+		if err != nil {
+			t.Fatalf("%s: %v", event.Type, err)
+		}
+		reloaded, err := s.Serialize(spooled(t, event))
+		if err != nil {
+			t.Fatalf("%s after spooling: %v", event.Type, err)
+		}
+
+		// the struct in the reporter's data is a map, so only deterministic encodings can be compared
+		decoded := decodeProtobuf(t, srv.URL, message)
+		deterministic := proto.MarshalOptions{Deterministic: true}
+		a, err := deterministic.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := deterministic.Marshal(decodeProtobuf(t, srv.URL, reloaded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s: a spooled event is encoded differently", event.Type)
+		}
+
+		fields := decoded.Descriptor().Fields()
+		switch data := event.Data.(type) {
+		case *api.ResourceData:
+			if decoded.Descriptor().Name() != "ResourceEvent" {
+				t.Fatalf("expected a ResourceEvent, got %s", decoded.Descriptor().Name())
+			}
+			after := decoded.Get(fields.ByName("after")).Message()
+			if decoded.Get(fields.ByName("resource_id")).String() != data.ResourceId ||
+				after.Get(after.Descriptor().Fields().ByName("display_name")).String() != data.After.DisplayName {
+				t.Errorf("unexpected resource event: %v", decoded)
+			}
+			reporterData := after.Get(after.Descriptor().Fields().ByName("reporter_data")).List().Get(0).Message()
+			d := reporterData.Get(reporterData.Descriptor().Fields().ByName("data")).Message()
+			structValue := d.Get(d.Descriptor().Fields().ByName("struct_value")).Message()
+			nodes := structValue.Get(structValue.Descriptor().Fields().ByName("fields")).Map().Get(protoreflect.ValueOfString("nodes").MapKey())
+			if !nodes.IsValid() {
+				t.Errorf("expected the reporter's data as a struct, got %v", d)
+			}
+		case *api.ResyncData:
+			if decoded.Descriptor().Name() != "ResyncCompleted" {
+				t.Fatalf("expected a ResyncCompleted, got %s", decoded.Descriptor().Name())
+			}
+			if decoded.Get(fields.ByName("resync_id")).String() != data.ResyncId || decoded.Get(fields.ByName("count")).Int() != data.Count {
+				t.Errorf("unexpected resync event: %v", decoded)
+			}
+		}
+	}
+}
+
+// decodeProtobuf decodes the message with its registered schema and the message indexes in its header.
+func decodeProtobuf(t *testing.T, registryURL string, message []byte) *dynamicpb.Message {
+	text, payload := registeredSchemaText(t, registryURL, message)
+	if text.SchemaType != registry.Protobuf {
+		t.Fatalf("expected a protobuf schema, got %q", text.SchemaType)
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"registered.proto": text.Schema}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), "registered.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the indexes are a count and an index per level.  0 is short for the first top level message.
+	index := int64(0)
+	count, n := binary.Varint(payload)
+	payload = payload[n:]
+	if count > 0 {
+		index, n = binary.Varint(payload)
+		payload = payload[n:]
+	}
+
+	msg := dynamicpb.NewMessage(files[0].Messages().Get(int(index)))
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestSwitchingSerializations(t *testing.T) {
+	srv := newRegistry(t)
+	for _, serialization := range []string{SerializationAvro, SerializationProtobuf, SerializationAvro} {
+		s := newTestSerializer(t, srv.URL, serialization)
+		if _, err := s.Serialize(testEvents()[0]); err != nil {
+			t.Fatalf("%s: %v", serialization, err)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/subjects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var subjects []string
+	if err := json.NewDecoder(resp.Body).Decode(&subjects); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"com.redhat.inventory.v1.ResourceEvent-avro",
+		"com.redhat.inventory.v1.ResourceEvent-protobuf",
+		"com.redhat.inventory.v1.ResyncCompleted-avro",
+		"com.redhat.inventory.v1.ResyncCompleted-protobuf",
+	}
+	if fmt.Sprint(subjects) != fmt.Sprint(expected) {
+		t.Errorf("expected the subjects %v, got %v", expected, subjects)
+	}
+}
+
+func TestProduceSetsTheRegisteredDataSchema(t *testing.T) {
+	srv := newRegistry(t)
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("events", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers(), "log_level": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	m := &KafkaManager{
+		Source:     "urn:test",
+		Producer:   producer,
+		Serializer: newTestSerializer(t, srv.URL, SerializationProtobuf),
+		Log:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	if err := NewProducer(m, "events", nil).Produce(context.Background(), testEvents()[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers(), "group.id": "test", "log_level": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+	topic := "events"
+	if err := consumer.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: kafka.OffsetBeginning}}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := consumer.ReadMessage(10 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dataSchema string
+	for _, h := range msg.Headers {
+		if h.Key == "ce_dataschema" {
+			dataSchema = string(h.Value)
+		}
+	}
+	expected := fmt.Sprintf("%s/schemas/ids/%d", srv.URL, binary.BigEndian.Uint32(msg.Value[1:5]))
+	if dataSchema != expected {
+		t.Errorf("expected the dataschema %s, got %q", expected, dataSchema)
+	}
+}
@@ -1,21 +1,52 @@
 package eventing
 
 import (
-	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
 
+	"github.com/csams/common-inventory/pkg/eventing/delivery"
+	"github.com/csams/common-inventory/pkg/eventing/file"
+	"github.com/csams/common-inventory/pkg/eventing/jetstream"
 	"github.com/csams/common-inventory/pkg/eventing/kafka"
+	"github.com/csams/common-inventory/pkg/eventing/subscriptions"
+	"github.com/csams/common-inventory/pkg/eventing/webhook"
 	"github.com/spf13/pflag"
 )
 
+// Eventers are the eventing subsystems.
+var Eventers = []string{"stdout", "file", "kafka", "jetstream", "webhook", "subscriptions"}
+
 type Options struct {
-	Kafka   *kafka.Options `mapstructure:"kafka"`
-	Eventer string         `mapstructure:"eventer"`
+	Kafka         *kafka.Options         `mapstructure:"kafka"`
+	JetStream     *jetstream.Options     `mapstructure:"jetstream"`
+	Webhook       *webhook.Options       `mapstructure:"webhook"`
+	File          *file.Options          `mapstructure:"file"`
+	Subscriptions *subscriptions.Options `mapstructure:"subscriptions"`
+	Delivery      *delivery.Options      `mapstructure:"delivery"`
+	Eventer       string                 `mapstructure:"eventer"`
+
+	// Eventers send every event with each of the eventers.  It overrides Eventer.
+	Eventers []string `mapstructure:"eventers"`
+
+	// Policies are failure policies for some of the eventers.  The others use Delivery.  They can only be set in the
+	// config file.
+	Policies map[string]*delivery.Options `mapstructure:"policies"`
+
+	// Source is the source attribute of the events.
+	Source string `mapstructure:"source"`
 }
 
 func NewOptions() *Options {
 	return &Options{
-		Kafka:   kafka.NewOptions(),
-		Eventer: "stdout",
+		Kafka:         kafka.NewOptions(),
+		JetStream:     jetstream.NewOptions(),
+		Webhook:       webhook.NewOptions(),
+		File:          file.NewOptions(),
+		Subscriptions: subscriptions.NewOptions(),
+		Delivery:      delivery.NewOptions(),
+		Eventer:       "stdout",
 	}
 }
 
@@ -24,24 +55,110 @@ func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
 		prefix = prefix + "."
 	}
 
-	fs.StringVar(&o.Eventer, prefix+"eventer", o.Eventer, "The eventing subsystem to use.  Either stdout or kafka.")
+	fs.StringVar(&o.Eventer, prefix+"eventer", o.Eventer, "The eventing subsystem to use.  Either stdout, file, kafka, jetstream, webhook or subscriptions.")
+	fs.StringSliceVar(&o.Eventers, prefix+"eventers", o.Eventers, "The eventing subsystems to send every event with, like kafka,webhook.  Overrides eventer.")
+	fs.StringVar(&o.Source, prefix+"source", o.Source, "The CloudEvents source of the events.  Defaults to urn:common-inventory:<hostname>.")
 
 	o.Kafka.AddFlags(fs, prefix+"kafka")
+	o.JetStream.AddFlags(fs, prefix+"jetstream")
+	o.Webhook.AddFlags(fs, prefix+"webhook")
+	o.File.AddFlags(fs, prefix+"file")
+	o.Subscriptions.AddFlags(fs, prefix+"subscriptions")
+	o.Delivery.AddFlags(fs, prefix+"delivery")
 }
 
 func (o *Options) Complete() []error {
-	return nil
+	var errs []error
+
+	if len(o.Eventers) == 0 {
+		o.Eventers = []string{o.Eventer}
+	}
+
+	if o.Source == "" {
+		if host, err := os.Hostname(); err == nil {
+			o.Source = "urn:common-inventory:" + host
+		} else {
+			errs = append(errs, fmt.Errorf("eventing.source isn't set and the hostname is unknown: %w", err))
+		}
+	}
+
+	errs = append(errs, o.Delivery.Complete()...)
+	for _, p := range o.Policies {
+		errs = append(errs, p.Complete()...)
+	}
+
+	return errs
 }
 
 func (o *Options) Validate() []error {
 	var errs []error
-	if o.Eventer != "stdout" && o.Eventer != "kafka" {
-		errs = append(errs, errors.New("eventer must be either stdout or kafka"))
+
+	for i, e := range o.Eventers {
+		if !slices.Contains(Eventers, e) {
+			errs = append(errs, fmt.Errorf("invalid eventer %s.  Options are stdout, file, kafka, jetstream, webhook and subscriptions", e))
+		} else if slices.Index(o.Eventers, e) != i {
+			errs = append(errs, fmt.Errorf("the eventer %s is listed more than once", e))
+		}
+	}
+
+	if _, err := url.Parse(o.Source); err != nil || o.Source == "" {
+		errs = append(errs, fmt.Errorf("eventing.source must be a URI reference: %s", o.Source))
 	}
 
-	if o.Eventer == "kafka" {
+	if o.uses("kafka") {
 		errs = append(errs, o.Kafka.Validate()...)
 	}
 
+	if o.uses("jetstream") {
+		errs = append(errs, o.JetStream.Validate()...)
+	}
+
+	if o.uses("webhook") {
+		errs = append(errs, o.Webhook.Validate()...)
+	}
+
+	if o.uses("file") {
+		errs = append(errs, o.File.Validate()...)
+	}
+
+	if o.uses("subscriptions") {
+		errs = append(errs, o.Subscriptions.Validate()...)
+	}
+
+	errs = append(errs, o.Delivery.Validate()...)
+	for name, p := range o.Policies {
+		if !o.uses(name) {
+			errs = append(errs, fmt.Errorf("there's a policy for %s, which isn't one of the eventers", name))
+		}
+		for _, err := range p.Validate() {
+			errs = append(errs, fmt.Errorf("the policy of %s: %w", name, err))
+		}
+	}
+
+	// the spools can't share a file
+	spoolers := map[string]string{}
+	for _, e := range o.Eventers {
+		p := o.Policy(e)
+		if p.FailurePolicy != delivery.Spool {
+			continue
+		}
+		if other, ok := spoolers[p.SpoolFile]; ok {
+			errs = append(errs, fmt.Errorf("%s and %s can't use the same spool-file %s", other, e, p.SpoolFile))
+		}
+		spoolers[p.SpoolFile] = e
+	}
+
 	return errs
 }
+
+// Policy returns the failure policy of the eventer.
+func (o *Options) Policy(eventer string) *delivery.Options {
+	if p, ok := o.Policies[eventer]; ok {
+		return p
+	}
+	return o.Delivery
+}
+
+func (o *Options) uses(eventer string) bool {
+	return slices.Contains(o.Eventers, eventer)
+}
@@ -11,19 +11,26 @@ import (
 )
 
 type StdOutManager struct {
+	Source  string
 	Encoder *json.Encoder
 	Errors  chan error
 }
 
-func New() (*StdOutManager, error) {
+func New(source string) (*StdOutManager, error) {
 	return &StdOutManager{
+		Source:  source,
 		Encoder: json.NewEncoder(os.Stdout),
 		Errors:  make(chan error),
 	}, nil
 }
 
+// Produce writes the event as a structured mode CloudEvent.
 func (p *StdOutManager) Produce(ctx context.Context, event *api.Event) error {
-	return p.Encoder.Encode(event)
+	e, err := api.NewCloudEvent(p.Source, event)
+	if err != nil {
+		return err
+	}
+	return p.Encoder.Encode(e)
 }
 
 func (m *StdOutManager) Errs() <-chan error {
@@ -35,6 +42,14 @@ func (m *StdOutManager) Lookup(identity *authnapi.Identity, resource *models.Res
 	return m, nil
 }
 
+func (m *StdOutManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return m, nil
+}
+
+func (m *StdOutManager) Ready() error {
+	return nil
+}
+
 func (m *StdOutManager) Shutdown(ctx context.Context) error {
 	return nil
 }
@@ -0,0 +1,48 @@
+package subscriptions
+
+import (
+	"time"
+)
+
+type Config struct {
+	*Options
+}
+
+type completedConfig struct {
+	PollInterval  time.Duration
+	Timeout       time.Duration
+	RetryAttempts int
+	RetryBackoff  time.Duration
+	BatchSize     int
+	Workers       int
+
+	// Targets are the addresses callbacks may be sent to.
+	Targets *Targets
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	targets, err := NewTargets(c.AllowedNetworks)
+	if err != nil {
+		return CompletedConfig{}, err
+	}
+
+	return CompletedConfig{&completedConfig{
+		PollInterval:  c.PollInterval,
+		Timeout:       c.Timeout,
+		RetryAttempts: c.RetryAttempts,
+		RetryBackoff:  c.RetryBackoff,
+		BatchSize:     c.BatchSize,
+		Workers:       c.Workers,
+		Targets:       targets,
+	}}, nil
+}
@@ -0,0 +1,311 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gorm.io/gorm"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/eventing/webhook"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// SubscriptionManager records a delivery for every subscription that matches an event and sends the deliveries
+// that are due.  The deliveries are in the database, so retries and redeliveries survive restarts, and several
+// servers can share them: a server claims a delivery by moving its next attempt past the timeout before sending
+// it.
+type SubscriptionManager struct {
+	Config CompletedConfig
+	Source string
+	Db     *gorm.DB
+	Client *http.Client
+	Errors *api.ErrorReporter
+	Log    *slog.Logger
+
+	nudge   chan struct{}
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func New(config CompletedConfig, db *gorm.DB, source string, log *slog.Logger) (*SubscriptionManager, error) {
+	m := &SubscriptionManager{
+		Config: config,
+		Source: source,
+		Db:     db,
+		Client: &http.Client{Transport: config.Targets.Transport()},
+		Errors: api.NewErrorReporter(log),
+		Log:    log,
+		nudge:  make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	m.stopped.Add(1)
+	go m.run()
+
+	return m, nil
+}
+
+func (m *SubscriptionManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return &producer{Manager: m, Resource: resource}, nil
+}
+
+// LookupAll returns a producer for every subscription to the event's type, whatever its other filters are.
+func (m *SubscriptionManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return &producer{Manager: m, All: true}, nil
+}
+
+func (m *SubscriptionManager) Errs() <-chan error {
+	return m.Errors.Errs()
+}
+
+func (m *SubscriptionManager) Ready() error {
+	return nil
+}
+
+// Shutdown waits for the deliveries being sent.  The rest are sent by the next server to start.
+func (m *SubscriptionManager) Shutdown(ctx context.Context) error {
+	m.Errors.Stop()
+	close(m.stop)
+
+	done := make(chan struct{})
+	go func() {
+		m.stopped.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type producer struct {
+	Manager  *SubscriptionManager
+	Resource *models.Resource
+
+	// All sends the event to every subscription to its type.
+	All bool
+}
+
+// Produce records a pending delivery of the event for each matching subscription.
+func (p *producer) Produce(ctx context.Context, event *api.Event) error {
+	m := p.Manager
+
+	e, err := api.NewCloudEvent(m.Source, event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// TODO: filter in the database if there are ever enough subscriptions for it to matter
+	var subscriptions []models.Subscription
+	if err := m.Db.WithContext(ctx).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []models.Delivery
+	for _, s := range subscriptions {
+		if p.All {
+			if !matches(s.EventTypes, event.Type) {
+				continue
+			}
+		} else if !Matches(&s, event, p.Resource) {
+			continue
+		}
+		deliveries = append(deliveries, models.Delivery{
+			SubscriptionID: s.ID,
+			EventId:        e.ID(),
+			EventType:      e.Type(),
+			Subject:        e.Subject(),
+			Event:          string(body),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  &now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := m.Db.WithContext(ctx).Create(&deliveries).Error; err != nil {
+		return err
+	}
+
+	select {
+	case m.nudge <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Matches reports whether the event about the resource matches every one of the subscription's non-empty filters.
+func Matches(s *models.Subscription, event *api.Event, resource *models.Resource) bool {
+	workspace := ""
+	if resource != nil && resource.Workspace != nil {
+		workspace = *resource.Workspace
+	}
+
+	return matches(s.ResourceTypes, event.ResourceType) &&
+		matches(s.Workspaces, workspace) &&
+		matches(s.EventTypes, event.Type)
+}
+
+func matches(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, value)
+}
+
+// run sends the due deliveries every poll interval and right after new ones are recorded.
+func (m *SubscriptionManager) run() {
+	defer m.stopped.Done()
+	ticker := time.NewTicker(m.Config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.nudge:
+		case <-m.stop:
+			return
+		}
+		m.dispatch()
+	}
+}
+
+// dispatch sends batches of due deliveries until there are none left or the manager is stopped.
+func (m *SubscriptionManager) dispatch() {
+	for {
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+
+		var due []models.Delivery
+		err := m.Db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now().UTC()).
+			Order("id").Limit(m.Config.BatchSize).Find(&due).Error
+		if err != nil {
+			m.Log.Error(fmt.Sprintf("Failed to load the due deliveries: %v", err))
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		sem := make(chan struct{}, m.Config.Workers)
+		var wg sync.WaitGroup
+		for i := range due {
+			d := &due[i]
+			if !m.claim(d) {
+				continue
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				m.attempt(d)
+			}()
+		}
+		wg.Wait()
+
+		if len(due) < m.Config.BatchSize {
+			return
+		}
+	}
+}
+
+// claim moves the delivery's next attempt past the time it takes to send it, so no other server sends it too.  It
+// returns false if another server claimed it first.
+func (m *SubscriptionManager) claim(d *models.Delivery) bool {
+	now := time.Now().UTC()
+	lease := now.Add(2 * m.Config.Timeout)
+	result := m.Db.Model(&models.Delivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", d.ID, models.DeliveryPending, now).
+		Update("next_attempt_at", lease)
+	if result.Error != nil {
+		m.Log.Error(fmt.Sprintf("Failed to claim delivery %d: %v", d.ID, result.Error))
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// attempt sends the delivery and records the outcome.  Failed attempts are retried with backoff until there have
+// been as many as configured or the subscriber rejects the event.
+func (m *SubscriptionManager) attempt(d *models.Delivery) {
+	sendErr := m.send(d)
+
+	now := time.Now().UTC()
+	update := map[string]any{
+		"attempts":        d.Attempts + 1,
+		"last_attempt_at": now,
+	}
+
+	var rejected *webhook.RejectedError
+	switch {
+	case sendErr == nil:
+		update["status"] = models.DeliveryDelivered
+		update["delivered_at"] = now
+		update["last_error"] = ""
+		update["next_attempt_at"] = nil
+	case errors.As(sendErr, &rejected) || d.Attempts+1 >= m.Config.RetryAttempts:
+		update["status"] = models.DeliveryFailed
+		update["last_error"] = sendErr.Error()
+		update["next_attempt_at"] = nil
+	default:
+		update["last_error"] = sendErr.Error()
+		update["next_attempt_at"] = now.Add(m.Config.RetryBackoff << d.Attempts)
+	}
+
+	if err := m.Db.Model(d).Updates(update).Error; err != nil {
+		m.Log.Error(fmt.Sprintf("Failed to record the outcome of delivery %d: %v", d.ID, err))
+	}
+
+	if update["status"] == models.DeliveryFailed {
+		m.Log.Error(fmt.Sprintf("Delivery %d to subscription %d failed: %v", d.ID, d.SubscriptionID, sendErr))
+		m.Errors.Report(api.Transient(&api.DeliveryError{Destination: fmt.Sprintf("subscription %d", d.SubscriptionID), Err: sendErr}))
+	} else if sendErr != nil {
+		m.Log.Warn(fmt.Sprintf("Delivery %d to subscription %d failed, retrying: %v", d.ID, d.SubscriptionID, sendErr))
+	}
+}
+
+func (m *SubscriptionManager) send(d *models.Delivery) error {
+	var s models.Subscription
+	if err := m.Db.First(&s, d.SubscriptionID).Error; err != nil {
+		return fmt.Errorf("failed to load the subscription: %w", err)
+	}
+
+	var e cloudevents.Event
+	if err := json.Unmarshal([]byte(d.Event), &e); err != nil {
+		return fmt.Errorf("failed to read the event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.Config.Timeout)
+	defer cancel()
+
+	req, err := webhook.NewRequest(ctx, s.CallbackURL, s.Secret, webhook.Structured, e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	return webhook.CheckResponse(resp)
+}
@@ -0,0 +1,77 @@
+package subscriptions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	PollInterval  time.Duration `mapstructure:"poll-interval"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	RetryAttempts int           `mapstructure:"retry-attempts"`
+	RetryBackoff  time.Duration `mapstructure:"retry-backoff"`
+	BatchSize     int           `mapstructure:"batch-size"`
+	Workers       int           `mapstructure:"workers"`
+
+	// AllowedNetworks are the private networks callbacks may be sent to, like 10.1.0.0/16.  Loopback, private and
+	// link-local addresses are refused otherwise.
+	AllowedNetworks []string `mapstructure:"allowed-networks"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		PollInterval:  time.Second,
+		Timeout:       10 * time.Second,
+		RetryAttempts: 5,
+		RetryBackoff:  time.Second,
+		BatchSize:     100,
+		Workers:       4,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.DurationVar(&o.PollInterval, prefix+"poll-interval", o.PollInterval, "How often to look for deliveries that are due, like retries and redeliveries.")
+	fs.DurationVar(&o.Timeout, prefix+"timeout", o.Timeout, "How long to wait for a subscriber to accept an event.")
+	fs.IntVar(&o.RetryAttempts, prefix+"retry-attempts", o.RetryAttempts, "How many times to try a delivery before it fails.")
+	fs.DurationVar(&o.RetryBackoff, prefix+"retry-backoff", o.RetryBackoff, "How long to wait before the first retry.  The wait doubles after each one.")
+	fs.IntVar(&o.BatchSize, prefix+"batch-size", o.BatchSize, "How many due deliveries to load at a time.")
+	fs.IntVar(&o.Workers, prefix+"workers", o.Workers, "How many deliveries to send at the same time.")
+	fs.StringSliceVar(&o.AllowedNetworks, prefix+"allowed-networks", o.AllowedNetworks, "The loopback, private or link-local networks callbacks may be sent to, like 10.1.0.0/16.  Callbacks to the rest are refused.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if o.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("the subscriptions poll-interval must be positive: %s", o.PollInterval))
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("the subscriptions timeout must be positive: %s", o.Timeout))
+	}
+	if o.RetryAttempts < 1 {
+		errs = append(errs, fmt.Errorf("the subscriptions retry-attempts must be at least 1: %d", o.RetryAttempts))
+	}
+	if o.RetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("the subscriptions retry-backoff must be positive: %s", o.RetryBackoff))
+	}
+	if o.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("the subscriptions batch-size must be at least 1: %d", o.BatchSize))
+	}
+	if o.Workers < 1 {
+		errs = append(errs, fmt.Errorf("the subscriptions workers must be at least 1: %d", o.Workers))
+	}
+	if _, err := NewTargets(o.AllowedNetworks); err != nil {
+		errs = append(errs, fmt.Errorf("the subscriptions allowed-networks: %w", err))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
@@ -0,0 +1,107 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Targets decides which addresses callbacks may be sent to.  Anyone who may list resources can register a
+// callback, so loopback, private, link-local, unspecified and multicast addresses are refused unless an admin
+// allowed their network.  Otherwise a subscription could make the server reach services that are only meant to be
+// reached from inside.  A nil Targets allows no such networks.
+type Targets struct {
+	Allowed []*net.IPNet
+}
+
+// NewTargets parses the allowed networks, which are CIDRs like 10.1.0.0/16.
+func NewTargets(allowed []string) (*Targets, error) {
+	t := &Targets{}
+	for _, cidr := range allowed {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %s: %w", cidr, err)
+		}
+		t.Allowed = append(t.Allowed, network)
+	}
+	return t, nil
+}
+
+// Allows returns true for public addresses and the addresses in the allowed networks.
+func (t *Targets) Allows(ip net.IP) bool {
+	if t != nil {
+		for _, n := range t.Allowed {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// CheckURL returns an error if the host of the callback URL is or resolves to an address that isn't allowed.  A
+// host that can't be resolved yet passes, since the dialer checks the address again for every delivery.
+func (t *Targets) CheckURL(ctx context.Context, callback string) error {
+	u, err := url.Parse(callback)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if !t.Allows(ip) {
+			if ip.String() != host {
+				return fmt.Errorf("%s resolves to %s, which is a loopback, private, link-local or multicast address that isn't allowed", host, ip)
+			}
+			return fmt.Errorf("%s is a loopback, private, link-local or multicast address that isn't allowed", ip)
+		}
+	}
+	return nil
+}
+
+// Transport returns an HTTP transport that only connects to allowed addresses.  The address is checked after it's
+// resolved, right before connecting, so a name that's changed to point inside since the subscription was created,
+// or a redirect, can't get around the check.  Proxies from the environment aren't used, since they'd be checked
+// instead of the callback.
+func (t *Targets) Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !t.Allows(ip) {
+				return fmt.Errorf("callbacks may not connect to %s", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
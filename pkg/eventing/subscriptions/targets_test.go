@@ -0,0 +1,114 @@
+package subscriptions
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAllows(t *testing.T) {
+	targets, err := NewTargets([]string{"10.1.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"10.1.2.3", true},
+		{"10.2.0.1", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := targets.Allows(net.ParseIP(tt.ip)); got != tt.allowed {
+			t.Errorf("%s: expected allowed %t, got %t", tt.ip, tt.allowed, got)
+		}
+	}
+
+	var none *Targets
+	if none.Allows(net.ParseIP("10.1.2.3")) {
+		t.Error("nil targets shouldn't allow private addresses")
+	}
+}
+
+func TestNewTargetsRejectsInvalidNetworks(t *testing.T) {
+	if _, err := NewTargets([]string{"10.1.0.0"}); err == nil {
+		t.Error("expected an error for an address without a prefix length")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	var targets *Targets
+	ctx := context.Background()
+
+	for _, u := range []string{
+		"http://127.0.0.1:8080/events",
+		"http://[::1]/events",
+		"http://169.254.169.254/latest/meta-data",
+		"https://localhost/events",
+	} {
+		if err := targets.CheckURL(ctx, u); err == nil {
+			t.Errorf("expected %s to be refused", u)
+		}
+	}
+
+	if err := targets.CheckURL(ctx, "https://93.184.216.34/events"); err != nil {
+		t.Errorf("expected a public address to be allowed, got %v", err)
+	}
+}
+
+func TestTransportChecksTheDialedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var refused *Targets
+	resp, err := (&http.Client{Transport: refused.Transport()}).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the connection to a loopback address to be refused")
+	}
+
+	allowed, err := NewTargets([]string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = (&http.Client{Transport: allowed.Transport()}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected an allowed network to be reachable, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTransportChecksRedirects(t *testing.T) {
+	// the callback's address is allowed, but it redirects to one that isn't
+	callback := httptest.NewServer(http.RedirectHandler("http://127.0.0.2/", http.StatusFound))
+	defer callback.Close()
+
+	targets, err := NewTargets([]string{"127.0.0.1/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: targets.Transport()}).Get(callback.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the redirect to be refused")
+	}
+	if !strings.Contains(err.Error(), "callbacks may not connect to 127.0.0.2") {
+		t.Errorf("expected the dialer to refuse the address, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	*Options
+}
+
+type completedConfig struct {
+	Subscribers    []*Subscriber
+	Timeout        time.Duration
+	RetryAttempts  int
+	RetryBackoff   time.Duration
+	QueueSize      int
+	DeadLetterFile string
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+// Complete reads the subscribers' secret files and gives them the default mode.
+func (c *Config) Complete() (CompletedConfig, error) {
+	var subscribers []*Subscriber
+	for _, s := range c.Subscribers {
+		sub := *s
+		if sub.Mode == "" {
+			sub.Mode = c.Mode
+		}
+		if sub.SecretFile != "" {
+			b, err := os.ReadFile(sub.SecretFile)
+			if err != nil {
+				return CompletedConfig{}, fmt.Errorf("failed to read the secret of webhook subscriber %s: %w", sub.Name, err)
+			}
+			sub.Secret = strings.TrimSpace(string(b))
+		}
+		subscribers = append(subscribers, &sub)
+	}
+
+	return CompletedConfig{&completedConfig{
+		Subscribers:    subscribers,
+		Timeout:        c.Timeout,
+		RetryAttempts:  c.RetryAttempts,
+		RetryBackoff:   c.RetryBackoff,
+		QueueSize:      c.QueueSize,
+		DeadLetterFile: c.DeadLetterFile,
+	}}, nil
+}
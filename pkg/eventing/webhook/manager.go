@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// WebhookManager POSTs every event to every subscriber.  Each subscriber has a queue and a goroutine, so a slow
+// subscriber doesn't hold up the others and each gets the events in order.  Events that can't be delivered after
+// the retries are dead-lettered and reported on Errs.
+type WebhookManager struct {
+	Config CompletedConfig
+	Source string
+	Client *http.Client
+	Errors *api.ErrorReporter
+	Log    *slog.Logger
+
+	workers     []*worker
+	deadLetters *deadLetters
+
+	// mu guards closed, so Produce doesn't send on a closed queue
+	mu           sync.RWMutex
+	closed       bool
+	stopped      sync.WaitGroup
+	shutdownOnce sync.Once
+	shutdownErr  error
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+type worker struct {
+	Subscriber *Subscriber
+	Queue      chan cloudevents.Event
+
+	// err is why the last attempt to deliver to the subscriber failed.  It's nil once one succeeds or the queue is
+	// empty.
+	mu  sync.Mutex
+	err error
+}
+
+func (w *worker) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+func (w *worker) getErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func New(config CompletedConfig, source string, log *slog.Logger) (*WebhookManager, error) {
+	m := &WebhookManager{
+		Config: config,
+		Source: source,
+		Client: &http.Client{},
+		Errors: api.NewErrorReporter(log),
+		Log:    log,
+	}
+
+	if config.DeadLetterFile != "" {
+		d, err := openDeadLetters(config.DeadLetterFile)
+		if err != nil {
+			return nil, err
+		}
+		m.deadLetters = d
+	}
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	for _, s := range config.Subscribers {
+		w := &worker{Subscriber: s, Queue: make(chan cloudevents.Event, config.QueueSize)}
+		m.workers = append(m.workers, w)
+
+		m.stopped.Add(1)
+		go m.run(w)
+	}
+
+	return m, nil
+}
+
+// Lookup returns the manager, since every subscriber gets every event.
+func (m *WebhookManager) Lookup(identity *authnapi.Identity, resource *models.Resource) (api.Producer, error) {
+	return m, nil
+}
+
+func (m *WebhookManager) LookupAll(identity *authnapi.Identity) (api.Producer, error) {
+	return m, nil
+}
+
+// Produce queues the event for every subscriber.  It's dead-lettered for the subscribers whose queues are full.
+func (m *WebhookManager) Produce(ctx context.Context, event *api.Event) error {
+	e, err := api.NewCloudEvent(m.Source, event)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return errors.New("the webhook eventer is shut down")
+	}
+
+	for _, w := range m.workers {
+		select {
+		case w.Queue <- e:
+		default:
+			m.fail(w.Subscriber, e, errors.New("the queue is full"))
+		}
+	}
+	return nil
+}
+
+func (m *WebhookManager) Errs() <-chan error {
+	return m.Errors.Errs()
+}
+
+// Ready returns an error naming the subscribers whose last delivery attempt failed while they have events to
+// deliver.
+func (m *WebhookManager) Ready() error {
+	var errs []error
+	for _, w := range m.workers {
+		if err := w.getErr(); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s is failing: %w", w.Subscriber.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown delivers the queued events.  The ones left when the context is done are dead-lettered.  Only the first
+// call shuts the manager down, and later ones return its error.
+func (m *WebhookManager) Shutdown(ctx context.Context) error {
+	m.shutdownOnce.Do(func() {
+		m.shutdownErr = m.shutdown(ctx)
+	})
+	return m.shutdownErr
+}
+
+func (m *WebhookManager) shutdown(ctx context.Context) error {
+	m.Errors.Stop()
+	m.mu.Lock()
+	m.closed = true
+	for _, w := range m.workers {
+		close(w.Queue)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.stopped.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		m.cancel()
+		<-done
+	}
+	m.cancel()
+
+	if m.deadLetters != nil {
+		return m.deadLetters.Close()
+	}
+	return nil
+}
+
+func (m *WebhookManager) run(w *worker) {
+	defer m.stopped.Done()
+	for e := range w.Queue {
+		if err := m.deliver(w, e); err != nil {
+			m.fail(w.Subscriber, e, err)
+			if len(w.Queue) == 0 {
+				// nothing is waiting for the subscriber, so it doesn't keep the server from being ready until
+				// the next event comes in
+				w.setErr(nil)
+			}
+		}
+	}
+}
+
+// deliver tries up to the configured number of times, doubling the wait after each failure.  Responses that mean
+// the request itself is wrong aren't retried.
+func (m *WebhookManager) deliver(w *worker, e cloudevents.Event) error {
+	s := w.Subscriber
+	backoff := m.Config.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := m.send(s, e)
+		w.setErr(err)
+		if err == nil {
+			return nil
+		}
+
+		var rejected *RejectedError
+		if errors.As(err, &rejected) || attempt >= m.Config.RetryAttempts {
+			return err
+		}
+
+		m.Log.Warn(fmt.Sprintf("Failed to deliver event %s to webhook %s, retrying in %s: %v", e.ID(), s.Name, backoff, err))
+		select {
+		case <-time.After(backoff):
+		case <-m.ctx.Done():
+			return fmt.Errorf("gave up on shutdown: %w", err)
+		}
+		backoff *= 2
+	}
+}
+
+func (m *WebhookManager) send(s *Subscriber, e cloudevents.Event) error {
+	ctx, cancel := context.WithTimeout(m.ctx, m.Config.Timeout)
+	defer cancel()
+
+	req, err := NewRequest(ctx, s.URL, s.Secret, s.Mode, e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	return CheckResponse(resp)
+}
+
+// fail dead-letters the event and reports it on Errs.
+func (m *WebhookManager) fail(s *Subscriber, e cloudevents.Event, err error) {
+	m.Log.Error(fmt.Sprintf("Failed to deliver event %s to webhook %s: %v", e.ID(), s.Name, err))
+
+	if m.deadLetters != nil {
+		if dlErr := m.deadLetters.Append(s.Name, e, err); dlErr != nil {
+			m.Log.Error(fmt.Sprintf("Failed to dead-letter event %s: %v", e.ID(), dlErr))
+		}
+	}
+
+	m.Errors.Report(api.Transient(&api.DeliveryError{Destination: s.Name, Err: err}))
+}
+
+// deadLetters is a file with one json document per undelivered event.
+type deadLetters struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+type deadLetter struct {
+	Subscriber string            `json:"subscriber"`
+	Error      string            `json:"error"`
+	Time       time.Time         `json:"time"`
+	Event      cloudevents.Event `json:"event"`
+}
+
+func openDeadLetters(path string) (*deadLetters, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the dead letter file %s: %w", path, err)
+	}
+	return &deadLetters{file: f}, nil
+}
+
+func (d *deadLetters) Append(subscriber string, e cloudevents.Event, err error) error {
+	b, mErr := json.Marshal(&deadLetter{Subscriber: subscriber, Error: err.Error(), Time: time.Now().UTC(), Event: e})
+	if mErr != nil {
+		return mErr
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return d.file.Sync()
+}
+
+func (d *deadLetters) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
@@ -0,0 +1,348 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/csams/common-inventory/pkg/eventing/api"
+)
+
+const secret = "s3cr3t"
+
+// request is what a test subscriber got.
+type request struct {
+	Header http.Header
+	Body   []byte
+	Time   time.Time
+}
+
+// subscriber is a test webhook that responds with the statuses in turn and then 200.
+type subscriber struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+	received chan struct{}
+}
+
+func newSubscriber(t *testing.T, statuses ...int) *subscriber {
+	s := &subscriber{statuses: statuses, received: make(chan struct{}, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, request{Header: r.Header.Clone(), Body: body, Time: time.Now()})
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+		s.received <- struct{}{}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *subscriber) Requests() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]request(nil), s.requests...)
+}
+
+func newManager(t *testing.T, url string, mode string, attempts int) *WebhookManager {
+	m, err := New(CompletedConfig{&completedConfig{
+		Subscribers:    []*Subscriber{{Name: "test", URL: url, Secret: secret, Mode: mode}},
+		Timeout:        time.Second,
+		RetryAttempts:  attempts,
+		RetryBackoff:   20 * time.Millisecond,
+		QueueSize:      10,
+		DeadLetterFile: filepath.Join(t.TempDir(), "dead-letters.jsonl"),
+	}}, "urn:test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	return m
+}
+
+func testEvent() *api.Event {
+	return &api.Event{
+		Type:    api.ResourceCreated,
+		Subject: "/api/inventory/v1alpha1/resources/hosts/1",
+		Key:     "1",
+		Data:    map[string]any{"resource_id": "1"},
+	}
+}
+
+// shutdown waits for the queued events to be delivered or dead-lettered.
+func shutdown(t *testing.T, m *WebhookManager) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readDeadLetters(t *testing.T, m *WebhookManager) []deadLetter {
+	f, err := os.Open(m.Config.DeadLetterFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var letters []deadLetter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d deadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, d)
+	}
+	return letters
+}
+
+// checkSignature recomputes the signature of the request rather than calling Sign.
+func checkSignature(t *testing.T, r request) {
+	t.Helper()
+	timestamp := r.Header.Get(TimestampHeader)
+	if timestamp == "" {
+		t.Fatal("the request has no timestamp")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + string(r.Body)))
+	if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(SignatureHeader) != expected {
+		t.Errorf("expected the signature %s, got %s", expected, r.Header.Get(SignatureHeader))
+	}
+}
+
+func TestStructuredMode(t *testing.T) {
+	s := newSubscriber(t)
+	m := newManager(t, s.URL, Structured, 1)
+
+	if err := m.Produce(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, m)
+
+	requests := s.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	r := requests[0]
+	checkSignature(t, r)
+
+	if ct := r.Header.Get("Content-Type"); ct != "application/cloudevents+json" {
+		t.Errorf("expected a structured event, got the content type %s", ct)
+	}
+	if r.Header.Get("Ce-Id") != "" {
+		t.Error("a structured event shouldn't have ce- headers")
+	}
+
+	var e struct {
+		ID      string         `json:"id"`
+		Type    string         `json:"type"`
+		Source  string         `json:"source"`
+		Subject string         `json:"subject"`
+		Data    map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(r.Body, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == "" || e.Type != api.ResourceCreated || e.Source != "urn:test" || e.Subject != testEvent().Subject {
+		t.Errorf("unexpected event attributes: %+v", e)
+	}
+	if e.Data["resource_id"] != "1" {
+		t.Errorf("unexpected data: %v", e.Data)
+	}
+}
+
+func TestBinaryMode(t *testing.T) {
+	s := newSubscriber(t)
+	m := newManager(t, s.URL, Binary, 1)
+
+	if err := m.Produce(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, m)
+
+	requests := s.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	r := requests[0]
+	checkSignature(t, r)
+
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected the data's content type, got %s", ct)
+	}
+	if r.Header.Get("Ce-Id") == "" || r.Header.Get("Ce-Type") != api.ResourceCreated || r.Header.Get("Ce-Subject") != testEvent().Subject {
+		t.Errorf("unexpected ce- headers: %v", r.Header)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(r.Body, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["resource_id"] != "1" {
+		t.Errorf("expected the body to be the data, got %s", r.Body)
+	}
+}
+
+func TestRetriesWithBackoff(t *testing.T) {
+	s := newSubscriber(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	m := newManager(t, s.URL, Structured, 3)
+
+	if err := m.Produce(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, m)
+
+	requests := s.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(requests))
+	}
+	if gap := requests[1].Time.Sub(requests[0].Time); gap < 20*time.Millisecond {
+		t.Errorf("expected the first retry after the backoff, got %s", gap)
+	}
+	if gap := requests[2].Time.Sub(requests[1].Time); gap < 40*time.Millisecond {
+		t.Errorf("expected the backoff to double, got %s", gap)
+	}
+	if letters := readDeadLetters(t, m); len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %d", len(letters))
+	}
+	if err := m.Ready(); err != nil {
+		t.Errorf("expected the subscriber to be ready after a delivery, got %v", err)
+	}
+}
+
+func TestRejectionsAreNotRetried(t *testing.T) {
+	s := newSubscriber(t, http.StatusBadRequest)
+	m := newManager(t, s.URL, Structured, 3)
+
+	if err := m.Produce(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, m)
+
+	if requests := s.Requests(); len(requests) != 1 {
+		t.Fatalf("expected 1 attempt, got %d", len(requests))
+	}
+	letters := readDeadLetters(t, m)
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	s := newSubscriber(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	m := newManager(t, s.URL, Structured, 2)
+
+	if err := m.Produce(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	shutdown(t, m)
+
+	if requests := s.Requests(); len(requests) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(requests))
+	}
+
+	letters := readDeadLetters(t, m)
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	d := letters[0]
+	if d.Subscriber != "test" || d.Error == "" || d.Event.Subject() != testEvent().Subject {
+		t.Errorf("unexpected dead letter: %+v", d)
+	}
+
+	select {
+	case err := <-m.Errs():
+		var delivery *api.DeliveryError
+		if !errors.As(err, &delivery) || delivery.Destination != "test" || api.IsFatal(err) {
+			t.Errorf("expected a transient delivery error for the subscriber, got %v", err)
+		}
+	default:
+		t.Error("the failure wasn't reported")
+	}
+}
+
+func TestFullQueuesAreDeadLettered(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+	defer close(block)
+
+	m := newManager(t, server.URL, Structured, 1)
+	// the first event is taken off the queue, then it holds 10
+	for i := 0; i < 12; i++ {
+		if err := m.Produce(context.Background(), testEvent()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	letters := readDeadLetters(t, m)
+	if len(letters) == 0 {
+		t.Fatal("expected the events that didn't fit to be dead-lettered")
+	}
+	if letters[0].Error != "the queue is full" {
+		t.Errorf("unexpected error: %s", letters[0].Error)
+	}
+}
+
+func TestReadyReportsFailingSubscribers(t *testing.T) {
+	s := newSubscriber(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	m := newManager(t, s.URL, Structured, 5)
+
+	if err := m.Ready(); err != nil {
+		t.Fatalf("expected a new manager to be ready, got %v", err)
+	}
+	if err := m.Produce(context.Background(), testEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	<-s.received
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Ready() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := m.Ready(); err == nil {
+		t.Error("expected the failing subscriber to make the manager unready")
+	}
+
+	shutdown(t, m)
+	if err := m.Ready(); err != nil {
+		t.Errorf("expected the manager to be ready once the event was delivered, got %v", err)
+	}
+}
+
+func TestShutdownTwice(t *testing.T) {
+	s := newSubscriber(t)
+	m := newManager(t, s.URL, Structured, 1)
+
+	shutdown(t, m)
+	shutdown(t, m)
+
+	if err := m.Produce(context.Background(), testEvent()); err == nil {
+		t.Error("expected Produce to fail after shutdown")
+	}
+}
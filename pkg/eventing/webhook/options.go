@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// Subscribers get every event.  They can only be set in the config file.
+	Subscribers []*Subscriber `mapstructure:"subscribers"`
+
+	Mode           string        `mapstructure:"mode"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+	RetryAttempts  int           `mapstructure:"retry-attempts"`
+	RetryBackoff   time.Duration `mapstructure:"retry-backoff"`
+	QueueSize      int           `mapstructure:"queue-size"`
+	DeadLetterFile string        `mapstructure:"dead-letter-file"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Mode:          Structured,
+		Timeout:       10 * time.Second,
+		RetryAttempts: 5,
+		RetryBackoff:  time.Second,
+		QueueSize:     1000,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.StringVar(&o.Mode, prefix+"mode", o.Mode, "How subscribers get the CloudEvents unless they say otherwise.  Either binary or structured.")
+	fs.DurationVar(&o.Timeout, prefix+"timeout", o.Timeout, "How long to wait for a subscriber to accept an event.")
+	fs.IntVar(&o.RetryAttempts, prefix+"retry-attempts", o.RetryAttempts, "How many times to try to deliver an event to a subscriber before dead-lettering it.")
+	fs.DurationVar(&o.RetryBackoff, prefix+"retry-backoff", o.RetryBackoff, "How long to wait before the first retry.  The wait doubles after each one.")
+	fs.IntVar(&o.QueueSize, prefix+"queue-size", o.QueueSize, "How many events each subscriber can have waiting before new ones are dead-lettered.")
+	fs.StringVar(&o.DeadLetterFile, prefix+"dead-letter-file", o.DeadLetterFile, "The file that gets the events that couldn't be delivered.  They're only logged if it isn't set.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if len(o.Subscribers) == 0 {
+		errs = append(errs, fmt.Errorf("the webhook eventer needs at least one subscriber"))
+	}
+
+	names := map[string]bool{}
+	for i, s := range o.Subscribers {
+		for _, err := range s.Validate() {
+			errs = append(errs, fmt.Errorf("webhook subscribers[%d]: %w", i, err))
+		}
+		if names[s.Name] {
+			errs = append(errs, fmt.Errorf("webhook subscribers[%d]: the name %s is already used", i, s.Name))
+		}
+		names[s.Name] = true
+	}
+
+	if err := validateMode(o.Mode); err != nil {
+		errs = append(errs, err)
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("the webhook timeout must be positive: %s", o.Timeout))
+	}
+	if o.RetryAttempts < 1 {
+		errs = append(errs, fmt.Errorf("the webhook retry-attempts must be at least 1: %d", o.RetryAttempts))
+	}
+	if o.RetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("the webhook retry-backoff must be positive: %s", o.RetryBackoff))
+	}
+	if o.QueueSize < 1 {
+		errs = append(errs, fmt.Errorf("the webhook queue-size must be at least 1: %d", o.QueueSize))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
+
+func validateMode(mode string) error {
+	if mode != Binary && mode != Structured {
+		return fmt.Errorf("invalid webhook mode %s.  Options are binary and structured", mode)
+	}
+	return nil
+}
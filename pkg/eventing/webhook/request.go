@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+)
+
+// NewRequest returns a POST of the event to the URL in the mode, signed with the secret.
+func NewRequest(ctx context.Context, url string, secret string, mode string, e cloudevents.Event) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := binding.WithForceStructured(ctx)
+	if mode == Binary {
+		encoding = binding.WithForceBinary(ctx)
+	}
+	if err := cehttp.WriteRequest(encoding, (*binding.EventMessage)(&e), req); err != nil {
+		return nil, err
+	}
+
+	// the body is read back so it can be signed
+	var body []byte
+	if req.Body != nil {
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	return req, nil
+}
+
+// RejectedError is a 4xx response other than 408 and 429, which retrying won't change.
+type RejectedError struct {
+	Status string
+}
+
+func (e *RejectedError) Error() string {
+	return "the subscriber rejected the event: " + e.Status
+}
+
+// CheckResponse closes the response and returns an error unless it's a 2xx.
+func CheckResponse(resp *http.Response) error {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return &RejectedError{Status: resp.Status}
+	default:
+		return fmt.Errorf("the subscriber responded %s", resp.Status)
+	}
+}
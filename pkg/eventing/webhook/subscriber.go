@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+)
+
+// The ways a subscriber can get the CloudEvents.  See
+// https://github.com/cloudevents/spec/blob/main/cloudevents/bindings/http-protocol-binding.md#3-http-message-mapping
+const (
+	// Binary puts the attributes in ce- headers and the data in the body.
+	Binary = "binary"
+
+	// Structured puts the whole event in the body as application/cloudevents+json.
+	Structured = "structured"
+)
+
+// The headers of the signature.  The signature is the hex HMAC-SHA256 of the timestamp, a '.' and the body.
+const (
+	TimestampHeader = "X-Inventory-Timestamp"
+	SignatureHeader = "X-Inventory-Signature"
+)
+
+// Subscriber is a URL that gets every event.  Its secret signs them.
+type Subscriber struct {
+	Name       string `mapstructure:"name"`
+	URL        string `mapstructure:"url"`
+	Secret     string `mapstructure:"secret"`
+	SecretFile string `mapstructure:"secret-file"`
+
+	// Mode is binary or structured.  It defaults to eventing.webhook.mode.
+	Mode string `mapstructure:"mode"`
+}
+
+func (s *Subscriber) Validate() []error {
+	var errs []error
+
+	if s.Name == "" {
+		errs = append(errs, fmt.Errorf("every subscriber needs a name"))
+	}
+
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("the url must be an http or https URL: %s", s.URL))
+	}
+
+	if (s.Secret == "") == (s.SecretFile == "") {
+		errs = append(errs, fmt.Errorf("exactly one of secret and secret-file must be set"))
+	}
+
+	if s.Mode != "" {
+		if err := validateMode(s.Mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// Sign returns the signature of the body sent at the timestamp.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
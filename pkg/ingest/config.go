@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type Config struct {
+	*Options
+}
+
+type completedConfig struct {
+	Enabled         bool
+	DeadLetterTopic string
+	Topics          map[string]*Topic
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	ConsumerConfig  *kafka.ConfigMap
+	ProducerConfig  *kafka.ConfigMap
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	topics := map[string]*Topic{}
+	for _, t := range c.Topics {
+		topics[t.Name] = t
+	}
+
+	// offsets are stored by hand once a message is ingested or dead-lettered, and only stored offsets are
+	// committed
+	consumer := &kafka.ConfigMap{
+		"bootstrap.servers":        c.BootstrapServers,
+		"group.id":                 c.GroupId,
+		"enable.auto.commit":       true,
+		"enable.auto.offset.store": false,
+		"auto.offset.reset":        "earliest",
+	}
+	producer := &kafka.ConfigMap{
+		"bootstrap.servers":  c.BootstrapServers,
+		"enable.idempotence": true,
+	}
+	for k, v := range c.Properties {
+		if err := consumer.SetKey(k, v); err != nil {
+			return CompletedConfig{}, err
+		}
+		if err := producer.SetKey(k, v); err != nil {
+			return CompletedConfig{}, err
+		}
+	}
+
+	return CompletedConfig{&completedConfig{
+		Enabled:         c.Enabled,
+		DeadLetterTopic: c.DeadLetterTopic,
+		Topics:          topics,
+		RetryBackoff:    c.RetryBackoff,
+		MaxRetryBackoff: c.MaxRetryBackoff,
+		ConsumerConfig:  consumer,
+		ProducerConfig:  producer,
+	}}, nil
+}
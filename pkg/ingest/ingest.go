@@ -0,0 +1,451 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	confluent "github.com/cloudevents/sdk-go/protocol/kafka_confluent/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"gorm.io/gorm"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/controllers"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+// The types of the CloudEvents reporters publish.  The data of both is a ResourceIn, but deletes only need the
+// ResourceType and LocalResourceId.
+const (
+	// ResourceReported creates the resource if the reporter hasn't reported it before and updates it otherwise,
+	// so a message that's delivered twice does no harm.
+	ResourceReported = "com.redhat.inventory.reporter.resource.reported"
+
+	// ResourceRemoved deletes the resource.  It does nothing if the resource is already gone.
+	ResourceRemoved = "com.redhat.inventory.reporter.resource.deleted"
+)
+
+// The headers added to dead-lettered messages.
+const (
+	HeaderError     = "ingest-error"
+	HeaderTopic     = "ingest-topic"
+	HeaderPartition = "ingest-partition"
+	HeaderOffset    = "ingest-offset"
+)
+
+// topicCheckTimeoutMs is how long to wait at startup for the brokers to confirm the topics exist.
+const topicCheckTimeoutMs = 10000
+
+// Ingester consumes reporter updates from kafka and applies them like the resource endpoints do.  A message's
+// offset is stored only after its change is committed to the database or it's dead-lettered, so a crash
+// redelivers the messages it was working on.  Messages that can't be ingested, like malformed ones or ones that
+// fail validation, go to the dead letter topic.  Failures that may pass, like the database being unavailable, are
+// retried with backoff, which holds up the rest of the partition.  The partition is paused while it waits and the
+// consumer keeps polling, so it isn't evicted from the group for taking longer than max.poll.interval.ms.
+type Ingester struct {
+	Config      CompletedConfig
+	Controllers map[string]*controllers.ResourceController
+	Consumer    *kafka.Consumer
+	Producer    *kafka.Producer
+	Errors      *eventingapi.ErrorReporter
+	Log         *slog.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped sync.WaitGroup
+
+	// retries are the paused partitions and the messages they're waiting to retry.  Only run uses them.
+	retries map[partition]*retry
+}
+
+// partition is a topic partition.  kafka.TopicPartition can't be a map key since its topic is a pointer.
+type partition struct {
+	topic string
+	id    int32
+}
+
+// retry is a message that failed and is fetched again from the paused partition once its backoff has passed.
+type retry struct {
+	offset  kafka.Offset
+	backoff time.Duration
+	at      time.Time
+	paused  bool
+}
+
+// New subscribes to the configured topics.  It returns an error if the brokers don't know any of them or the dead
+// letter topic.
+func New(config CompletedConfig, resourceControllers map[string]*controllers.ResourceController, log *slog.Logger) (*Ingester, error) {
+	producer, err := kafka.NewProducer(config.ProducerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var topics []string
+	for name := range config.Topics {
+		topics = append(topics, name)
+	}
+	slices.Sort(topics)
+
+	if err := checkTopics(producer, append([]string{config.DeadLetterTopic}, topics...)); err != nil {
+		producer.Close()
+		return nil, err
+	}
+
+	consumer, err := kafka.NewConsumer(config.ConsumerConfig)
+	if err != nil {
+		producer.Close()
+		return nil, err
+	}
+
+	i := &Ingester{
+		Config:      config,
+		Controllers: resourceControllers,
+		Consumer:    consumer,
+		Producer:    producer,
+		Errors:      eventingapi.NewErrorReporter(log),
+		Log:         log,
+		retries:     map[partition]*retry{},
+	}
+
+	if err := consumer.SubscribeTopics(topics, i.rebalanced); err != nil {
+		consumer.Close()
+		producer.Close()
+		return nil, err
+	}
+	i.ctx, i.cancel = context.WithCancel(context.Background())
+
+	i.stopped.Add(2)
+	go i.run()
+	go i.handleProducerEvents()
+
+	return i, nil
+}
+
+// Errs reports fatal kafka errors.  The ingester has stopped consuming when it sends one.
+func (i *Ingester) Errs() <-chan error {
+	return i.Errors.Errs()
+}
+
+// Shutdown stops consuming, waits for the message being ingested and commits the stored offsets.  A message that's
+// waiting to be retried is left for the next consumer.
+func (i *Ingester) Shutdown(ctx context.Context) error {
+	i.Errors.Stop()
+	i.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		i.stopped.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	err := i.Consumer.Close()
+	i.Producer.Close()
+	return err
+}
+
+func (i *Ingester) run() {
+	defer i.stopped.Done()
+
+	for i.ctx.Err() == nil {
+		i.resume()
+
+		switch ev := i.Consumer.Poll(100).(type) {
+		case *kafka.Message:
+			if err := i.handle(ev); err != nil {
+				i.Log.Error(err.Error())
+				i.Errors.Report(err)
+				return
+			}
+		case kafka.Error:
+			if ev.IsFatal() {
+				i.Log.Error(fmt.Sprintf("Fatal kafka consumer error: %v", ev))
+				i.Errors.Report(ev)
+				return
+			}
+			i.Log.Warn(fmt.Sprintf("Kafka consumer error: %v", ev))
+		case nil:
+		default:
+			i.Log.Debug(fmt.Sprintf("Ignored event: %v", ev))
+		}
+	}
+}
+
+// rebalanced forgets the retries of partitions that are revoked.  Their messages are retried by whoever gets them
+// next.  librdkafka assigns and revokes the partitions itself.
+func (i *Ingester) rebalanced(c *kafka.Consumer, ev kafka.Event) error {
+	if revoked, ok := ev.(kafka.RevokedPartitions); ok {
+		for _, tp := range revoked.Partitions {
+			delete(i.retries, partition{topic: *tp.Topic, id: tp.Partition})
+		}
+	}
+	return nil
+}
+
+// resume resumes the paused partitions whose backoff has passed, so their failed messages are fetched again.
+func (i *Ingester) resume() {
+	now := time.Now()
+	for p, r := range i.retries {
+		if !r.paused || now.Before(r.at) {
+			continue
+		}
+		topic := p.topic
+		if err := i.Consumer.Resume([]kafka.TopicPartition{{Topic: &topic, Partition: p.id}}); err != nil {
+			i.Log.Warn(fmt.Sprintf("Failed to resume %s [%d]: %v", p.topic, p.id, err))
+			continue
+		}
+		r.paused = false
+	}
+}
+
+// handleProducerEvents reads the dead letter producer's events until it's closed.  Deliveries are reported to
+// deadLetter directly.
+func (i *Ingester) handleProducerEvents() {
+	defer i.stopped.Done()
+
+	for {
+		select {
+		case e := <-i.Producer.Events():
+			if ev, ok := e.(kafka.Error); ok {
+				if ev.IsFatal() {
+					i.Log.Error(fmt.Sprintf("Fatal kafka producer error: %v", ev))
+					i.Errors.Report(ev)
+				} else {
+					i.Log.Warn(fmt.Sprintf("Kafka producer error: %v", ev))
+				}
+			}
+		case <-i.ctx.Done():
+			return
+		}
+	}
+}
+
+// handle ingests the message and stores its offset once it's ingested or dead-lettered.  If it fails in a way that
+// may pass, its partition is paused and rewound to it, and it's fetched again after the backoff.  It returns an
+// error if the partition can't be rewound, since the message wouldn't be retried.
+func (i *Ingester) handle(msg *kafka.Message) error {
+	p := partition{topic: *msg.TopicPartition.Topic, id: msg.TopicPartition.Partition}
+	r := i.retries[p]
+	if r != nil && r.paused {
+		// fetched before the partition was paused, and fetched again after it's resumed
+		return nil
+	}
+
+	err := i.ingest(i.ctx, msg)
+
+	var eventErr *controllers.EventError
+	switch {
+	case err == nil:
+	case errors.As(err, &eventErr):
+		// the change is saved, and ingesting it again wouldn't send the event
+		i.Log.Error(fmt.Sprintf("Ingested %s but couldn't send its event: %v", describe(msg), eventErr.Err))
+	case rejected(err):
+		i.Log.Warn(fmt.Sprintf("Dead-lettering %s: %v", describe(msg), err))
+		err = i.deadLetter(msg, err)
+	}
+
+	if err == nil || errors.As(err, &eventErr) {
+		delete(i.retries, p)
+		if _, err := i.Consumer.StoreMessage(msg); err != nil {
+			i.Log.Error(fmt.Sprintf("Failed to store the offset of %s: %v", describe(msg), err))
+		}
+		return nil
+	}
+	if i.ctx.Err() != nil {
+		return nil
+	}
+
+	backoff := i.Config.RetryBackoff
+	if r != nil && r.offset == msg.TopicPartition.Offset {
+		backoff = min(2*r.backoff, i.Config.MaxRetryBackoff)
+	}
+	i.Log.Warn(fmt.Sprintf("Failed to ingest %s, retrying in %s: %v", describe(msg), backoff, err))
+
+	tp := []kafka.TopicPartition{msg.TopicPartition}
+	if err := i.Consumer.Pause(tp); err != nil {
+		i.Log.Error(fmt.Sprintf("Failed to pause %s [%d]: %v", p.topic, p.id, err))
+	}
+	seeked, err := i.Consumer.SeekPartitions(tp)
+	if err == nil && seeked[0].Error != nil {
+		err = seeked[0].Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rewind to %s, so it can't be retried: %w", describe(msg), err)
+	}
+	i.retries[p] = &retry{
+		offset:  msg.TopicPartition.Offset,
+		backoff: backoff,
+		at:      time.Now().Add(backoff),
+		paused:  true,
+	}
+	return nil
+}
+
+// ingest applies the change in the message as the reporter of its topic.
+func (i *Ingester) ingest(ctx context.Context, msg *kafka.Message) error {
+	topic, found := i.Config.Topics[*msg.TopicPartition.Topic]
+	if !found {
+		return reject(fmt.Errorf("topic %s isn't configured", *msg.TopicPartition.Topic))
+	}
+	identity := &authnapi.Identity{
+		Principal:  topic.ReporterId,
+		Type:       topic.ReporterType,
+		Tenant:     topic.Tenant,
+		IsReporter: true,
+	}
+
+	e, err := binding.ToEvent(ctx, confluent.NewMessage(msg))
+	if err != nil {
+		return reject(fmt.Errorf("the message isn't a CloudEvent: %w", err))
+	}
+
+	var input models.ResourceIn
+	if err := e.DataAs(&input); err != nil {
+		return reject(fmt.Errorf("the data isn't a resource: %w", err))
+	}
+
+	c, found := i.Controllers[input.ResourceType]
+	if !found {
+		return reject(fmt.Errorf("unknown resource type %q", input.ResourceType))
+	}
+
+	switch e.Type() {
+	case ResourceReported:
+		existing, err := c.FindReported(ctx, identity, input.LocalResourceId)
+		switch {
+		case err == nil:
+			_, err = c.UpdateResource(ctx, existing.ID, &input, identity)
+			return err
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			_, err = c.CreateResource(ctx, &input, identity)
+			return err
+		default:
+			return err
+		}
+	case ResourceRemoved:
+		if input.LocalResourceId == "" {
+			return cerrors.NewValidationProblem([]error{cerrors.NewFieldError("LocalResourceId", "must not be empty")})
+		}
+		existing, err := c.FindReported(ctx, identity, input.LocalResourceId)
+		switch {
+		case err == nil:
+			// it may have been deleted since it was found
+			var p *cerrors.Problem
+			if err := c.DeleteResource(ctx, existing.ID, identity); err != nil && !(errors.As(err, &p) && p.Status == http.StatusNotFound) {
+				return err
+			}
+			return nil
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil
+		default:
+			return err
+		}
+	default:
+		return reject(fmt.Errorf("unknown event type %q", e.Type()))
+	}
+}
+
+// deadLetter sends the message to the dead letter topic with headers that say why and where it came from, and
+// waits for the brokers to acknowledge it.
+func (i *Ingester) deadLetter(msg *kafka.Message, cause error) error {
+	headers := slices.Clone(msg.Headers)
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderTopic, Value: []byte(*msg.TopicPartition.Topic)},
+		kafka.Header{Key: HeaderPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: HeaderOffset, Value: []byte(msg.TopicPartition.Offset.String())},
+	)
+
+	delivered := make(chan kafka.Event, 1)
+	err := i.Producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &i.Config.DeadLetterTopic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	}, delivered)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter the message: %w", err)
+	}
+
+	select {
+	case e := <-delivered:
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return fmt.Errorf("failed to dead-letter the message: %w", m.TopicPartition.Error)
+		}
+		return nil
+	case <-i.ctx.Done():
+		return i.ctx.Err()
+	}
+}
+
+// rejectedError is a message that can't be ingested however often it's retried.
+type rejectedError struct {
+	err error
+}
+
+func reject(err error) error {
+	return &rejectedError{err: err}
+}
+
+func (e *rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectedError) Unwrap() error {
+	return e.err
+}
+
+// rejected reports whether retrying can't help: the message is malformed or the change was refused the way a
+// request would have been refused with a 4xx.
+func rejected(err error) bool {
+	var r *rejectedError
+	if errors.As(err, &r) {
+		return true
+	}
+
+	var p *cerrors.Problem
+	if !errors.As(err, &p) {
+		p = controllers.DbProblem(err, "")
+	}
+	return p.Status < http.StatusInternalServerError
+}
+
+func describe(msg *kafka.Message) string {
+	return fmt.Sprintf("message %s [%d] at offset %v", *msg.TopicPartition.Topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+}
+
+// checkTopics returns an error unless the brokers know all the topics.
+func checkTopics(producer *kafka.Producer, topics []string) error {
+	metadata, err := producer.GetMetadata(nil, true, topicCheckTimeoutMs)
+	if err != nil {
+		return fmt.Errorf("failed to check the kafka topics exist: %w", err)
+	}
+
+	var missing []string
+	for _, t := range topics {
+		md, found := metadata.Topics[t]
+		if !found || md.Error.Code() != kafka.ErrNoError {
+			missing = append(missing, t)
+		}
+	}
+	if missing != nil {
+		return fmt.Errorf("the kafka topics don't exist: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/csams/common-inventory/pkg/authz/allow"
+	"github.com/csams/common-inventory/pkg/controllers"
+	"github.com/csams/common-inventory/pkg/eventing/stdout"
+	"github.com/csams/common-inventory/pkg/models"
+	"github.com/csams/common-inventory/pkg/models/migrations"
+)
+
+// logBuffer collects the logs of the ingester's goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "inventory.db")), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newCluster(t *testing.T) *kafka.MockCluster {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	for _, topic := range []string{"acm-resources", "dead-letters"} {
+		if err := cluster.CreateTopic(topic, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cluster
+}
+
+func produce(t *testing.T, cluster *kafka.MockCluster, localResourceId string) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	value, err := json.Marshal(map[string]any{
+		"specversion": "1.0",
+		"id":          localResourceId,
+		"source":      "urn:acm",
+		"type":        ResourceReported,
+		"data": map[string]any{
+			"DisplayName":     "cluster " + localResourceId,
+			"ResourceType":    "cluster",
+			"LocalResourceId": localResourceId,
+			"Workspace":       "prod",
+			"Data":            map[string]any{},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := "acm-resources"
+	delivered := make(chan kafka.Event, 1)
+	err = producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(localResourceId),
+		Value:          value,
+		Headers:        []kafka.Header{{Key: "content-type", Value: []byte("application/cloudevents+json")}},
+	}, delivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := (<-delivered).(*kafka.Message); m.TopicPartition.Error != nil {
+		t.Fatal(m.TopicPartition.Error)
+	}
+}
+
+func TestRetriesKeepPolling(t *testing.T) {
+	cluster := newCluster(t)
+	db := newDb(t)
+
+	// the message fails until the table is back, which is longer than max.poll.interval.ms
+	if err := db.Exec("alter table reporter_data rename to reporter_data_away").Error; err != nil {
+		t.Fatal(err)
+	}
+	produce(t, cluster, "1")
+
+	o := NewOptions()
+	o.Enabled = true
+	o.BootstrapServers = cluster.BootstrapServers()
+	o.DeadLetterTopic = "dead-letters"
+	o.Topics = []*Topic{{Name: "acm-resources", ReporterId: "acm-hub-1", ReporterType: "ACM"}}
+	o.Properties = map[string]string{"session.timeout.ms": "3000", "heartbeat.interval.ms": "500", "max.poll.interval.ms": "3000"}
+	o.RetryBackoff = 100 * time.Millisecond
+	o.MaxRetryBackoff = 400 * time.Millisecond
+	if errs := o.Validate(); errs != nil {
+		t.Fatal(errs)
+	}
+	config, err := NewConfig(o).Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := &logBuffer{}
+	log := slog.New(slog.NewTextHandler(logs, nil))
+	em, _ := stdout.New("urn:test")
+	resourceControllers := controllers.NewResourceControllers(db, allow.New(), em, false, log)
+
+	i, err := New(config, resourceControllers, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(logs.String(), "Failed to ingest") && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(5 * time.Second)
+	if err := db.Exec("alter table reporter_data_away rename to reporter_data").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var resource models.Resource
+	for time.Now().Before(deadline.Add(5 * time.Second)) {
+		if err := db.Where("display_name = ?", "cluster 1").First(&resource).Error; err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if resource.ID == 0 {
+		t.Fatalf("the message wasn't ingested after the database came back:\n%s", logs)
+	}
+
+	if err := i.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), "max.poll.interval.ms") {
+		t.Errorf("the consumer left the group while retrying:\n%s", logs)
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers(), "group.id": o.GroupId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+	topic := "acm-resources"
+	committed, err := consumer.Committed([]kafka.TopicPartition{{Topic: &topic, Partition: 0}}, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed[0].Offset != 1 {
+		t.Errorf("expected the offset after the message to be committed, got %v", committed[0].Offset)
+	}
+}
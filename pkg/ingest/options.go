@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	BootstrapServers string `mapstructure:"bootstrap-servers"`
+	GroupId          string `mapstructure:"group-id"`
+	DeadLetterTopic  string `mapstructure:"dead-letter-topic"`
+
+	// Topics are the topics to consume and the reporter each one is from.  They can only be set in the config file.
+	Topics []*Topic `mapstructure:"topics"`
+
+	// Properties are more librdkafka properties for the consumer and the dead letter producer, like
+	// security.protocol.  They can only be set in the config file.
+	Properties map[string]string `mapstructure:"properties"`
+
+	RetryBackoff    time.Duration `mapstructure:"retry-backoff"`
+	MaxRetryBackoff time.Duration `mapstructure:"max-retry-backoff"`
+}
+
+// Topic is a topic of reporter updates.  Anyone who can write to it reports as its reporter, so access to the
+// topic has to be restricted like a reporter's credentials.
+type Topic struct {
+	Name         string `mapstructure:"name"`
+	ReporterId   string `mapstructure:"reporter-id"`
+	ReporterType string `mapstructure:"reporter-type"`
+	Tenant       string `mapstructure:"tenant"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		GroupId:         "common-inventory-ingest",
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: time.Minute,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.BoolVar(&o.Enabled, prefix+"enabled", o.Enabled, "Consume reporter updates from kafka as well as serving the API.")
+	fs.StringVar(&o.BootstrapServers, prefix+"bootstrap-servers", o.BootstrapServers, "The kafka brokers to consume from.")
+	fs.StringVar(&o.GroupId, prefix+"group-id", o.GroupId, "The consumer group shared by the servers.")
+	fs.StringVar(&o.DeadLetterTopic, prefix+"dead-letter-topic", o.DeadLetterTopic, "The topic for messages that can't be ingested.")
+	fs.DurationVar(&o.RetryBackoff, prefix+"retry-backoff", o.RetryBackoff, "How long to wait before retrying a message that failed because of an outage.  The wait doubles after each retry.")
+	fs.DurationVar(&o.MaxRetryBackoff, prefix+"max-retry-backoff", o.MaxRetryBackoff, "The longest wait between retries.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if !o.Enabled {
+		return nil
+	}
+
+	if o.BootstrapServers == "" {
+		errs = append(errs, fmt.Errorf("ingest bootstrap-servers is required"))
+	}
+	if o.GroupId == "" {
+		errs = append(errs, fmt.Errorf("ingest group-id is required"))
+	}
+	if o.DeadLetterTopic == "" {
+		errs = append(errs, fmt.Errorf("ingest dead-letter-topic is required"))
+	}
+	if len(o.Topics) == 0 {
+		errs = append(errs, fmt.Errorf("ingest needs at least one topic"))
+	}
+
+	var names []string
+	for i, t := range o.Topics {
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("ingest topic %d needs a name", i))
+			continue
+		}
+		if slices.Contains(names, t.Name) {
+			errs = append(errs, fmt.Errorf("ingest topic %s is listed more than once", t.Name))
+		}
+		names = append(names, t.Name)
+
+		if t.Name == o.DeadLetterTopic {
+			errs = append(errs, fmt.Errorf("ingest topic %s is also the dead-letter-topic", t.Name))
+		}
+		if t.ReporterId == "" {
+			errs = append(errs, fmt.Errorf("ingest topic %s needs a reporter-id", t.Name))
+		}
+		if t.ReporterType == "" {
+			errs = append(errs, fmt.Errorf("ingest topic %s needs a reporter-type", t.Name))
+		}
+	}
+
+	if o.RetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("the ingest retry-backoff must be positive: %s", o.RetryBackoff))
+	}
+	if o.MaxRetryBackoff < o.RetryBackoff {
+		errs = append(errs, fmt.Errorf("the ingest max-retry-backoff must be at least the retry-backoff: %s", o.MaxRetryBackoff))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// ApiKeyIn is the identity an API key is issued for.
+type ApiKeyIn struct {
+	Principal  string
+	Type       string
+	Tenant     string
+	Groups     []string
+	IsReporter bool
+
+	// Scopes limits what the key may do.  Nil means no limits.
+	Scopes *authnapi.Scopes
+
+	// ExpiresAt is when the key stops working.  Nil means never.
+	ExpiresAt *time.Time
+}
+
+func (k *ApiKeyIn) Validate() []error {
+	var errs []error
+
+	if len(k.Principal) == 0 {
+		errs = append(errs, cerrors.NewFieldError("Principal", "must not be empty"))
+	}
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		errs = append(errs, cerrors.NewFieldError("ExpiresAt", "must be in the future"))
+	}
+
+	return errs
+}
+
+// ApiKey is a key issued through the admin API.  Callers present it as <KeyId>.<secret>, and only the hash of the
+// secret is stored.
+type ApiKey struct {
+	ID        IDType `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// KeyId is the public part of the key.  It's used in the API key's href.
+	KeyId string `gorm:"not null;uniqueIndex"`
+	Hash  string `gorm:"not null" json:"-"`
+
+	Principal  string `gorm:"not null"`
+	Type       string
+	Tenant     string
+	Groups     []string `gorm:"serializer:json"`
+	IsReporter bool
+	Scopes     *authnapi.Scopes `gorm:"serializer:json"`
+
+	ExpiresAt *time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+func NewApiKey(in *ApiKeyIn, keyId string, hash string) *ApiKey {
+	return &ApiKey{
+		KeyId:      keyId,
+		Hash:       hash,
+		Principal:  in.Principal,
+		Type:       in.Type,
+		Tenant:     in.Tenant,
+		Groups:     in.Groups,
+		IsReporter: in.IsReporter,
+		Scopes:     in.Scopes,
+		ExpiresAt:  in.ExpiresAt,
+	}
+}
+
+// Identity is the identity the key authenticates as.
+func (k *ApiKey) Identity() *authnapi.Identity {
+	return &authnapi.Identity{
+		Principal:  k.Principal,
+		Type:       k.Type,
+		Tenant:     k.Tenant,
+		Groups:     k.Groups,
+		IsReporter: k.IsReporter,
+		Scopes:     k.Scopes,
+	}
+}
+
+// Active reports whether the key may be used at the given time.
+func (k *ApiKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type ApiKeyOut struct {
+	*ApiKey
+	Href string
+
+	// Token is only returned when the key is issued or rotated.  It can't be recovered afterward.
+	Token string `json:",omitempty"`
+}
+
+func NewApiKeyOut(k *ApiKey, href string, token string) *ApiKeyOut {
+	return &ApiKeyOut{
+		ApiKey: k,
+		Href:   href,
+		Token:  token,
+	}
+}
@@ -0,0 +1,272 @@
+// Package migrations applies the versioned schema migrations embedded under sql/<dialect>.
+//
+// Each migration is a pair of files named <version>_<name>.up.sql and <version>_<name>.down.sql.  Versions are
+// applied in ascending order and recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+const TableName = "schema_migrations"
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a known migration and when it was applied.  AppliedAt is nil for pending migrations.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// SchemaMigration is a row of the schema_migrations table.
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return TableName
+}
+
+type Migrator struct {
+	Db         *gorm.DB
+	Migrations []Migration
+
+	// DryRun writes the SQL that would be run to Out instead of running it.
+	DryRun bool
+	Out    io.Writer
+}
+
+// New returns a Migrator with the migrations for the database's dialect.
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		Db:         db,
+		Migrations: migrations,
+	}, nil
+}
+
+// Load reads the embedded migrations for a dialect ordered by version.
+func Load(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database dialect %s", dialect)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		parts := fileName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, found := byVersion[version]
+		if !found {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, parts[2])
+		}
+
+		if parts[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status returns every known migration with its applied time.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range m.Migrations {
+		s := Status{Migration: migration}
+		if a, found := applied[migration.Version]; found {
+			appliedAt := a.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
+
+// Check returns an error unless every known migration has been applied and no unknown ones have.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := map[int64]bool{}
+	var pending int
+	for _, migration := range m.Migrations {
+		known[migration.Version] = true
+		if _, found := applied[migration.Version]; !found {
+			pending++
+		}
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("the database schema has %d pending migrations.  Run the migrate command", pending)
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("the database schema has migration %d applied, which this version doesn't know about", version)
+		}
+	}
+
+	return nil
+}
+
+// Up applies all pending migrations in order and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if !m.DryRun {
+		if err := m.Db.WithContext(ctx).AutoMigrate(&SchemaMigration{}); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, migration := range m.Migrations {
+		if _, found := applied[migration.Version]; found {
+			continue
+		}
+
+		record := &SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+		if err := m.run(ctx, migration, "up", migration.Up, func(tx *gorm.DB) error {
+			return tx.Create(record).Error
+		}); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down rolls back the n most recently applied migrations and returns how many were rolled back.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.Migrations) - 1; i >= 0 && count < n; i-- {
+		migration := m.Migrations[i]
+		if _, found := applied[migration.Version]; !found {
+			continue
+		}
+
+		if err := m.run(ctx, migration, "down", migration.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		}); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// run executes the migration's SQL and records the change in a single transaction.
+func (m *Migrator) run(ctx context.Context, migration Migration, direction string, sql string, record func(*gorm.DB) error) error {
+	if m.DryRun {
+		_, err := fmt.Fprintf(m.Out, "-- %06d_%s.%s.sql\n%s\n", migration.Version, migration.Name, direction, sql)
+		return err
+	}
+
+	err := m.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+		return record(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+	}
+	return nil
+}
+
+// applied returns the recorded migrations by version.  A database without the schema_migrations table has none.
+func (m *Migrator) applied(ctx context.Context) (map[int64]SchemaMigration, error) {
+	db := m.Db.WithContext(ctx)
+	applied := map[int64]SchemaMigration{}
+
+	if !db.Migrator().HasTable(TableName) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
@@ -0,0 +1,150 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMigrator(t *testing.T) *Migrator {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "inventory.db")), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// expectApplied fails unless every migration's applied state is the expected one.
+func expectApplied(t *testing.T, m *Migrator, applied bool) {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(m.Migrations) {
+		t.Fatalf("expected the status of %d migrations, got %d", len(m.Migrations), len(statuses))
+	}
+	for _, s := range statuses {
+		if (s.AppliedAt != nil) != applied {
+			t.Errorf("migration %d_%s: expected applied to be %t", s.Version, s.Name, applied)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	m := newMigrator(t)
+
+	if n, err := m.Up(ctx); err != nil || n != len(m.Migrations) {
+		t.Fatalf("expected %d migrations to be applied, got %d: %v", len(m.Migrations), n, err)
+	}
+	expectApplied(t, m, true)
+	if err := m.Check(ctx); err != nil {
+		t.Error(err)
+	}
+	for _, table := range []string{"resources", "reporter_data", "reporters", "api_keys", "subscriptions", "resyncs"} {
+		if !m.Db.Migrator().HasTable(table) {
+			t.Errorf("expected table %s", table)
+		}
+	}
+	if n, err := m.Up(ctx); err != nil || n != 0 {
+		t.Errorf("expected nothing to apply, got %d: %v", n, err)
+	}
+
+	if n, err := m.Down(ctx, len(m.Migrations)); err != nil || n != len(m.Migrations) {
+		t.Fatalf("expected %d migrations to be rolled back, got %d: %v", len(m.Migrations), n, err)
+	}
+	expectApplied(t, m, false)
+	if m.Db.Migrator().HasTable("resources") {
+		t.Error("expected the tables to be dropped")
+	}
+
+	if n, err := m.Up(ctx); err != nil || n != len(m.Migrations) {
+		t.Fatalf("expected %d migrations to be applied again, got %d: %v", len(m.Migrations), n, err)
+	}
+	expectApplied(t, m, true)
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	m := newMigrator(t)
+
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "pending") {
+		t.Errorf("expected pending migrations, got %v", err)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "1 pending") {
+		t.Errorf("expected a pending migration, got %v", err)
+	}
+
+	// a database migrated by a newer version
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Db.Create(&SchemaMigration{Version: 999999, Name: "newer", AppliedAt: time.Now().UTC()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "999999") {
+		t.Errorf("expected an unknown migration, got %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	m := newMigrator(t)
+	var out bytes.Buffer
+	m.DryRun, m.Out = true, &out
+
+	if n, err := m.Up(ctx); err != nil || n != len(m.Migrations) {
+		t.Fatalf("expected %d migrations to be printed, got %d: %v", len(m.Migrations), n, err)
+	}
+	for _, migration := range m.Migrations {
+		if !strings.Contains(out.String(), migration.Up) {
+			t.Errorf("expected the SQL of migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+	if !strings.HasPrefix(out.String(), "-- 000001_initial.up.sql\n") {
+		t.Errorf("expected the name of the first migration first, got:\n%s", out.String())
+	}
+
+	if m.Db.Migrator().HasTable(TableName) || m.Db.Migrator().HasTable("resources") {
+		t.Error("expected a dry run to leave the database alone")
+	}
+}
+
+func TestDialectsHaveTheSameMigrations(t *testing.T) {
+	postgres, err := Load("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Load("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations and sqlite has %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("postgres has %d_%s where sqlite has %d_%s", postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+-- The schema previously created by gorm's AutoMigrate.  IF NOT EXISTS lets databases created that way adopt
+-- versioned migrations.
+CREATE TABLE IF NOT EXISTS "resources" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "display_name" text NOT NULL,
+    "resource_type" text NOT NULL,
+    "workspace" text,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "reporter_data" (
+    "reporter_id" text,
+    "resource_id" bigint,
+    "reporter_type" text,
+    "created" timestamptz,
+    "updated" timestamptz,
+    "local_resource_id" text,
+    "reporter_version" text,
+    "console_href" text,
+    "api_href" text,
+    "data" jsonb,
+    PRIMARY KEY ("reporter_id", "reporter_type", "local_resource_id"),
+    CONSTRAINT "fk_resources_reporter_data" FOREIGN KEY ("resource_id") REFERENCES "resources"("id")
+);
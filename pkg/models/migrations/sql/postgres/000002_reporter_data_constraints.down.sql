@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_reporter_data_resource_id";
+
+ALTER TABLE "reporter_data" DROP CONSTRAINT IF EXISTS "fk_resources_reporter_data";
+ALTER TABLE "reporter_data" ADD CONSTRAINT "fk_resources_reporter_data"
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id");
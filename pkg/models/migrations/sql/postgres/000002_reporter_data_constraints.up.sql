@@ -0,0 +1,8 @@
+-- Deleting a resource deletes its reporter data.
+ALTER TABLE "reporter_data" DROP CONSTRAINT IF EXISTS "fk_resources_reporter_data";
+ALTER TABLE "reporter_data" ADD CONSTRAINT "fk_resources_reporter_data"
+    FOREIGN KEY ("resource_id") REFERENCES "resources"("id") ON DELETE CASCADE;
+
+-- The primary key already maps each of a reporter instance's local ids to exactly one resource.  Preloading a
+-- resource's reporter data looks it up by resource_id.
+CREATE INDEX IF NOT EXISTS "idx_reporter_data_resource_id" ON "reporter_data" ("resource_id");
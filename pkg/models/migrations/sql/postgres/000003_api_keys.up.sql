@@ -0,0 +1,20 @@
+-- API keys issued through the admin API.  Only a hash of each key's secret is stored.
+CREATE TABLE "api_keys" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "key_id" text NOT NULL,
+    "hash" text NOT NULL,
+    "principal" text NOT NULL,
+    "type" text,
+    "tenant" text,
+    "groups" jsonb,
+    "is_reporter" boolean NOT NULL DEFAULT false,
+    "scopes" jsonb,
+    "expires_at" timestamptz,
+    "rotated_at" timestamptz,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX "idx_api_keys_key_id" ON "api_keys" ("key_id");
@@ -0,0 +1,15 @@
+-- Registered reporter instances.  A reporter's principal is the reporter_id of the data it reports.
+CREATE TABLE "reporters" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "principal" text NOT NULL,
+    "reporter_type" text NOT NULL,
+    "reporter_version" text,
+    "owning_team" text,
+    "resource_types" jsonb,
+    "last_seen_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX "idx_reporters_principal" ON "reporters" ("principal");
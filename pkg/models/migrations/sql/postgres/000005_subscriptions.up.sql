@@ -0,0 +1,37 @@
+-- Webhook subscriptions and the deliveries of the events that matched them.
+CREATE TABLE "subscriptions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "owner" text NOT NULL,
+    "callback_url" text NOT NULL,
+    "resource_types" jsonb,
+    "workspaces" jsonb,
+    "event_types" jsonb,
+    "secret" text NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX "idx_subscriptions_owner" ON "subscriptions" ("owner");
+
+CREATE TABLE "deliveries" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "subscription_id" bigint NOT NULL,
+    "event_id" text NOT NULL,
+    "event_type" text NOT NULL,
+    "subject" text,
+    "event" text NOT NULL,
+    "status" text NOT NULL,
+    "attempts" bigint,
+    "last_error" text,
+    "last_attempt_at" timestamptz,
+    "next_attempt_at" timestamptz,
+    "delivered_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_subscriptions_deliveries" FOREIGN KEY ("subscription_id") REFERENCES "subscriptions"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_deliveries_subscription_id" ON "deliveries" ("subscription_id");
+CREATE INDEX "idx_deliveries_status" ON "deliveries" ("status");
@@ -0,0 +1,19 @@
+-- Resyncs of the snapshot events and how far they got.
+CREATE TABLE "resyncs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "requested_by" text NOT NULL,
+    "resource_type" text,
+    "workspace" text,
+    "reporter_type" text,
+    "reporter_id" text,
+    "status" text NOT NULL,
+    "last_resource_id" bigint,
+    "sent" bigint,
+    "last_error" text,
+    "completed_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX "idx_resyncs_status" ON "resyncs" ("status");
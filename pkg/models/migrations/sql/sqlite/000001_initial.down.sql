@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `reporter_data`;
+DROP TABLE IF EXISTS `resources`;
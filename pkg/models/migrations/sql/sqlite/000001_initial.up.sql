@@ -0,0 +1,25 @@
+-- The schema previously created by gorm's AutoMigrate.  IF NOT EXISTS lets databases created that way adopt
+-- versioned migrations.
+CREATE TABLE IF NOT EXISTS `resources` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `display_name` text NOT NULL,
+    `resource_type` text NOT NULL,
+    `workspace` text
+);
+
+CREATE TABLE IF NOT EXISTS `reporter_data` (
+    `reporter_id` text,
+    `resource_id` integer,
+    `reporter_type` text,
+    `created` datetime,
+    `updated` datetime,
+    `local_resource_id` text,
+    `reporter_version` text,
+    `console_href` text,
+    `api_href` text,
+    `data` JSON,
+    PRIMARY KEY (`reporter_id`, `reporter_type`, `local_resource_id`),
+    CONSTRAINT `fk_resources_reporter_data` FOREIGN KEY (`resource_id`) REFERENCES `resources`(`id`)
+);
@@ -0,0 +1,22 @@
+CREATE TABLE `reporter_data_old` (
+    `reporter_id` text,
+    `resource_id` integer,
+    `reporter_type` text,
+    `created` datetime,
+    `updated` datetime,
+    `local_resource_id` text,
+    `reporter_version` text,
+    `console_href` text,
+    `api_href` text,
+    `data` JSON,
+    PRIMARY KEY (`reporter_id`, `reporter_type`, `local_resource_id`),
+    CONSTRAINT `fk_resources_reporter_data` FOREIGN KEY (`resource_id`) REFERENCES `resources`(`id`)
+);
+
+INSERT INTO `reporter_data_old` SELECT
+    `reporter_id`, `resource_id`, `reporter_type`, `created`, `updated`, `local_resource_id`, `reporter_version`,
+    `console_href`, `api_href`, `data`
+FROM `reporter_data`;
+
+DROP TABLE `reporter_data`;
+ALTER TABLE `reporter_data_old` RENAME TO `reporter_data`;
@@ -0,0 +1,28 @@
+-- sqlite can't alter a table's constraints, so rebuild reporter_data with a foreign key that deletes the
+-- reporter data along with its resource.
+CREATE TABLE `reporter_data_new` (
+    `reporter_id` text,
+    `resource_id` integer,
+    `reporter_type` text,
+    `created` datetime,
+    `updated` datetime,
+    `local_resource_id` text,
+    `reporter_version` text,
+    `console_href` text,
+    `api_href` text,
+    `data` JSON,
+    PRIMARY KEY (`reporter_id`, `reporter_type`, `local_resource_id`),
+    CONSTRAINT `fk_resources_reporter_data` FOREIGN KEY (`resource_id`) REFERENCES `resources`(`id`) ON DELETE CASCADE
+);
+
+INSERT INTO `reporter_data_new` SELECT
+    `reporter_id`, `resource_id`, `reporter_type`, `created`, `updated`, `local_resource_id`, `reporter_version`,
+    `console_href`, `api_href`, `data`
+FROM `reporter_data`;
+
+DROP TABLE `reporter_data`;
+ALTER TABLE `reporter_data_new` RENAME TO `reporter_data`;
+
+-- The primary key already maps each of a reporter instance's local ids to exactly one resource.  Preloading a
+-- resource's reporter data looks it up by resource_id.
+CREATE INDEX `idx_reporter_data_resource_id` ON `reporter_data` (`resource_id`);
@@ -0,0 +1,19 @@
+-- API keys issued through the admin API.  Only a hash of each key's secret is stored.
+CREATE TABLE `api_keys` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `key_id` text NOT NULL,
+    `hash` text NOT NULL,
+    `principal` text NOT NULL,
+    `type` text,
+    `tenant` text,
+    `groups` JSON,
+    `is_reporter` numeric NOT NULL DEFAULT false,
+    `scopes` JSON,
+    `expires_at` datetime,
+    `rotated_at` datetime,
+    `revoked_at` datetime
+);
+
+CREATE UNIQUE INDEX `idx_api_keys_key_id` ON `api_keys` (`key_id`);
@@ -0,0 +1,14 @@
+-- Registered reporter instances.  A reporter's principal is the reporter_id of the data it reports.
+CREATE TABLE `reporters` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `principal` text NOT NULL,
+    `reporter_type` text NOT NULL,
+    `reporter_version` text,
+    `owning_team` text,
+    `resource_types` JSON,
+    `last_seen_at` datetime
+);
+
+CREATE UNIQUE INDEX `idx_reporters_principal` ON `reporters` (`principal`);
@@ -0,0 +1,35 @@
+-- Webhook subscriptions and the deliveries of the events that matched them.
+CREATE TABLE `subscriptions` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `owner` text NOT NULL,
+    `callback_url` text NOT NULL,
+    `resource_types` JSON,
+    `workspaces` JSON,
+    `event_types` JSON,
+    `secret` text NOT NULL
+);
+
+CREATE INDEX `idx_subscriptions_owner` ON `subscriptions` (`owner`);
+
+CREATE TABLE `deliveries` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `subscription_id` integer NOT NULL,
+    `event_id` text NOT NULL,
+    `event_type` text NOT NULL,
+    `subject` text,
+    `event` text NOT NULL,
+    `status` text NOT NULL,
+    `attempts` integer,
+    `last_error` text,
+    `last_attempt_at` datetime,
+    `next_attempt_at` datetime,
+    `delivered_at` datetime,
+    CONSTRAINT `fk_subscriptions_deliveries` FOREIGN KEY (`subscription_id`) REFERENCES `subscriptions`(`id`) ON DELETE CASCADE
+);
+
+CREATE INDEX `idx_deliveries_subscription_id` ON `deliveries` (`subscription_id`);
+CREATE INDEX `idx_deliveries_status` ON `deliveries` (`status`);
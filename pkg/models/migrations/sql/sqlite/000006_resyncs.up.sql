@@ -0,0 +1,18 @@
+-- Resyncs of the snapshot events and how far they got.
+CREATE TABLE `resyncs` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `requested_by` text NOT NULL,
+    `resource_type` text,
+    `workspace` text,
+    `reporter_type` text,
+    `reporter_id` text,
+    `status` text NOT NULL,
+    `last_resource_id` integer,
+    `sent` integer,
+    `last_error` text,
+    `completed_at` datetime
+);
+
+CREATE INDEX `idx_resyncs_status` ON `resyncs` (`status`);
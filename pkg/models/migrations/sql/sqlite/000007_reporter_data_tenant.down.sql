@@ -0,0 +1 @@
+ALTER TABLE `reporter_data` DROP COLUMN `tenant`;
@@ -0,0 +1,2 @@
+-- The tenant of the reporter's identity, so snapshots of the resource are routed like the reporter's changes.
+ALTER TABLE `reporter_data` ADD COLUMN `tenant` text;
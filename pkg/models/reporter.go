@@ -0,0 +1,88 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// ReporterIn registers a reporter instance.
+type ReporterIn struct {
+	// Principal is the authenticated principal the reporter reports as.  It's the ReporterID of the reporter's data.
+	Principal string
+
+	ReporterType    string
+	ReporterVersion string
+
+	// OwningTeam is who to contact about the reporter.
+	OwningTeam string
+
+	// ResourceTypes are the resource types the reporter may report.  Empty allows every type.
+	ResourceTypes []string
+}
+
+func (in *ReporterIn) Validate() []error {
+	var errs []error
+
+	if len(in.Principal) == 0 {
+		errs = append(errs, cerrors.NewFieldError("Principal", "must not be empty"))
+	}
+
+	if len(in.ReporterType) == 0 {
+		errs = append(errs, cerrors.NewFieldError("ReporterType", "must not be empty"))
+	}
+
+	return errs
+}
+
+// Reporter is a registered reporter instance.
+type Reporter struct {
+	ID        IDType `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Principal string `gorm:"not null;uniqueIndex"`
+
+	ReporterType    string `gorm:"not null"`
+	ReporterVersion string
+	OwningTeam      string
+	ResourceTypes   []string `gorm:"serializer:json"`
+
+	// LastSeenAt is when the reporter last created, updated or deleted a resource.
+	LastSeenAt *time.Time
+}
+
+func NewReporter(in *ReporterIn) *Reporter {
+	r := &Reporter{}
+	r.Update(in)
+	return r
+}
+
+// Update replaces the registration with the input.  The principal and last seen time are kept.
+func (r *Reporter) Update(in *ReporterIn) {
+	if r.Principal == "" {
+		r.Principal = in.Principal
+	}
+	r.ReporterType = in.ReporterType
+	r.ReporterVersion = in.ReporterVersion
+	r.OwningTeam = in.OwningTeam
+	r.ResourceTypes = in.ResourceTypes
+}
+
+// Allows reports whether the reporter may report resources of the type.
+func (r *Reporter) Allows(resourceType string) bool {
+	return len(r.ResourceTypes) == 0 || slices.Contains(r.ResourceTypes, resourceType)
+}
+
+type ReporterOut struct {
+	*Reporter
+	Href string
+}
+
+func NewReporterOut(r *Reporter, href string) *ReporterOut {
+	return &ReporterOut{
+		Reporter: r,
+		Href:     href,
+	}
+}
@@ -2,10 +2,12 @@ package models
 
 import (
 	"encoding/json"
-	"errors"
 	"time"
 
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	cerrors "github.com/csams/common-inventory/pkg/errors"
 )
 
 type IDType int64
@@ -35,10 +37,10 @@ type ResourceIn struct {
 	// This is the type of the Data blob below.  It specifies whether this is an OCM cluster, an ACM cluster,
 	// etc.  It seems reasonable to infer the value from the caller's identity data, but it's not clear that's
 	// *always* the case.  So, allow it to be passed explicitly and then log a warning or something if the value
-	// doesn't match the inferred type.
+	// doesn't match the inferred type.  It may be omitted when the caller's registration or identity has a type.
 	ReporterType string
 
-	// The version of the reporter.
+	// The version of the reporter.  It defaults to the version of the caller's registration.
 	ReporterVersion string
 
 	// This should be data about the resource type that is specific to the ReporterType.
@@ -49,26 +51,19 @@ func (r *ResourceIn) Validate() []error {
 	var errs []error
 
 	if len(r.DisplayName) == 0 {
-		errs = append(errs, errors.New("Resource DisplayName must not be empty"))
+		errs = append(errs, cerrors.NewFieldError("DisplayName", "must not be empty"))
 	}
 
 	if len(r.ResourceType) == 0 {
-		errs = append(errs, errors.New("Resource ResourceType must not be empty"))
+		errs = append(errs, cerrors.NewFieldError("ResourceType", "must not be empty"))
 	}
 
 	if len(r.LocalResourceId) == 0 {
-		errs = append(errs, errors.New("ReporterData LocalResourceId must not be empty"))
-	}
-
-	if len(r.ReporterType) == 0 {
-		errs = append(errs, errors.New("Resource ReporterType must not be empty"))
+		errs = append(errs, cerrors.NewFieldError("LocalResourceId", "must not be empty"))
 	}
 
 	if len(r.Data) == 0 {
-		errs = append(errs, errors.New("ReporterData Data must not be empty"))
-	}
-	if r.Data == nil || len(r.Data) == 0 {
-		errs = append(errs, errors.New("Resource Data must not be empty"))
+		errs = append(errs, cerrors.NewFieldError("Data", "must not be empty"))
 	}
 
 	return errs
@@ -80,7 +75,7 @@ type ReporterData struct {
 	ReporterID string `gorm:"primaryKey"`
 
 	// This is necessary to satisfy gorm so the collection in the Resource model works.
-	ResourceID IDType `json:"-"`
+	ResourceID IDType `gorm:"index" json:"-"`
 
 	// This is the type of the Data blob below.  It specifies whether this is an OCM cluster, an ACM cluster,
 	// etc.  It seems reasonable to infer the value from the caller's identity data, but it's not clear that's
@@ -88,6 +83,9 @@ type ReporterData struct {
 	// doesn't match the inferred type.
 	ReporterType string `gorm:"primaryKey"`
 
+	// Tenant is the tenant of the reporter's identity.  Resyncs route the resource's snapshots with it.
+	Tenant string `json:"-"`
+
 	Created time.Time
 	Updated time.Time
 
@@ -115,7 +113,7 @@ type Resource struct {
 	Workspace    *string
 
 	// ReporterData is a map from ReporterType to the reporter's representation of the resource.
-	ReporterData []ReporterData
+	ReporterData []ReporterData `gorm:"constraint:OnDelete:CASCADE"`
 }
 
 type ResourceOut struct {
@@ -129,3 +127,27 @@ func NewResourceOut(r *Resource, href string) *ResourceOut {
 		Href:     href,
 	}
 }
+
+// FilterResources selects the resources in the workspace that have a reporter of the type with the id.  Empty
+// values don't filter.
+func FilterResources(workspace, reporterType, reporterId string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if workspace != "" {
+			db = db.Where("resources.workspace = ?", workspace)
+		}
+
+		if reporterType != "" || reporterId != "" {
+			// use a subquery so resources with several matching reporters aren't returned more than once
+			reporters := db.Session(&gorm.Session{NewDB: true}).Table("reporter_data").Select("resource_id")
+			if reporterType != "" {
+				reporters = reporters.Where("reporter_type = ?", reporterType)
+			}
+			if reporterId != "" {
+				reporters = reporters.Where("reporter_id = ?", reporterId)
+			}
+			db = db.Where("resources.id in (?)", reporters)
+		}
+
+		return db
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// ResyncIn selects the resources a resync sends snapshots of.  Empty filters match every resource.
+type ResyncIn struct {
+	ResourceType string
+	Workspace    string
+	ReporterType string
+	ReporterId   string
+}
+
+// The statuses of a resync.
+const (
+	ResyncRunning   = "running"
+	ResyncCompleted = "completed"
+	ResyncFailed    = "failed"
+)
+
+// Resync is a snapshot event for each matching resource in id order, then an event that marks the end.  It
+// records the last resource it sent, so a resync that stopped resumes after it.
+type Resync struct {
+	ID        IDType `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// RequestedBy is the principal that started the resync.
+	RequestedBy string `gorm:"not null"`
+
+	ResourceType string
+	Workspace    string
+	ReporterType string
+	ReporterId   string
+
+	Status string `gorm:"not null;index"`
+
+	// LastResourceId is the id of the last resource whose snapshot was sent.
+	LastResourceId IDType `json:"-"`
+
+	// Sent is how many snapshots were sent.
+	Sent        int64
+	LastError   string
+	CompletedAt *time.Time
+}
+
+func NewResync(in *ResyncIn, requestedBy string) *Resync {
+	return &Resync{
+		RequestedBy:  requestedBy,
+		ResourceType: in.ResourceType,
+		Workspace:    in.Workspace,
+		ReporterType: in.ReporterType,
+		ReporterId:   in.ReporterId,
+		Status:       ResyncRunning,
+	}
+}
+
+type ResyncOut struct {
+	*Resync
+	Href string
+}
+
+func NewResyncOut(r *Resync, href string) *ResyncOut {
+	return &ResyncOut{
+		Resync: r,
+		Href:   href,
+	}
+}
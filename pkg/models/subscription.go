@@ -0,0 +1,123 @@
+package models
+
+import (
+	"net/url"
+	"time"
+
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+)
+
+// SubscriptionIn registers a callback URL for the events that match every one of its non-empty filters.
+type SubscriptionIn struct {
+	CallbackURL string
+
+	// ResourceTypes defaults to the types the caller may list.
+	ResourceTypes []string
+	Workspaces    []string
+	EventTypes    []string
+}
+
+func (s *SubscriptionIn) Validate() []error {
+	var errs []error
+
+	if u, err := url.Parse(s.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, cerrors.NewFieldError("CallbackURL", "must be an http or https URL"))
+	}
+
+	return errs
+}
+
+// Subscription is a callback URL that gets the matching events, signed with its secret.  Only its owner and admins
+// may see or change it.
+type Subscription struct {
+	ID        IDType `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Owner is the principal that registered the subscription.
+	Owner       string `gorm:"not null;index"`
+	CallbackURL string `gorm:"not null"`
+
+	ResourceTypes []string `gorm:"serializer:json"`
+	Workspaces    []string `gorm:"serializer:json"`
+	EventTypes    []string `gorm:"serializer:json"`
+
+	// Secret keys the signatures of the deliveries.  It's only returned when the subscription is created.
+	Secret string `gorm:"not null" json:"-"`
+}
+
+func NewSubscription(in *SubscriptionIn, owner string, secret string) *Subscription {
+	s := &Subscription{
+		Owner:  owner,
+		Secret: secret,
+	}
+	s.Update(in)
+	return s
+}
+
+// Update replaces the callback and the filters.
+func (s *Subscription) Update(in *SubscriptionIn) {
+	s.CallbackURL = in.CallbackURL
+	s.ResourceTypes = in.ResourceTypes
+	s.Workspaces = in.Workspaces
+	s.EventTypes = in.EventTypes
+}
+
+type SubscriptionOut struct {
+	*Subscription
+	Href string
+
+	// Secret is only returned when the subscription is created.  It can't be recovered afterward.
+	Secret string `json:",omitempty"`
+}
+
+func NewSubscriptionOut(s *Subscription, href string, secret string) *SubscriptionOut {
+	return &SubscriptionOut{
+		Subscription: s,
+		Href:         href,
+		Secret:       secret,
+	}
+}
+
+// The statuses of a delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is an event for a subscription and what happened when it was sent.  Pending deliveries are sent once
+// NextAttemptAt has passed.
+type Delivery struct {
+	ID        IDType `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	SubscriptionID IDType `gorm:"not null;index" json:"-"`
+
+	EventId   string `gorm:"not null"`
+	EventType string `gorm:"not null"`
+	Subject   string
+
+	// Event is the structured mode CloudEvent.
+	Event string `gorm:"not null" json:"-"`
+
+	Status        string `gorm:"not null;index"`
+	Attempts      int
+	LastError     string
+	LastAttemptAt *time.Time
+	NextAttemptAt *time.Time `json:"-"`
+	DeliveredAt   *time.Time
+}
+
+type DeliveryOut struct {
+	*Delivery
+	Href string
+}
+
+func NewDeliveryOut(d *Delivery, href string) *DeliveryOut {
+	return &DeliveryOut{
+		Delivery: d,
+		Href:     href,
+	}
+}
@@ -0,0 +1,27 @@
+package resync
+
+type Config struct {
+	*Options
+}
+
+type completedConfig struct {
+	Rate      float64
+	BatchSize int
+}
+
+type CompletedConfig struct {
+	*completedConfig
+}
+
+func NewConfig(o *Options) *Config {
+	return &Config{
+		Options: o,
+	}
+}
+
+func (c *Config) Complete() (CompletedConfig, error) {
+	return CompletedConfig{&completedConfig{
+		Rate:      c.Rate,
+		BatchSize: c.BatchSize,
+	}}, nil
+}
@@ -0,0 +1,44 @@
+package resync
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Rate      float64 `mapstructure:"rate"`
+	BatchSize int     `mapstructure:"batch-size"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Rate:      100,
+		BatchSize: 100,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet, prefix string) {
+	if prefix != "" {
+		prefix = prefix + "."
+	}
+	fs.Float64Var(&o.Rate, prefix+"rate", o.Rate, "How many snapshot events to send per second.  0 sends them as fast as the eventer takes them.")
+	fs.IntVar(&o.BatchSize, prefix+"batch-size", o.BatchSize, "How many resources to load at a time.  Progress is recorded after each batch.")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+
+	if o.Rate < 0 {
+		errs = append(errs, fmt.Errorf("the resync rate must not be negative: %v", o.Rate))
+	}
+	if o.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("the resync batch-size must be at least 1: %d", o.BatchSize))
+	}
+
+	return errs
+}
+
+func (o *Options) Complete() []error {
+	return nil
+}
@@ -0,0 +1,307 @@
+package resync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	cerrors "github.com/csams/common-inventory/pkg/errors"
+	eventingapi "github.com/csams/common-inventory/pkg/eventing/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+var (
+	ErrRunning   = errors.New("the resync is already running")
+	ErrCompleted = errors.New("the resync is already completed")
+)
+
+// Resyncer sends a snapshot event for every resource that matches a resync's filters, then an event that marks
+// the end of the snapshot, so consumers that lost their state can rebuild it.  The resync records the last
+// resource it sent after every batch and when it stops, so running it again resumes where it left off.
+//
+// A Resyncer only knows about the resyncs it's running.  Two servers running the same resync send duplicate
+// snapshots, which consumers have to tolerate anyway.
+type Resyncer struct {
+	Config CompletedConfig
+
+	// BasePath is the path under which the inventory API is served.
+	BasePath string
+
+	// ResourcePaths maps each resource type to the base path of its resources.  Resources of other types are
+	// skipped.
+	ResourcePaths map[string]string
+
+	Db       *gorm.DB
+	Eventing eventingapi.Manager
+	Log      *slog.Logger
+
+	mu      sync.Mutex
+	running map[models.IDType]context.CancelFunc
+	stopped sync.WaitGroup
+}
+
+func New(config CompletedConfig, basePath string, resourcePaths map[string]string, db *gorm.DB, eventing eventingapi.Manager, log *slog.Logger) *Resyncer {
+	return &Resyncer{
+		Config:        config,
+		BasePath:      basePath,
+		ResourcePaths: resourcePaths,
+		Db:            db,
+		Eventing:      eventing,
+		Log:           log,
+		running:       map[models.IDType]context.CancelFunc{},
+	}
+}
+
+// Href is the resync's href.  It's the subject of the event that marks its end.
+func (r *Resyncer) Href(resync *models.Resync) string {
+	return fmt.Sprintf("%s/admin/resyncs/%d", r.BasePath, resync.ID)
+}
+
+// Validate returns an error for a resource type that isn't served.
+func (r *Resyncer) Validate(in *models.ResyncIn) []error {
+	var errs []error
+
+	if _, found := r.ResourcePaths[in.ResourceType]; in.ResourceType != "" && !found {
+		errs = append(errs, cerrors.NewFieldError("ResourceType", fmt.Sprintf("unknown resource type %s", in.ResourceType)))
+	}
+
+	return errs
+}
+
+// Create records a new resync started by the identity.  It doesn't run it.
+func (r *Resyncer) Create(ctx context.Context, in *models.ResyncIn, identity *authnapi.Identity) (*models.Resync, error) {
+	resync := models.NewResync(in, identity.Principal)
+	if err := r.Db.WithContext(ctx).Create(resync).Error; err != nil {
+		return nil, err
+	}
+	return resync, nil
+}
+
+// Start runs the resync in the background until it's done, it fails, it's canceled or the Resyncer is shut down.
+func (r *Resyncer) Start(resync *models.Resync, identity *authnapi.Identity) error {
+	ctx, err := r.claim(context.Background(), resync)
+	if err != nil {
+		return err
+	}
+
+	r.stopped.Add(1)
+	go func() {
+		defer r.stopped.Done()
+		defer r.release(resync)
+		r.run(ctx, resync, identity)
+	}()
+	return nil
+}
+
+// Run runs the resync until it's done, it fails or the context is done.
+func (r *Resyncer) Run(ctx context.Context, resync *models.Resync, identity *authnapi.Identity) error {
+	ctx, err := r.claim(ctx, resync)
+	if err != nil {
+		return err
+	}
+	defer r.release(resync)
+	return r.run(ctx, resync, identity)
+}
+
+// Cancel stops the resync with the id.  It returns false if the resync isn't running here.
+func (r *Resyncer) Cancel(id models.IDType) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, found := r.running[id]
+	if found {
+		cancel()
+	}
+	return found
+}
+
+// Shutdown stops the running resyncs and waits for them to record how far they got.
+func (r *Resyncer) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	for _, cancel := range r.running {
+		cancel()
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.stopped.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Resyncer) claim(ctx context.Context, resync *models.Resync) (context.Context, error) {
+	if resync.Status == models.ResyncCompleted {
+		return nil, ErrCompleted
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, found := r.running[resync.ID]; found {
+		return nil, ErrRunning
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.running[resync.ID] = cancel
+	return ctx, nil
+}
+
+func (r *Resyncer) release(resync *models.Resync) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, found := r.running[resync.ID]; found {
+		cancel()
+		delete(r.running, resync.ID)
+	}
+}
+
+// run sends the snapshots after the last resource the resync sent and the event that marks the end, and records
+// the outcome.
+func (r *Resyncer) run(ctx context.Context, resync *models.Resync, identity *authnapi.Identity) error {
+	resync.Status = models.ResyncRunning
+	resync.LastError = ""
+	if err := r.record(resync); err != nil {
+		return err
+	}
+	r.Log.Info(fmt.Sprintf("Resync %d started after resource %d", resync.ID, resync.LastResourceId))
+
+	err := r.send(ctx, resync, identity)
+	if err == nil {
+		err = r.complete(ctx, resync, identity)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			err = errors.New("the resync was stopped")
+		}
+		resync.Status = models.ResyncFailed
+		resync.LastError = err.Error()
+		if recordErr := r.record(resync); recordErr != nil {
+			r.Log.Error(fmt.Sprintf("Failed to record the progress of resync %d: %v", resync.ID, recordErr))
+		}
+		r.Log.Warn(fmt.Sprintf("Resync %d stopped after %d snapshots at resource %d: %v", resync.ID, resync.Sent, resync.LastResourceId, err))
+		return err
+	}
+
+	r.Log.Info(fmt.Sprintf("Resync %d completed after %d snapshots", resync.ID, resync.Sent))
+	return nil
+}
+
+// send sends the snapshots in batches, at no more than the configured rate, and records the progress after each
+// batch.
+func (r *Resyncer) send(ctx context.Context, resync *models.Resync, identity *authnapi.Identity) error {
+	limit := rate.Inf
+	if r.Config.Rate > 0 {
+		limit = rate.Limit(r.Config.Rate)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	resyncId := strconv.FormatInt(int64(resync.ID), 10)
+	for {
+		var resources []models.Resource
+		err := r.Db.WithContext(ctx).Scopes(r.filter(resync)).Where("resources.id > ?", resync.LastResourceId).
+			Preload(clause.Associations).Order("id").Limit(r.Config.BatchSize).Find(&resources).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range resources {
+			resource := &resources[i]
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+
+			p, err := r.Eventing.Lookup(reporterIdentity(resync, resource, identity), resource)
+			if err != nil {
+				return err
+			}
+			href := fmt.Sprintf("%s/%d", r.ResourcePaths[resource.ResourceType], resource.ID)
+			if err := p.Produce(ctx, eventingapi.NewSnapshotEvent(href, resource, resyncId, identity)); err != nil {
+				return fmt.Errorf("failed to send the snapshot of resource %d: %w", resource.ID, err)
+			}
+
+			resync.LastResourceId = resource.ID
+			resync.Sent++
+		}
+
+		if err := r.record(resync); err != nil {
+			return err
+		}
+		if len(resources) < r.Config.BatchSize {
+			return nil
+		}
+	}
+}
+
+// complete sends the event that marks the end of the resync to every destination.
+func (r *Resyncer) complete(ctx context.Context, resync *models.Resync, identity *authnapi.Identity) error {
+	p, err := r.Eventing.LookupAll(identity)
+	if err != nil {
+		return err
+	}
+
+	filter := eventingapi.ResyncFilter{
+		ResourceType: resync.ResourceType,
+		Workspace:    resync.Workspace,
+		ReporterType: resync.ReporterType,
+		ReporterId:   resync.ReporterId,
+	}
+	resyncId := strconv.FormatInt(int64(resync.ID), 10)
+	if err := p.Produce(ctx, eventingapi.NewResyncCompletedEvent(r.Href(resync), resyncId, filter, resync.Sent, identity)); err != nil {
+		return fmt.Errorf("failed to send the end of the resync: %w", err)
+	}
+
+	now := time.Now().UTC()
+	resync.Status = models.ResyncCompleted
+	resync.CompletedAt = &now
+	return r.record(resync)
+}
+
+// record saves the resync's progress.  It's saved even when the context is done, so a resync that's stopped can
+// be resumed from where it got to.
+func (r *Resyncer) record(resync *models.Resync) error {
+	return r.Db.Model(resync).Select("status", "last_resource_id", "sent", "last_error", "completed_at").Updates(resync).Error
+}
+
+// filter selects the resources the resync matches, like the filters of the list endpoints.
+func (r *Resyncer) filter(resync *models.Resync) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if resync.ResourceType != "" {
+			db = db.Where("resources.resource_type = ?", resync.ResourceType)
+		} else {
+			var types []string
+			for t := range r.ResourcePaths {
+				types = append(types, t)
+			}
+			db = db.Where("resources.resource_type in ?", types)
+		}
+
+		return models.FilterResources(resync.Workspace, resync.ReporterType, resync.ReporterId)(db)
+	}
+}
+
+// reporterIdentity is the identity of the first reporter of the resource that the resync's filters match, so its
+// snapshot is routed like that reporter's changes.  It's the identity that runs the resync if the resource has no
+// reporters.
+func reporterIdentity(resync *models.Resync, resource *models.Resource, identity *authnapi.Identity) *authnapi.Identity {
+	for _, d := range resource.ReporterData {
+		if (resync.ReporterType == "" || d.ReporterType == resync.ReporterType) && (resync.ReporterId == "" || d.ReporterID == resync.ReporterId) {
+			return &authnapi.Identity{Principal: d.ReporterID, Type: d.ReporterType, Tenant: d.Tenant, IsReporter: true}
+		}
+	}
+	return identity
+}
@@ -0,0 +1,25 @@
+package resync
+
+import (
+	"testing"
+
+	authnapi "github.com/csams/common-inventory/pkg/authn/api"
+	"github.com/csams/common-inventory/pkg/models"
+)
+
+func TestSnapshotsAreRoutedWithTheReportersTenant(t *testing.T) {
+	resource := &models.Resource{ReporterData: []models.ReporterData{
+		{ReporterID: "ocm-1", ReporterType: "OCM", Tenant: "tenant-a"},
+		{ReporterID: "acm-hub-1", ReporterType: "ACM", Tenant: "tenant-b"},
+	}}
+	admin := &authnapi.Identity{Principal: "admin"}
+
+	identity := reporterIdentity(&models.Resync{ReporterType: "ACM"}, resource, admin)
+	if identity.Principal != "acm-hub-1" || identity.Type != "ACM" || identity.Tenant != "tenant-b" || !identity.IsReporter {
+		t.Errorf("expected the ACM reporter's identity, got %+v", identity)
+	}
+
+	if identity := reporterIdentity(&models.Resync{ReporterType: "HBI"}, resource, admin); identity != admin {
+		t.Errorf("expected the identity running the resync, got %+v", identity)
+	}
+}
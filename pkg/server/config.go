@@ -60,7 +60,7 @@ func (c *Config) Complete() (CompletedConfig, error) {
 
 	return CompletedConfig{&completedConfig{
 		Options:       c.Options,
-		SecureServing: c.Options.ServingCertFile != "" || c.Options.PrivateKeyFile != "" || c.Options.CertOpt > int(tls.NoClientCert),
+		SecureServing: c.Options.ServingCertFile != "" && c.Options.PrivateKeyFile != "",
 		TLSConfig:     tlsConfig,
 	}}, err
 }
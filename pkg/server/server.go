@@ -24,6 +24,9 @@ type preparedServer struct {
 
 func New(c CompletedConfig, handler http.Handler, log *slog.Logger) *Server {
 	return &Server{
+		SecureServing:   c.SecureServing,
+		ServingCertFile: c.Options.ServingCertFile,
+		PrivateKeyFile:  c.Options.PrivateKeyFile,
 		HttpServer: &http.Server{
 			Addr:         c.Options.Addr,
 			ReadTimeout:  time.Duration(c.Options.ReadTimeout) * time.Second,
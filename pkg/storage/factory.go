@@ -20,5 +20,6 @@ func New(c CompletedConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("unrecognized database type: %s", c.Database)
 	}
 
-	return gorm.Open(opener(c.DSN), &gorm.Config{})
+	// TranslateError maps constraint violations to errors like gorm.ErrDuplicatedKey
+	return gorm.Open(opener(c.DSN), &gorm.Config{TranslateError: true})
 }
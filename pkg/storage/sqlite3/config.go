@@ -1,11 +1,14 @@
 package sqlite3
 
+import "strings"
+
 type Config struct {
 	*Options
 }
 
 type completedConfig struct {
 	*Config
+	DSN string
 }
 
 type CompletedConfig struct {
@@ -17,7 +20,19 @@ func NewConfig(o *Options) *Config {
 }
 
 func (c *Config) Complete() CompletedConfig {
+	dsn := c.DSN
+
+	// sqlite only enforces foreign keys (and cascades deletes) when they're enabled on each connection.
+	if !strings.Contains(dsn, "_foreign_keys=") && !strings.Contains(dsn, "_fk=") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&_foreign_keys=on"
+		} else {
+			dsn += "?_foreign_keys=on"
+		}
+	}
+
 	return CompletedConfig{&completedConfig{
-		c,
+		Config: c,
+		DSN:    dsn,
 	}}
 }